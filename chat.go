@@ -2,23 +2,41 @@ package goai
 
 import (
 	"context"
+	"time"
 
+	"github.com/tech1024/goai/chat"
 	"github.com/tech1024/goai/prompt"
 )
 
 type ChatModel interface {
-	Call(ctx context.Context, prompt prompt.Prompt) (string, error)
-	Stream(ctx context.Context, prompt prompt.Prompt, receive func([]byte) error) error
+	Call(ctx context.Context, prompt prompt.Prompt) (*chat.Response, error)
+	Stream(ctx context.Context, prompt prompt.Prompt, receive func(*chat.Response) error) error
 }
 
-func NewChat(chatModel ChatModel) *Chat {
-	return &Chat{
+// ChatOption configures a Chat.
+type ChatOption func(*Chat)
+
+// WithHooks adds hooks receiving the events of every call.
+func WithHooks(hs ...Hook) ChatOption {
+	return func(c *Chat) {
+		c.hooks = append(c.hooks, hs...)
+	}
+}
+
+func NewChat(chatModel ChatModel, options ...ChatOption) *Chat {
+	c := &Chat{
 		chatModel: chatModel,
 	}
+	for _, option := range options {
+		option(c)
+	}
+
+	return c
 }
 
 type Chat struct {
 	chatModel ChatModel
+	hooks     hooks
 }
 
 // Chat send a message, it returns string
@@ -36,9 +54,75 @@ func (c *Chat) ChatStream(ctx context.Context, content string, receive func([]by
 }
 
 func (c *Chat) Prompt(ctx context.Context, p prompt.Prompt) (string, error) {
-	return c.chatModel.Call(ctx, p)
+	resp, err := c.Call(ctx, p)
+	if err != nil {
+		return "", err
+	}
+
+	return resp.Text(), nil
 }
 
 func (c *Chat) Stream(ctx context.Context, p prompt.Prompt, fn func([]byte) error) error {
-	return c.chatModel.Stream(ctx, p, fn)
+	return c.StreamResponse(ctx, p, func(resp *chat.Response) error {
+		if text := resp.Text(); text != "" {
+			return fn([]byte(text))
+		}
+
+		return nil
+	})
+}
+
+// Call send a prompt, it returns the whole response including its usage
+func (c *Chat) Call(ctx context.Context, p prompt.Prompt) (*chat.Response, error) {
+	info := &CallInfo{Prompt: p, Start: time.Now()}
+	ctx = c.hooks.OnStart(ctx, info)
+
+	resp, err := c.chatModel.Call(ctx, p)
+	info.End = time.Now()
+	if err != nil {
+		c.hooks.OnError(ctx, info, err)
+		return nil, err
+	}
+
+	info.Response = resp
+	for _, generation := range resp.Generations {
+		for _, toolCall := range generation.ToolCalls {
+			c.hooks.OnToolCall(ctx, info, toolCall)
+		}
+	}
+	c.hooks.OnEnd(ctx, info)
+
+	return resp, nil
+}
+
+// StreamResponse send a prompt, need to receive the chunks of its response
+func (c *Chat) StreamResponse(ctx context.Context, p prompt.Prompt, fn func(*chat.Response) error) error {
+	info := &CallInfo{Prompt: p, Stream: true, Start: time.Now(), Response: &chat.Response{}}
+	ctx = c.hooks.OnStart(ctx, info)
+
+	err := c.chatModel.Stream(ctx, p, func(chunk *chat.Response) error {
+		if info.FirstChunk.IsZero() {
+			info.FirstChunk = time.Now()
+			c.hooks.OnFirstChunk(ctx, info)
+		}
+
+		info.Response.Append(chunk)
+		c.hooks.OnChunk(ctx, info, chunk)
+		for _, generation := range chunk.Generations {
+			for _, toolCall := range generation.ToolCalls {
+				c.hooks.OnToolCall(ctx, info, toolCall)
+			}
+		}
+
+		return fn(chunk)
+	})
+	info.End = time.Now()
+	if err != nil {
+		c.hooks.OnError(ctx, info, err)
+		return err
+	}
+
+	c.hooks.OnEnd(ctx, info)
+
+	return nil
 }
@@ -0,0 +1,172 @@
+package chat
+
+import (
+	"math"
+	"time"
+)
+
+// ToolCall a call of a tool requested by the model.
+type ToolCall struct {
+	// ID the identifier of the call, if the provider reports one.
+	ID string
+
+	// Name the name of the tool.
+	Name string
+
+	// Arguments the arguments of the call encoded as JSON.
+	Arguments string
+}
+
+// ToolCallDelta a fragment of a tool call streamed by the model, the
+// fragments of a call share its index and their arguments concatenate.
+type ToolCallDelta struct {
+	// Index the position of the call among the calls of the generation.
+	Index int
+
+	// ID and Name are set by the first fragment of a call.
+	ID   string
+	Name string
+
+	// Arguments the next part of the JSON encoded arguments.
+	Arguments string
+}
+
+// LogProb the log probability of a generated token.
+type LogProb struct {
+	Token   string
+	LogProb float64
+
+	// TopLogProbs the most likely tokens at the position, if requested.
+	TopLogProbs []LogProb
+}
+
+// Probability returns the probability of the token.
+func (l LogProb) Probability() float64 {
+	return math.Exp(l.LogProb)
+}
+
+// FinishReason the reason the model stopped generating.
+type FinishReason string
+
+const (
+	FinishReasonStop          FinishReason = "stop"
+	FinishReasonLength        FinishReason = "length"
+	FinishReasonToolCalls     FinishReason = "tool_calls"
+	FinishReasonContentFilter FinishReason = "content_filter"
+)
+
+// Generation a single completion generated by the model.
+type Generation struct {
+	// Content the generated text, or the text delta when streaming.
+	Content string
+
+	// Thinking the reasoning of a thinking model, if the provider returns it.
+	Thinking string
+
+	// ToolCalls the tools the model wants to call.
+	ToolCalls []ToolCall
+
+	// ToolCallDeltas the tool call fragments of a stream chunk, for progress
+	// only. The assembled calls follow in ToolCalls of a later chunk.
+	ToolCallDeltas []ToolCallDelta
+
+	// FinishReason the reason the generation ended, a stream reports it with
+	// its last chunk. Providers may report reasons not listed here.
+	FinishReason FinishReason
+
+	// LogProbs the log probabilities of the generated tokens, if requested.
+	LogProbs []LogProb
+
+	// Metadata the provider specific data to be sent back with the assistant
+	// message of the generation, e.g. the signed thinking blocks of anthropic.
+	Metadata map[string]any
+}
+
+// Timing the durations reported by the provider.
+type Timing struct {
+	// PromptDuration the time spent evaluating the prompt.
+	PromptDuration time.Duration
+
+	// CompletionDuration the time spent generating the completion.
+	CompletionDuration time.Duration
+}
+
+// Response the result of a chat model call, or a single chunk of a stream.
+type Response struct {
+	// ID the identifier of the completion, if the provider reports one.
+	ID string
+
+	// Model the model that generated the response.
+	Model string
+
+	// Generations the candidates generated, one unless more were requested.
+	Generations []Generation
+
+	// Usage the tokens consumed, a stream reports it with its last chunk.
+	Usage Usage
+
+	// Timing the durations of the call, if the provider reports them.
+	Timing Timing
+}
+
+// Text returns the content of the first generation.
+func (r *Response) Text() string {
+	if r == nil || len(r.Generations) == 0 {
+		return ""
+	}
+
+	return r.Generations[0].Content
+}
+
+// ToolCalls returns the tool calls of the first generation.
+func (r *Response) ToolCalls() []ToolCall {
+	if r == nil || len(r.Generations) == 0 {
+		return nil
+	}
+
+	return r.Generations[0].ToolCalls
+}
+
+// FinishReason returns the finish reason of the first generation.
+func (r *Response) FinishReason() FinishReason {
+	if r == nil || len(r.Generations) == 0 {
+		return ""
+	}
+
+	return r.Generations[0].FinishReason
+}
+
+// Append accumulates a chunk of a stream into the response.
+func (r *Response) Append(chunk *Response) {
+	if chunk.ID != "" {
+		r.ID = chunk.ID
+	}
+	if chunk.Model != "" {
+		r.Model = chunk.Model
+	}
+	if !chunk.Usage.IsZero() {
+		r.Usage = chunk.Usage
+	}
+	if chunk.Timing != (Timing{}) {
+		r.Timing = chunk.Timing
+	}
+
+	for i, generation := range chunk.Generations {
+		if i >= len(r.Generations) {
+			r.Generations = append(r.Generations, Generation{})
+		}
+		r.Generations[i].Content += generation.Content
+		r.Generations[i].Thinking += generation.Thinking
+		r.Generations[i].ToolCalls = append(r.Generations[i].ToolCalls, generation.ToolCalls...)
+		if generation.FinishReason != "" {
+			r.Generations[i].FinishReason = generation.FinishReason
+		}
+		r.Generations[i].LogProbs = append(r.Generations[i].LogProbs, generation.LogProbs...)
+		for key, value := range generation.Metadata {
+			if r.Generations[i].Metadata == nil {
+				r.Generations[i].Metadata = make(map[string]any)
+			}
+			r.Generations[i].Metadata[key] = value
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package goai
+
+import (
+	"context"
+
+	"github.com/tech1024/goai/completion"
+)
+
+// CompletionModel a model completing text without the chat format, e.g. code models
+// filling in the middle.
+type CompletionModel interface {
+	Call(ctx context.Context, request completion.Request) (*completion.Response, error)
+	Stream(ctx context.Context, request completion.Request, receive func(*completion.Response) error) error
+}
+
+func NewCompletion(completionModel CompletionModel) *Completion {
+	return &Completion{
+		completionModel: completionModel,
+	}
+}
+
+type Completion struct {
+	completionModel CompletionModel
+}
+
+// Complete completes the text, it returns the generated text
+func (c *Completion) Complete(ctx context.Context, text string) (string, error) {
+	resp, err := c.completionModel.Call(ctx, completion.NewRequest(text, completion.Option{}))
+	if err != nil {
+		return "", err
+	}
+
+	return resp.Text, nil
+}
+
+// FillInTheMiddle generates the text between prefix and suffix
+func (c *Completion) FillInTheMiddle(ctx context.Context, prefix, suffix string) (string, error) {
+	request := completion.NewRequest(prefix, completion.Option{})
+	request.Suffix = suffix
+
+	resp, err := c.completionModel.Call(ctx, request)
+	if err != nil {
+		return "", err
+	}
+
+	return resp.Text, nil
+}
+
+// CompleteStream completes the text, need to receive its returns
+func (c *Completion) CompleteStream(ctx context.Context, text string, receive func([]byte) error) error {
+	return c.completionModel.Stream(ctx, completion.NewRequest(text, completion.Option{}), func(resp *completion.Response) error {
+		if resp.Text != "" {
+			return receive([]byte(resp.Text))
+		}
+
+		return nil
+	})
+}
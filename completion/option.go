@@ -0,0 +1,26 @@
+package completion
+
+import "github.com/tech1024/goai/prompt"
+
+type Option struct {
+	// Model the model to use for the completion.
+	Model string
+
+	// System overrides the system prompt of the model.
+	System string
+
+	// Raw sends the prompt without applying the template of the model.
+	Raw bool
+
+	// Template overrides the prompt template of the model.
+	Template string
+
+	// MaxTokens the maximum number of tokens to generate.
+	MaxTokens int
+
+	// Stop the sequences that stop the generation.
+	Stop []string
+
+	// Extensions the provider specific options, as for chat prompts.
+	Extensions []prompt.Extension
+}
@@ -0,0 +1,22 @@
+package completion
+
+func NewRequest(prompt string, option Option) Request {
+	return Request{
+		Prompt: prompt,
+		Option: option,
+	}
+}
+
+type Request struct {
+	// Prompt the text to complete, or the code before the cursor when filling in the middle.
+	Prompt string
+
+	// Suffix the text after the generated text, e.g. the code after the cursor.
+	Suffix string
+
+	// Context the Context of a previous response to continue from, if the
+	// provider supports it.
+	Context []int
+
+	Option Option
+}
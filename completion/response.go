@@ -0,0 +1,28 @@
+package completion
+
+import "github.com/tech1024/goai/chat"
+
+type Response struct {
+	// Model the model that generated the response.
+	Model string
+
+	// Text the generated text, or the text delta when streaming.
+	Text string
+
+	// Thinking the reasoning of a thinking model, if the provider returns it.
+	Thinking string
+
+	// Usage the tokens consumed, a stream reports it with its last chunk.
+	Usage chat.Usage
+
+	// Timing the durations of the call, if the provider reports them.
+	Timing chat.Timing
+
+	// LogProbs the log probabilities of the generated tokens, if requested
+	// and reported by the provider.
+	LogProbs []chat.LogProb
+
+	// Context the encoded state of the conversation, if the provider reports
+	// it, a stream reports it with its last chunk.
+	Context []int
+}
@@ -0,0 +1,184 @@
+package goai
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DSN a parsed model data source name, either "provider://host/model?params",
+// e.g. "ollama://gpu1:11434/llama3.1", or "provider:model?params" using the
+// default host of the provider, e.g. "openai:gpt-4o-mini?temperature=0.2".
+//
+// Credentials are never part of a DSN, the providers read them from their
+// environment variables, the api_key_env parameter names another one.
+type DSN struct {
+	// Provider the name of the registered provider.
+	Provider string
+
+	// Host the host and port of the server, empty uses the provider default.
+	Host string
+
+	// Model the model, it may contain slashes and colons,
+	// e.g. "meta-llama/Llama-3.1-8B" or "llama3.1:8b".
+	Model string
+
+	params url.Values
+	read   map[string]bool
+}
+
+// ParseDSN parses a model DSN.
+func ParseDSN(dsn string) (*DSN, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("goai dsn: %w", err)
+	}
+	if u.Scheme == "" {
+		return nil, fmt.Errorf("goai dsn: missing provider in %q", dsn)
+	}
+	if u.User != nil {
+		return nil, errors.New("goai dsn: credentials are not supported, set them in an environment variable")
+	}
+
+	d := DSN{
+		Provider: u.Scheme,
+		Host:     u.Host,
+		Model:    strings.TrimPrefix(u.Path, "/"),
+		params:   u.Query(),
+		read:     make(map[string]bool),
+	}
+	if u.Opaque != "" {
+		if d.Model, err = url.PathUnescape(u.Opaque); err != nil {
+			return nil, fmt.Errorf("goai dsn: %w", err)
+		}
+	}
+
+	return &d, nil
+}
+
+// Param returns the value of the parameter, empty if it is not set.
+func (d *DSN) Param(name string) string {
+	d.read[name] = true
+
+	return d.params.Get(name)
+}
+
+// Values returns all values of the parameter, e.g. "stop=a&stop=b".
+func (d *DSN) Values(name string) []string {
+	d.read[name] = true
+
+	return d.params[name]
+}
+
+// Float returns the float value of the parameter, nil if it is not set.
+func (d *DSN) Float(name string) (*float64, error) {
+	return parseParam(d, name, func(s string) (float64, error) {
+		return strconv.ParseFloat(s, 64)
+	})
+}
+
+// Int returns the integer value of the parameter, nil if it is not set.
+func (d *DSN) Int(name string) (*int, error) {
+	return parseParam(d, name, strconv.Atoi)
+}
+
+// Bool returns the boolean value of the parameter, nil if it is not set.
+func (d *DSN) Bool(name string) (*bool, error) {
+	return parseParam(d, name, strconv.ParseBool)
+}
+
+// Duration returns the duration value of the parameter, e.g. "5m", nil if it
+// is not set.
+func (d *DSN) Duration(name string) (*time.Duration, error) {
+	return parseParam(d, name, time.ParseDuration)
+}
+
+func parseParam[T any](d *DSN, name string, parse func(string) (T, error)) (*T, error) {
+	s := d.Param(name)
+	if s == "" {
+		return nil, nil
+	}
+
+	v, err := parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("goai dsn: parameter %s: invalid value %q", name, s)
+	}
+
+	return &v, nil
+}
+
+// Rest returns the parameters not read so far as raw options of providers
+// passing them through, JSON values like numbers and booleans are decoded.
+func (d *DSN) Rest() map[string]any {
+	var rest map[string]any
+	for name := range d.params {
+		if d.read[name] {
+			continue
+		}
+		if rest == nil {
+			rest = make(map[string]any)
+		}
+
+		var value any
+		if s := d.Param(name); json.Unmarshal([]byte(s), &value) != nil {
+			value = s
+		}
+		rest[name] = value
+	}
+
+	return rest
+}
+
+// APIKey returns the api key read from the environment variable named by the
+// api_key_env parameter, empty if it is not set to let the provider read its
+// default variable.
+func (d *DSN) APIKey() (string, error) {
+	name := d.Param("api_key_env")
+	if name == "" {
+		return "", nil
+	}
+
+	apiKey := os.Getenv(name)
+	if apiKey == "" {
+		return "", fmt.Errorf("goai dsn: environment variable %s is not set", name)
+	}
+
+	return apiKey, nil
+}
+
+// BaseURL returns the base_url parameter, or the url of the Host with the
+// scheme and the path, e.g. BaseURL("https", "/v1"). It is empty if neither
+// is set to let the provider use its default.
+func (d *DSN) BaseURL(scheme, path string) string {
+	if baseURL := d.Param("base_url"); baseURL != "" {
+		return baseURL
+	}
+	if d.Host == "" {
+		return ""
+	}
+
+	return scheme + "://" + d.Host + path
+}
+
+// checkRead fails if a parameter has not been read by the provider, e.g. a
+// misspelled one.
+func (d *DSN) checkRead() error {
+	var unknown []string
+	for name := range d.params {
+		if !d.read[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+
+	sort.Strings(unknown)
+	return fmt.Errorf("goai dsn: unknown %s parameters %s", d.Provider, strings.Join(unknown, ", "))
+}
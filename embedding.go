@@ -10,6 +10,12 @@ type EmbeddingModel interface {
 	Call(context.Context, embedding.Request) (embedding.Response, error)
 }
 
+func NewEmbedding(embeddingModel EmbeddingModel) *Embedding {
+	return &Embedding{
+		embeddingModel: embeddingModel,
+	}
+}
+
 type Embedding struct {
 	embeddingModel EmbeddingModel
 }
@@ -1,8 +1,13 @@
 package embedding
 
+import "github.com/tech1024/goai/prompt"
+
 type Option struct {
 	// Model the model to use for the chat.
 	Model string
 
 	Dimensions int
+
+	// Extensions the provider specific options, as for chat prompts.
+	Extensions []prompt.Extension
 }
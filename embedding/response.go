@@ -1,7 +1,15 @@
 package embedding
 
+import "github.com/tech1024/goai/chat"
+
 type Response struct {
 	Embeddings []Embedding
+
+	// Model the model which created the embeddings, if reported by the provider.
+	Model string
+
+	// Usage the tokens of the inputs, if reported by the provider.
+	Usage chat.Usage
 }
 
 func (r *Response) List() [][]float32 {
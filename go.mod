@@ -2,4 +2,4 @@ module github.com/tech1024/goai
 
 go 1.23
 
-require github.com/sashabaranov/go-openai v1.38.0 // indirect
+require github.com/sashabaranov/go-openai v1.38.0
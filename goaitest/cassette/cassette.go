@@ -0,0 +1,425 @@
+// Package cassette records the HTTP traffic of provider clients to a file
+// once and replays it afterwards, so tests run offline and deterministic.
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNoInteraction is returned when replaying a request which has not been recorded.
+var ErrNoInteraction = errors.New("cassette: no recorded interaction matches the request")
+
+// Mode how the Recorder treats requests.
+type Mode int
+
+const (
+	// ModeReplay answers from the cassette only.
+	ModeReplay Mode = iota
+
+	// ModeRecord sends every request and records it, replacing the cassette.
+	ModeRecord
+
+	// ModeReplayOrRecord answers from the cassette and records missing requests.
+	ModeReplayOrRecord
+)
+
+// DefaultRedactedHeaders the headers never written to a cassette.
+var DefaultRedactedHeaders = []string{"Authorization", "Api-Key", "X-Api-Key", "X-Goog-Api-Key", "Openai-Organization", "Cookie", "Set-Cookie"}
+
+// DefaultRedactedQueryParams the query parameters never written to a cassette,
+// e.g. the api key of Gemini.
+var DefaultRedactedQueryParams = []string{"key"}
+
+// Chunk a part of a response body, read Delay after the previous one.
+type Chunk struct {
+	Delay time.Duration `json:"delay"`
+	Data  string        `json:"data"`
+}
+
+// Request a recorded request.
+type Request struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// Response a recorded response, its body is kept as the chunks it was read in.
+type Response struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Chunks     []Chunk     `json:"chunks"`
+}
+
+// Body returns the whole body of the response.
+func (r Response) Body() string {
+	var sb strings.Builder
+	for _, chunk := range r.Chunks {
+		sb.WriteString(chunk.Data)
+	}
+
+	return sb.String()
+}
+
+// Interaction a recorded request and its response.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Matcher reports whether a recorded request matches the request with the body.
+type Matcher func(r *http.Request, body []byte, recorded Request) bool
+
+// DefaultMatcher matches by method, path, query and normalized body.
+func DefaultMatcher(r *http.Request, body []byte, recorded Request) bool {
+	return r.Method == recorded.Method &&
+		requestURL(r) == recorded.URL &&
+		Normalize(body) == Normalize([]byte(recorded.Body))
+}
+
+// Normalize returns the canonical form of a JSON body, other bodies are only trimmed.
+func Normalize(body []byte) string {
+	var v any
+	if err := json.Unmarshal(body, &v); err != nil {
+		return string(bytes.TrimSpace(body))
+	}
+
+	bts, _ := json.Marshal(v)
+	return string(bts)
+}
+
+func requestURL(r *http.Request) string {
+	if r.URL.RawQuery == "" {
+		return r.URL.Path
+	}
+
+	return r.URL.Path + "?" + r.URL.RawQuery
+}
+
+// Recorder an http.RoundTripper recording to and replaying from a cassette file.
+type Recorder struct {
+	mu           sync.Mutex
+	path         string
+	mode         Mode
+	transport    http.RoundTripper
+	interactions []Interaction
+	used         []bool
+
+	// Matcher matches requests to recorded ones, defaults to DefaultMatcher.
+	Matcher Matcher
+
+	// RedactedHeaders the headers replaced before writing, defaults to DefaultRedactedHeaders.
+	RedactedHeaders []string
+
+	// RedactedQueryParams the query parameters replaced before writing and
+	// matching, defaults to DefaultRedactedQueryParams.
+	RedactedQueryParams []string
+
+	// ReplayTiming delays replayed chunks as they were recorded.
+	ReplayTiming bool
+}
+
+// New returns a Recorder for the cassette at path, transport sends the
+// requests when recording and defaults to http.DefaultTransport.
+func New(path string, mode Mode, transport http.RoundTripper) (*Recorder, error) {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	r := &Recorder{
+		path:      path,
+		mode:      mode,
+		transport: transport,
+	}
+
+	if mode == ModeRecord {
+		return r, nil
+	}
+
+	bts, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && mode == ModeReplayOrRecord {
+		return r, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(bts, &r.interactions); err != nil {
+		return nil, fmt.Errorf("cassette %s: %w", path, err)
+	}
+	r.used = make([]bool, len(r.interactions))
+
+	return r, nil
+}
+
+// Client returns an http.Client using the recorder as transport.
+func (r *Recorder) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// Interactions returns the interactions of the cassette.
+func (r *Recorder) Interactions() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]Interaction(nil), r.interactions...)
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		_ = req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	if r.mode != ModeRecord {
+		if interaction, ok := r.find(r.redactQuery(req), body); ok {
+			return r.replay(req, interaction), nil
+		}
+		if r.mode == ModeReplay {
+			return nil, fmt.Errorf("%w: %s %s", ErrNoInteraction, req.Method, requestURL(r.redactQuery(req)))
+		}
+	}
+
+	return r.record(req, body)
+}
+
+func (r *Recorder) find(req *http.Request, body []byte) (Interaction, bool) {
+	matcher := r.Matcher
+	if matcher == nil {
+		matcher = DefaultMatcher
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// the first unused match, repeated requests reuse the last match
+	last := -1
+	for i, interaction := range r.interactions {
+		if !matcher(req, body, interaction.Request) {
+			continue
+		}
+		if !r.used[i] {
+			r.used[i] = true
+			return interaction, true
+		}
+		last = i
+	}
+	if last >= 0 {
+		return r.interactions[last], true
+	}
+
+	return Interaction{}, false
+}
+
+func (r *Recorder) replay(req *http.Request, interaction Interaction) *http.Response {
+	resp := &http.Response{
+		StatusCode: interaction.Response.StatusCode,
+		Status:     fmt.Sprintf("%d %s", interaction.Response.StatusCode, http.StatusText(interaction.Response.StatusCode)),
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     interaction.Response.Header.Clone(),
+		Request:    req,
+		Body: &replayBody{
+			chunks: interaction.Response.Chunks,
+			timing: r.ReplayTiming,
+			done:   req.Context().Done(),
+		},
+		ContentLength: -1,
+	}
+	if resp.Header == nil {
+		resp.Header = make(http.Header)
+	}
+
+	return resp
+}
+
+func (r *Recorder) record(req *http.Request, body []byte) (*http.Response, error) {
+	resp, err := r.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	interaction := Interaction{
+		Request: Request{
+			Method: req.Method,
+			URL:    requestURL(r.redactQuery(req)),
+			Header: r.redact(req.Header),
+			Body:   string(body),
+		},
+		Response: Response{
+			StatusCode: resp.StatusCode,
+			Header:     r.redact(resp.Header),
+		},
+	}
+	resp.Body = &recordBody{
+		body:        resp.Body,
+		last:        time.Now(),
+		interaction: interaction,
+		save:        r.add,
+	}
+
+	return resp, nil
+}
+
+func (r *Recorder) redact(header http.Header) http.Header {
+	redacted := header.Clone()
+	headers := r.RedactedHeaders
+	if headers == nil {
+		headers = DefaultRedactedHeaders
+	}
+	for _, key := range headers {
+		if redacted.Get(key) != "" {
+			redacted.Set(key, "REDACTED")
+		}
+	}
+
+	return redacted
+}
+
+// redactQuery returns a shallow copy of the request with the redacted query
+// parameters replaced, the request itself if it has none of them.
+func (r *Recorder) redactQuery(req *http.Request) *http.Request {
+	params := r.RedactedQueryParams
+	if params == nil {
+		params = DefaultRedactedQueryParams
+	}
+
+	query := req.URL.Query()
+	var redacted bool
+	for _, param := range params {
+		if query.Has(param) {
+			query.Set(param, "REDACTED")
+			redacted = true
+		}
+	}
+	if !redacted {
+		return req
+	}
+
+	u := *req.URL
+	u.RawQuery = query.Encode()
+	clone := req.WithContext(req.Context())
+	clone.URL = &u
+
+	return clone
+}
+
+// add appends the interaction and writes the cassette.
+func (r *Recorder) add(interaction Interaction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.interactions = append(r.interactions, interaction)
+	r.used = append(r.used, true)
+
+	bts, err := json.MarshalIndent(r.interactions, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(r.path, bts, 0o644)
+}
+
+// recordBody records the chunks of a response body as they are read, the
+// interaction is saved once the body is read completely or closed.
+type recordBody struct {
+	body        io.ReadCloser
+	last        time.Time
+	interaction Interaction
+	save        func(Interaction) error
+	saved       bool
+}
+
+func (b *recordBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	if n > 0 {
+		now := time.Now()
+		b.interaction.Response.Chunks = append(b.interaction.Response.Chunks, Chunk{
+			Delay: now.Sub(b.last),
+			Data:  string(p[:n]),
+		})
+		b.last = now
+	}
+	if errors.Is(err, io.EOF) {
+		if saveErr := b.finish(); saveErr != nil {
+			return n, saveErr
+		}
+	}
+
+	return n, err
+}
+
+func (b *recordBody) Close() error {
+	err := b.body.Close()
+	if saveErr := b.finish(); saveErr != nil {
+		return saveErr
+	}
+
+	return err
+}
+
+func (b *recordBody) finish() error {
+	if b.saved {
+		return nil
+	}
+	b.saved = true
+
+	return b.save(b.interaction)
+}
+
+// replayBody returns the recorded chunks one by one.
+type replayBody struct {
+	chunks  []Chunk
+	current []byte
+	timing  bool
+	done    <-chan struct{}
+}
+
+func (b *replayBody) Read(p []byte) (int, error) {
+	for len(b.current) == 0 {
+		if len(b.chunks) == 0 {
+			return 0, io.EOF
+		}
+
+		chunk := b.chunks[0]
+		b.chunks = b.chunks[1:]
+		if b.timing && chunk.Delay > 0 {
+			timer := time.NewTimer(chunk.Delay)
+			select {
+			case <-b.done:
+				timer.Stop()
+				return 0, errors.New("cassette: request canceled")
+			case <-timer.C:
+			}
+		}
+		b.current = []byte(chunk.Data)
+	}
+
+	n := copy(p, b.current)
+	b.current = b.current[n:]
+
+	return n, nil
+}
+
+func (b *replayBody) Close() error {
+	return nil
+}
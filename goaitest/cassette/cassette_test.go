@@ -0,0 +1,118 @@
+package cassette
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	goopenai "github.com/sashabaranov/go-openai"
+	"github.com/tech1024/goai"
+	"github.com/tech1024/goai/goaitest"
+	"github.com/tech1024/goai/provider/openai"
+	"github.com/tech1024/goai/provider/openai/openaitest"
+)
+
+func newChat(t *testing.T, baseURL string, recorder *Recorder) *goai.Chat {
+	t.Helper()
+	config := goopenai.DefaultConfig("secret-token")
+	config.BaseURL = baseURL + "/v1"
+	config.HTTPClient = recorder.Client()
+
+	return goai.NewChat(openai.NewChatModel(goopenai.NewClientWithConfig(config), "gpt-test"))
+}
+
+func stream(c *goai.Chat, content string) (string, error) {
+	var sb strings.Builder
+	err := c.ChatStream(context.Background(), content, func(bts []byte) error {
+		sb.Write(bts)
+		return nil
+	})
+
+	return sb.String(), err
+}
+
+func TestRecorder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "chat.json")
+	ts := openaitest.NewServer(goaitest.NewChatModel(goaitest.Text("hello"), goaitest.Chunks("a", "b", "c")), nil)
+
+	recorder, err := New(path, ModeRecord, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := newChat(t, ts.URL, recorder)
+	if got, err := c.Chat(context.Background(), "q1"); err != nil || got != "hello" {
+		t.Fatalf("Chat() got = %q, error = %v", got, err)
+	}
+	if got, err := stream(c, "q2"); err != nil || got != "abc" {
+		t.Fatalf("ChatStream() got = %q, error = %v", got, err)
+	}
+	ts.Close()
+
+	bts, _ := os.ReadFile(path)
+	if strings.Contains(string(bts), "secret-token") || !strings.Contains(string(bts), "REDACTED") {
+		t.Errorf("cassette is not redacted: %s", bts)
+	}
+
+	recorder, err = New(path, ModeReplay, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c = newChat(t, ts.URL, recorder)
+	if got, err := stream(c, "q2"); err != nil || got != "abc" {
+		t.Errorf("ChatStream() got = %q, error = %v", got, err)
+	}
+	if got, err := c.Chat(context.Background(), "q1"); err != nil || got != "hello" {
+		t.Errorf("Chat() got = %q, error = %v", got, err)
+	}
+	if _, err = c.Chat(context.Background(), "q3"); !errors.Is(err, ErrNoInteraction) {
+		t.Errorf("Chat() error = %v, wantErr %v", err, ErrNoInteraction)
+	}
+}
+
+func TestRecorder_RedactGeminiKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gemini.json")
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"ok":true}`)
+	}))
+	defer ts.Close()
+
+	get := func(recorder *Recorder) (string, error) {
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+"/v1beta/models?key=secret-key&pageSize=10", nil)
+		req.Header.Set("x-goog-api-key", "secret-header")
+		resp, err := recorder.Client().Do(req)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		bts, err := io.ReadAll(resp.Body)
+
+		return string(bts), err
+	}
+
+	recorder, err := New(path, ModeRecord, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = get(recorder); err != nil {
+		t.Fatal(err)
+	}
+
+	bts, _ := os.ReadFile(path)
+	if strings.Contains(string(bts), "secret-key") || strings.Contains(string(bts), "secret-header") {
+		t.Errorf("cassette is not redacted: %s", bts)
+	}
+
+	recorder, err = New(path, ModeReplay, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := get(recorder); err != nil || got != `{"ok":true}` {
+		t.Errorf("replay got = %q, error = %v", got, err)
+	}
+}
@@ -0,0 +1,210 @@
+package goaitest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/tech1024/goai/chat"
+	"github.com/tech1024/goai/prompt"
+)
+
+// ErrNoReply is returned by the fake ChatModel when its queue is empty.
+var ErrNoReply = errors.New("goaitest: no reply queued")
+
+// Reply a scripted reply of the fake ChatModel.
+type Reply struct {
+	// Chunks the chunks streamed, a call receives them accumulated.
+	Chunks []*chat.Response
+
+	// Err the error returned instead of a response, or after the chunks
+	// when streaming.
+	Err error
+
+	// Latency the delay before the reply, or before each chunk when streaming.
+	Latency time.Duration
+}
+
+// Text returns a Reply with the text streamed as a single chunk.
+func Text(text string) Reply {
+	return Chunks(text)
+}
+
+// Chunks returns a Reply streaming the texts as separate chunks.
+func Chunks(texts ...string) Reply {
+	reply := Reply{Chunks: make([]*chat.Response, len(texts))}
+	for i, text := range texts {
+		reply.Chunks[i] = &chat.Response{Generations: []chat.Generation{{Content: text}}}
+	}
+
+	return reply
+}
+
+// ToolCalls returns a Reply requesting the tool calls.
+func ToolCalls(toolCalls ...chat.ToolCall) Reply {
+	return Reply{Chunks: []*chat.Response{{Generations: []chat.Generation{{ToolCalls: toolCalls}}}}}
+}
+
+// Error returns a Reply failing with the error.
+func Error(err error) Reply {
+	return Reply{Err: err}
+}
+
+// WithUsage appends a final chunk reporting the usage.
+func (r Reply) WithUsage(usage chat.Usage) Reply {
+	r.Chunks = append(r.Chunks, &chat.Response{Usage: usage})
+	return r
+}
+
+// WithFinishReason appends a final chunk reporting the finish reason.
+func (r Reply) WithFinishReason(reason chat.FinishReason) Reply {
+	r.Chunks = append(r.Chunks, &chat.Response{Generations: []chat.Generation{{FinishReason: reason}}})
+	return r
+}
+
+// WithLatency delays the reply.
+func (r Reply) WithLatency(latency time.Duration) Reply {
+	r.Latency = latency
+	return r
+}
+
+// ChatModel a fake goai.ChatModel answering with queued replies, it records
+// every prompt it receives.
+type ChatModel struct {
+	mu      sync.Mutex
+	model   string
+	replies []Reply
+	prompts []prompt.Prompt
+
+	// Fallback the reply used once the queue is empty, nil fails with ErrNoReply.
+	Fallback *Reply
+}
+
+// NewChatModel returns a fake ChatModel answering with the replies in order.
+func NewChatModel(replies ...Reply) *ChatModel {
+	return &ChatModel{
+		model:   "goaitest",
+		replies: replies,
+	}
+}
+
+// Enqueue appends replies to the queue.
+func (m *ChatModel) Enqueue(replies ...Reply) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.replies = append(m.replies, replies...)
+}
+
+// Prompts returns all prompts received so far.
+func (m *ChatModel) Prompts() []prompt.Prompt {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]prompt.Prompt(nil), m.prompts...)
+}
+
+// LastPrompt returns the prompt received last.
+func (m *ChatModel) LastPrompt() prompt.Prompt {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.prompts) == 0 {
+		return prompt.Prompt{}
+	}
+
+	return m.prompts[len(m.prompts)-1]
+}
+
+// Pending returns the number of queued replies.
+func (m *ChatModel) Pending() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.replies)
+}
+
+func (m *ChatModel) next(p prompt.Prompt) (Reply, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.prompts = append(m.prompts, p)
+	if len(m.replies) == 0 {
+		if m.Fallback == nil {
+			return Reply{}, ErrNoReply
+		}
+		return *m.Fallback, nil
+	}
+
+	reply := m.replies[0]
+	m.replies = m.replies[1:]
+
+	return reply, nil
+}
+
+func (m *ChatModel) Call(ctx context.Context, p prompt.Prompt) (*chat.Response, error) {
+	reply, err := m.next(p)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = sleep(ctx, reply.Latency); err != nil {
+		return nil, err
+	}
+	if reply.Err != nil {
+		return nil, reply.Err
+	}
+
+	resp := &chat.Response{Model: m.model}
+	for _, chunk := range reply.Chunks {
+		resp.Append(chunk)
+	}
+
+	return resp, nil
+}
+
+func (m *ChatModel) Stream(ctx context.Context, p prompt.Prompt, fn func(*chat.Response) error) error {
+	reply, err := m.next(p)
+	if err != nil {
+		return err
+	}
+
+	if len(reply.Chunks) == 0 {
+		if err = sleep(ctx, reply.Latency); err != nil {
+			return err
+		}
+	}
+
+	for _, chunk := range reply.Chunks {
+		if err = sleep(ctx, reply.Latency); err != nil {
+			return err
+		}
+
+		c := *chunk
+		if c.Model == "" {
+			c.Model = m.model
+		}
+		if err = fn(&c); err != nil {
+			return err
+		}
+	}
+
+	return reply.Err
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,75 @@
+package goaitest
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/tech1024/goai"
+	"github.com/tech1024/goai/chat"
+)
+
+func TestChatModel(t *testing.T) {
+	boom := errors.New("boom")
+	model := NewChatModel(
+		Text("hello"),
+		Chunks("a", "b", "c").WithUsage(chat.Usage{TotalTokens: 3}),
+		ToolCalls(chat.ToolCall{ID: "1", Name: "weather", Arguments: `{"city":"Paris"}`}),
+		Error(boom),
+		Text("slow").WithLatency(time.Second),
+	)
+	recorder := NewChatRecorder(model)
+	c := goai.NewChat(recorder)
+	ctx := context.Background()
+
+	if got, err := c.Chat(ctx, "q1"); err != nil || got != "hello" {
+		t.Errorf("Chat() got = %q, error = %v", got, err)
+	}
+
+	var chunks []string
+	err := c.ChatStream(ctx, "q2", func(bts []byte) error {
+		chunks = append(chunks, string(bts))
+		return nil
+	})
+	if err != nil || !reflect.DeepEqual(chunks, []string{"a", "b", "c"}) {
+		t.Errorf("ChatStream() got = %v, error = %v", chunks, err)
+	}
+
+	resp, err := c.Call(ctx, model.LastPrompt())
+	if err != nil || len(resp.ToolCalls()) != 1 || resp.ToolCalls()[0].Name != "weather" {
+		t.Errorf("Call() got = %v, error = %v", resp, err)
+	}
+
+	if _, err = c.Chat(ctx, "q4"); !errors.Is(err, boom) {
+		t.Errorf("Chat() error = %v, wantErr %v", err, boom)
+	}
+
+	timeout, cancel := context.WithTimeout(ctx, time.Millisecond)
+	defer cancel()
+	if _, err = c.Chat(timeout, "q5"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Chat() error = %v, wantErr %v", err, context.DeadlineExceeded)
+	}
+
+	if _, err = c.Chat(ctx, "q6"); !errors.Is(err, ErrNoReply) {
+		t.Errorf("Chat() error = %v, wantErr %v", err, ErrNoReply)
+	}
+
+	calls := recorder.Calls()
+	if len(calls) != 6 || calls[1].Prompt.Messages[0].Text() != "q2" || calls[1].Response.Usage.TotalTokens != 3 {
+		t.Errorf("Calls() got = %v", calls)
+	}
+}
+
+func TestEmbeddingModel(t *testing.T) {
+	e := goai.NewEmbedding(NewEmbeddingModel(8))
+	vectors, err := e.Embeds(context.Background(), "a", "b", "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(vectors[0]) != 8 || !reflect.DeepEqual(vectors[0], vectors[2]) || reflect.DeepEqual(vectors[0], vectors[1]) {
+		t.Errorf("Embeds() got = %v", vectors)
+	}
+}
@@ -0,0 +1,87 @@
+package goaitest
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"sync"
+
+	"github.com/tech1024/goai/embedding"
+)
+
+// EmbeddingModel a fake goai.EmbeddingModel returning deterministic vectors
+// derived from the hash of each input, equal texts get equal vectors.
+type EmbeddingModel struct {
+	mu         sync.Mutex
+	dimensions int
+	requests   []embedding.Request
+
+	// Err the error returned by every call, if set.
+	Err error
+}
+
+// NewEmbeddingModel returns a fake EmbeddingModel of the dimensions, which
+// embedding.Option.Dimensions overrides per request.
+func NewEmbeddingModel(dimensions int) *EmbeddingModel {
+	return &EmbeddingModel{dimensions: dimensions}
+}
+
+// Requests returns all requests received so far.
+func (m *EmbeddingModel) Requests() []embedding.Request {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]embedding.Request(nil), m.requests...)
+}
+
+func (m *EmbeddingModel) Call(ctx context.Context, request embedding.Request) (embedding.Response, error) {
+	m.mu.Lock()
+	m.requests = append(m.requests, request)
+	m.mu.Unlock()
+
+	var response embedding.Response
+	if m.Err != nil {
+		return response, m.Err
+	}
+	if err := ctx.Err(); err != nil {
+		return response, err
+	}
+
+	dimensions := m.dimensions
+	if request.Option.Dimensions > 0 {
+		dimensions = request.Option.Dimensions
+	}
+
+	response.Embeddings = make([]embedding.Embedding, len(request.Inputs))
+	for i, input := range request.Inputs {
+		response.Embeddings[i] = embedding.Embedding{
+			Embedding: Vector(input, dimensions),
+			Index:     i,
+		}
+	}
+
+	return response, nil
+}
+
+// Vector returns the normalized deterministic vector of the text.
+func Vector(text string, dimensions int) []float32 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(text))
+	r := rand.New(rand.NewSource(int64(h.Sum64())))
+
+	vector := make([]float32, dimensions)
+	var norm float64
+	for i := range vector {
+		v := r.NormFloat64()
+		vector[i] = float32(v)
+		norm += v * v
+	}
+
+	norm = math.Sqrt(norm)
+	for i := range vector {
+		vector[i] = float32(float64(vector[i]) / norm)
+	}
+
+	return vector
+}
@@ -0,0 +1,30 @@
+package goaitest
+
+import (
+	"errors"
+	"net/http"
+)
+
+// StatusError an error the fake servers answer with its HTTP status code.
+type StatusError struct {
+	Code    int
+	Message string
+}
+
+func (e *StatusError) Error() string {
+	return e.Message
+}
+
+func (e *StatusError) StatusCode() int {
+	return e.Code
+}
+
+// HTTPStatus returns the status code of the error, 500 unless it carries one.
+func HTTPStatus(err error) int {
+	var statusErr interface{ StatusCode() int }
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode()
+	}
+
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,101 @@
+package goaitest
+
+import (
+	"context"
+	"sync"
+
+	"github.com/tech1024/goai"
+	"github.com/tech1024/goai/chat"
+	"github.com/tech1024/goai/prompt"
+)
+
+// ChatCall a call recorded by the ChatRecorder.
+type ChatCall struct {
+	Prompt prompt.Prompt
+	Stream bool
+
+	// Response the response, accumulated from the chunks of a stream.
+	Response *chat.Response
+	Err      error
+}
+
+// ChatRecorder a goai.ChatModel recording every call to the wrapped model.
+type ChatRecorder struct {
+	mu    sync.Mutex
+	next  goai.ChatModel
+	calls []ChatCall
+}
+
+// NewChatRecorder returns a ChatRecorder wrapping the chatModel.
+func NewChatRecorder(chatModel goai.ChatModel) *ChatRecorder {
+	return &ChatRecorder{next: chatModel}
+}
+
+// Record a middleware recording the calls with the recorder, it replaces
+// the model the recorder wraps.
+func Record(recorder *ChatRecorder) goai.ChatModelMiddleware {
+	return func(next goai.ChatModel) goai.ChatModel {
+		recorder.next = next
+		return recorder
+	}
+}
+
+// Calls returns all calls recorded so far.
+func (r *ChatRecorder) Calls() []ChatCall {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]ChatCall(nil), r.calls...)
+}
+
+// Prompts returns the prompts of all calls recorded so far.
+func (r *ChatRecorder) Prompts() []prompt.Prompt {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	prompts := make([]prompt.Prompt, len(r.calls))
+	for i, call := range r.calls {
+		prompts[i] = call.Prompt
+	}
+
+	return prompts
+}
+
+func (r *ChatRecorder) record(call ChatCall) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.calls = append(r.calls, call)
+}
+
+func (r *ChatRecorder) Call(ctx context.Context, p prompt.Prompt) (*chat.Response, error) {
+	resp, err := r.next.Call(ctx, p)
+	r.record(ChatCall{Prompt: p, Response: resp, Err: err})
+
+	return resp, err
+}
+
+func (r *ChatRecorder) Stream(ctx context.Context, p prompt.Prompt, fn func(*chat.Response) error) error {
+	resp := &chat.Response{}
+	err := r.next.Stream(ctx, p, func(chunk *chat.Response) error {
+		resp.Append(chunk)
+		return fn(chunk)
+	})
+	r.record(ChatCall{Prompt: p, Stream: true, Response: resp, Err: err})
+
+	return err
+}
+
+// Stream streams the prompt with the chat model, it returns the response
+// accumulated from the chunks and the chunks.
+func Stream(ctx context.Context, chatModel goai.ChatModel, p prompt.Prompt) (*chat.Response, []*chat.Response, error) {
+	resp := &chat.Response{}
+	var chunks []*chat.Response
+	err := chatModel.Stream(ctx, p, func(chunk *chat.Response) error {
+		resp.Append(chunk)
+		chunks = append(chunks, chunk)
+		return nil
+	})
+
+	return resp, chunks, err
+}
@@ -0,0 +1,152 @@
+package goaitest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+)
+
+// HTTPRequest a request received by a fake server.
+type HTTPRequest struct {
+	Method string
+	Path   string
+	Header http.Header
+	Body   []byte
+}
+
+// RequestLog records the requests received by a fake server.
+type RequestLog struct {
+	mu       sync.Mutex
+	requests []HTTPRequest
+}
+
+// Wrap returns a handler recording every request before passing it to next.
+func (l *RequestLog) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		l.mu.Lock()
+		l.requests = append(l.requests, HTTPRequest{
+			Method: r.Method,
+			Path:   r.URL.Path,
+			Header: r.Header.Clone(),
+			Body:   body,
+		})
+		l.mu.Unlock()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Requests returns all requests received so far.
+func (l *RequestLog) Requests() []HTTPRequest {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return append([]HTTPRequest(nil), l.requests...)
+}
+
+// Server a fake HTTP server recording the requests it receives, the fake
+// servers of the providers embed it and register their endpoints.
+type Server struct {
+	*httptest.Server
+	RequestLog
+
+	mux *http.ServeMux
+}
+
+// NewServer starts a Server without any endpoint.
+func NewServer() *Server {
+	s := &Server{mux: http.NewServeMux()}
+	s.Server = httptest.NewServer(s.RequestLog.Wrap(s.mux))
+
+	return s
+}
+
+// HandleFunc registers a handler, e.g. to script an endpoint the fake does
+// not implement.
+func (s *Server) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) {
+	s.mux.HandleFunc(pattern, handler)
+}
+
+// WriteJSON writes the value as the JSON body of a response with the code.
+func WriteJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// EventStream writes server-sent events, the status and the headers of the
+// response are written with the first event.
+type EventStream struct {
+	// LineBreak ends the lines of an event, defaults to "\n".
+	LineBreak string
+
+	w       http.ResponseWriter
+	started bool
+}
+
+// NewEventStream returns an EventStream writing to the response.
+func NewEventStream(w http.ResponseWriter) *EventStream {
+	return &EventStream{w: w}
+}
+
+// Started reports whether an event has been sent, an error can only be
+// reported by an event then.
+func (s *EventStream) Started() bool {
+	return s.started
+}
+
+// Send sends the value as the JSON data of an event, its name is omitted
+// if empty.
+func (s *EventStream) Send(event string, v any) error {
+	bts, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	return s.send(event, bts)
+}
+
+// Done sends the [DONE] data ending an OpenAI stream.
+func (s *EventStream) Done() error {
+	return s.send("", []byte("[DONE]"))
+}
+
+func (s *EventStream) send(event string, data []byte) error {
+	if !s.started {
+		s.started = true
+		s.w.Header().Set("Content-Type", "text/event-stream")
+		s.w.WriteHeader(http.StatusOK)
+	}
+
+	lineBreak := s.LineBreak
+	if lineBreak == "" {
+		lineBreak = "\n"
+	}
+	if event != "" {
+		if _, err := fmt.Fprintf(s.w, "event: %s%s", event, lineBreak); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintf(s.w, "data: %s%s%s", data, lineBreak, lineBreak); err != nil {
+		return err
+	}
+	if flusher, ok := s.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+
+	return nil
+}
+
+// Fragments splits the arguments of a tool call like the streaming APIs do,
+// into an empty first fragment and two halves.
+func Fragments(arguments string) []string {
+	half := len(arguments) / 2
+	return []string{"", arguments[:half], arguments[half:]}
+}
@@ -0,0 +1,146 @@
+package goai
+
+import (
+	"context"
+	"time"
+
+	"github.com/tech1024/goai/chat"
+	"github.com/tech1024/goai/prompt"
+)
+
+// CallInfo describes a call of a Chat, it is shared by all events of the call.
+type CallInfo struct {
+	Prompt prompt.Prompt
+
+	// Stream reports whether the response is streamed.
+	Stream bool
+
+	// Start the time the call started.
+	Start time.Time
+
+	// FirstChunk the time the first chunk of a stream has been received.
+	FirstChunk time.Time
+
+	// End the time the call finished.
+	End time.Time
+
+	// Response the response, accumulated from the chunks of a stream.
+	Response *chat.Response
+}
+
+// Latency returns the total duration of the call.
+func (i *CallInfo) Latency() time.Duration {
+	if i.End.IsZero() {
+		return time.Since(i.Start)
+	}
+
+	return i.End.Sub(i.Start)
+}
+
+// TimeToFirstToken returns the time until the first chunk of a stream,
+// or the latency of a call which is not streamed.
+func (i *CallInfo) TimeToFirstToken() time.Duration {
+	if i.FirstChunk.IsZero() {
+		return i.Latency()
+	}
+
+	return i.FirstChunk.Sub(i.Start)
+}
+
+// TokensPerSecond returns the completion throughput, preferring the
+// generation duration reported by the provider.
+func (i *CallInfo) TokensPerSecond() float64 {
+	if i.Response == nil || i.Response.Usage.CompletionTokens == 0 {
+		return 0
+	}
+
+	duration := i.Response.Timing.CompletionDuration
+	if duration <= 0 && !i.FirstChunk.IsZero() {
+		duration = i.End.Sub(i.FirstChunk)
+	}
+	if duration <= 0 {
+		duration = i.Latency()
+	}
+	if duration <= 0 {
+		return 0
+	}
+
+	return float64(i.Response.Usage.CompletionTokens) / duration.Seconds()
+}
+
+// Hook receives the events of the calls of a Chat.
+type Hook interface {
+	// OnStart is called before the request is sent, the returned context is
+	// passed to the model and all later events of the call.
+	OnStart(ctx context.Context, info *CallInfo) context.Context
+
+	// OnFirstChunk is called when the first chunk of a stream arrives.
+	OnFirstChunk(ctx context.Context, info *CallInfo)
+
+	// OnChunk is called for every chunk of a stream.
+	OnChunk(ctx context.Context, info *CallInfo, chunk *chat.Response)
+
+	// OnToolCall is called for every tool call requested by the model.
+	OnToolCall(ctx context.Context, info *CallInfo, toolCall chat.ToolCall)
+
+	// OnEnd is called when the call succeeded.
+	OnEnd(ctx context.Context, info *CallInfo)
+
+	// OnError is called when the call failed.
+	OnError(ctx context.Context, info *CallInfo, err error)
+}
+
+// NopHook a Hook doing nothing, embed it to implement only some events.
+type NopHook struct{}
+
+func (NopHook) OnStart(ctx context.Context, _ *CallInfo) context.Context { return ctx }
+
+func (NopHook) OnFirstChunk(context.Context, *CallInfo) {}
+
+func (NopHook) OnChunk(context.Context, *CallInfo, *chat.Response) {}
+
+func (NopHook) OnToolCall(context.Context, *CallInfo, chat.ToolCall) {}
+
+func (NopHook) OnEnd(context.Context, *CallInfo) {}
+
+func (NopHook) OnError(context.Context, *CallInfo, error) {}
+
+type hooks []Hook
+
+func (hs hooks) OnStart(ctx context.Context, info *CallInfo) context.Context {
+	for _, h := range hs {
+		ctx = h.OnStart(ctx, info)
+	}
+
+	return ctx
+}
+
+func (hs hooks) OnFirstChunk(ctx context.Context, info *CallInfo) {
+	for _, h := range hs {
+		h.OnFirstChunk(ctx, info)
+	}
+}
+
+func (hs hooks) OnChunk(ctx context.Context, info *CallInfo, chunk *chat.Response) {
+	for _, h := range hs {
+		h.OnChunk(ctx, info, chunk)
+	}
+}
+
+func (hs hooks) OnToolCall(ctx context.Context, info *CallInfo, toolCall chat.ToolCall) {
+	for _, h := range hs {
+		h.OnToolCall(ctx, info, toolCall)
+	}
+}
+
+func (hs hooks) OnEnd(ctx context.Context, info *CallInfo) {
+	for _, h := range hs {
+		h.OnEnd(ctx, info)
+	}
+}
+
+func (hs hooks) OnError(ctx context.Context, info *CallInfo, err error) {
+	for _, h := range hs {
+		h.OnError(ctx, info, err)
+	}
+}
@@ -0,0 +1,36 @@
+// Package wire holds the helpers of the HTTP APIs shared by the providers, e.g.
+// the snippets of error bodies.
+package wire
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxSnippetSize the maximum number of bytes of a body kept in an error.
+const MaxSnippetSize = 512
+
+// ReadErrorBody reads the beginning of the body of an error response, it
+// returns the bytes to decode the error of the API from and their snippet.
+func ReadErrorBody(httpResp *http.Response) ([]byte, string) {
+	bts, _ := io.ReadAll(io.LimitReader(httpResp.Body, 64*1024))
+
+	return bts, Snippet(bts)
+}
+
+// Snippet returns the beginning of the body for error messages.
+func Snippet(bts []byte) string {
+	s := strings.TrimSpace(string(bts))
+	if len(s) <= MaxSnippetSize {
+		return s
+	}
+
+	s = s[:MaxSnippetSize]
+	for !utf8.ValidString(s) {
+		s = s[:len(s)-1]
+	}
+
+	return s + "..."
+}
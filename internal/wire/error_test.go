@@ -0,0 +1,18 @@
+package wire
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSnippet(t *testing.T) {
+	if got := Snippet([]byte("  bad gateway\n")); got != "bad gateway" {
+		t.Errorf("Snippet() got = %q", got)
+	}
+
+	got := Snippet([]byte("a" + strings.Repeat("é", MaxSnippetSize)))
+	if !strings.HasSuffix(got, "...") || len(got) > MaxSnippetSize+3 || !utf8.ValidString(got) {
+		t.Errorf("Snippet() got = %q", got)
+	}
+}
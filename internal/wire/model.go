@@ -0,0 +1,11 @@
+package wire
+
+// ModelOf returns the model reported by the server, the requested model if
+// the server omits it like some compatible servers do.
+func ModelOf(reported, requested string) string {
+	if reported != "" {
+		return reported
+	}
+
+	return requested
+}
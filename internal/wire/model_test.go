@@ -0,0 +1,12 @@
+package wire
+
+import "testing"
+
+func TestModelOf(t *testing.T) {
+	if got := ModelOf("gpt-4o-2024-08-06", "gpt-4o"); got != "gpt-4o-2024-08-06" {
+		t.Errorf("ModelOf() got = %q", got)
+	}
+	if got := ModelOf("", "gpt-4o"); got != "gpt-4o" {
+		t.Errorf("ModelOf() without a reported model got = %q", got)
+	}
+}
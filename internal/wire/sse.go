@@ -0,0 +1,44 @@
+package wire
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+// ReadEvents reads the server-sent events of the body, fn receives the data
+// of each event.
+func ReadEvents(body io.Reader, fn func(data []byte) error) error {
+	reader := bufio.NewReader(body)
+	var data bytes.Buffer
+
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+
+		line = bytes.TrimRight(line, "\r\n")
+		switch {
+		case len(line) == 0:
+			if data.Len() > 0 {
+				if err := fn(data.Bytes()); err != nil {
+					return err
+				}
+				data.Reset()
+			}
+		case bytes.HasPrefix(line, []byte("data:")):
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.Write(bytes.TrimPrefix(bytes.TrimPrefix(line, []byte("data:")), []byte(" ")))
+		}
+
+		if err == io.EOF {
+			if data.Len() > 0 {
+				return fn(data.Bytes())
+			}
+			return nil
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package wire
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadEvents(t *testing.T) {
+	body := "event: message\r\ndata: {\"a\":1}\r\n\r\n: comment\ndata: line 1\ndata:line 2\n\n\ndata: last"
+
+	var events []string
+	err := ReadEvents(strings.NewReader(body), func(data []byte) error {
+		events = append(events, string(data))
+		return nil
+	})
+	want := []string{`{"a":1}`, "line 1\nline 2", "last"}
+	if err != nil || !reflect.DeepEqual(events, want) {
+		t.Errorf("ReadEvents() got = %q, error = %v", events, err)
+	}
+
+	stop := errors.New("stop")
+	if err = ReadEvents(strings.NewReader(body), func([]byte) error { return stop }); !errors.Is(err, stop) {
+		t.Errorf("ReadEvents() error = %v, want %v", err, stop)
+	}
+}
@@ -0,0 +1,70 @@
+package wire
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/tech1024/goai/chat"
+)
+
+// ToolCallAssembler assembles the tool call fragments of a streamed choice of
+// the OpenAI-compatible APIs.
+type ToolCallAssembler struct {
+	// Err the error wrapped when the arguments of a call are no valid JSON.
+	Err error
+
+	calls []chat.ToolCall
+}
+
+// Add adds a fragment, it returns the fragment with its resolved index.
+func (a *ToolCallAssembler) Add(index *int, id, name, arguments string) chat.ToolCallDelta {
+	// compatible servers may omit the index, a fragment with an id starts a new call.
+	i := len(a.calls) - 1
+	if index != nil {
+		i = *index
+	} else if id != "" || i < 0 {
+		i = len(a.calls)
+	}
+
+	for len(a.calls) <= i {
+		a.calls = append(a.calls, chat.ToolCall{})
+	}
+
+	call := &a.calls[i]
+	if id != "" {
+		call.ID = id
+	}
+	if name != "" {
+		call.Name = name
+	}
+	call.Arguments += arguments
+
+	return chat.ToolCallDelta{
+		Index:     i,
+		ID:        id,
+		Name:      name,
+		Arguments: arguments,
+	}
+}
+
+// Pending reports whether fragments have been added since the last Complete.
+func (a *ToolCallAssembler) Pending() bool {
+	return len(a.calls) > 0
+}
+
+// Complete returns the assembled calls and resets the assembler.
+func (a *ToolCallAssembler) Complete() ([]chat.ToolCall, error) {
+	calls := a.calls
+	a.calls = nil
+
+	for i := range calls {
+		if calls[i].Arguments == "" {
+			calls[i].Arguments = "{}"
+		}
+		if !json.Valid([]byte(calls[i].Arguments)) {
+			return nil, fmt.Errorf("%w: %s: %s", a.Err, calls[i].Name, calls[i].Arguments)
+		}
+	}
+
+	return calls, nil
+}
@@ -0,0 +1,33 @@
+package wire
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/tech1024/goai/chat"
+)
+
+func TestToolCallAssembler(t *testing.T) {
+	errInvalid := errors.New("invalid")
+	a := &ToolCallAssembler{Err: errInvalid}
+
+	one := 1
+	a.Add(nil, "call_1", "weather", `{"city":`)
+	a.Add(nil, "", "", `"Paris"}`)
+	delta := a.Add(&one, "call_2", "now", "")
+	if delta != (chat.ToolCallDelta{Index: 1, ID: "call_2", Name: "now"}) || !a.Pending() {
+		t.Errorf("Add() got = %+v", delta)
+	}
+
+	calls, err := a.Complete()
+	want := []chat.ToolCall{{ID: "call_1", Name: "weather", Arguments: `{"city":"Paris"}`}, {ID: "call_2", Name: "now", Arguments: "{}"}}
+	if err != nil || !reflect.DeepEqual(calls, want) || a.Pending() {
+		t.Errorf("Complete() got = %+v, error = %v", calls, err)
+	}
+
+	a.Add(nil, "call_3", "weather", `{"city"`)
+	if _, err = a.Complete(); !errors.Is(err, errInvalid) {
+		t.Errorf("Complete() error = %v, want %v", err, errInvalid)
+	}
+}
@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+)
+
+// Error categories used as the category label of failed requests.
+const (
+	CategoryCanceled       = "canceled"
+	CategoryTimeout        = "timeout"
+	CategoryNetwork        = "network"
+	CategoryAuth           = "auth"
+	CategoryRateLimit      = "rate_limit"
+	CategoryInvalidRequest = "invalid_request"
+	CategoryServer         = "server"
+	CategoryOther          = "other"
+)
+
+// Classify returns the category of the error, errors carrying the HTTP status
+// of the response are recognized by a StatusCode() int method.
+func Classify(err error) string {
+	var statusErr interface{ StatusCode() int }
+	var netErr net.Error
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		return CategoryCanceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return CategoryTimeout
+	case errors.As(err, &statusErr):
+		return classifyStatus(statusErr.StatusCode())
+	case errors.As(err, &netErr):
+		if netErr.Timeout() {
+			return CategoryTimeout
+		}
+		return CategoryNetwork
+	}
+
+	return CategoryOther
+}
+
+func classifyStatus(code int) string {
+	switch {
+	case code == http.StatusUnauthorized || code == http.StatusForbidden:
+		return CategoryAuth
+	case code == http.StatusTooManyRequests:
+		return CategoryRateLimit
+	case code == http.StatusRequestTimeout || code == http.StatusGatewayTimeout:
+		return CategoryTimeout
+	case code >= http.StatusInternalServerError:
+		return CategoryServer
+	case code >= http.StatusBadRequest:
+		return CategoryInvalidRequest
+	}
+
+	return CategoryOther
+}
@@ -0,0 +1,37 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/tech1024/goai/goaitest"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"canceled", fmt.Errorf("call: %w", context.Canceled), CategoryCanceled},
+		{"deadline", context.DeadlineExceeded, CategoryTimeout},
+		{"status", &goaitest.StatusError{Code: http.StatusTooManyRequests}, CategoryRateLimit},
+		{"status wrapped", fmt.Errorf("chat: %w", &goaitest.StatusError{Code: http.StatusUnauthorized}), CategoryAuth},
+		{"status bad request", &goaitest.StatusError{Code: http.StatusBadRequest}, CategoryInvalidRequest},
+		{"status server", &goaitest.StatusError{Code: http.StatusBadGateway}, CategoryServer},
+		{"network timeout", &net.DNSError{IsTimeout: true}, CategoryTimeout},
+		{"network", &net.DNSError{}, CategoryNetwork},
+		{"other", errors.New("boom"), CategoryOther},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Classify(tt.err); got != tt.want {
+				t.Errorf("Classify() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/tech1024/goai"
+	"github.com/tech1024/goai/chat"
+	"github.com/tech1024/goai/embedding"
+	"github.com/tech1024/goai/prompt"
+)
+
+// Metrics records the requests of chat and embedding models, it is an
+// http.Handler serving them in the Prometheus text exposition format.
+type Metrics struct {
+	requests         *family
+	errors           *family
+	duration         *family
+	timeToFirstToken *family
+	promptTokens     *family
+	completionTokens *family
+
+	// Classify maps an error to its category label, defaults to Classify.
+	Classify func(error) string
+}
+
+// New returns Metrics whose histograms use the buckets, DefaultBuckets if none.
+func New(buckets ...float64) *Metrics {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+
+	return &Metrics{
+		requests: newFamily("goai_requests_total", "Total number of model requests.",
+			"counter", nil, "operation", "provider", "model"),
+		errors: newFamily("goai_request_errors_total", "Total number of failed model requests by error category.",
+			"counter", nil, "operation", "provider", "model", "category"),
+		duration: newFamily("goai_request_duration_seconds", "Latency of model requests in seconds.",
+			"histogram", buckets, "operation", "provider", "model"),
+		timeToFirstToken: newFamily("goai_time_to_first_token_seconds", "Time to the first chunk of streamed chat requests in seconds.",
+			"histogram", buckets, "provider", "model"),
+		promptTokens: newFamily("goai_prompt_tokens_total", "Total number of prompt tokens.",
+			"counter", nil, "operation", "provider", "model"),
+		completionTokens: newFamily("goai_completion_tokens_total", "Total number of completion tokens.",
+			"counter", nil, "operation", "provider", "model"),
+		Classify: Classify,
+	}
+}
+
+// ServeHTTP writes the metrics in the Prometheus text exposition format.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	var buf bytes.Buffer
+	for _, f := range []*family{m.requests, m.errors, m.duration, m.timeToFirstToken, m.promptTokens, m.completionTokens} {
+		if err := f.write(&buf); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = w.Write(buf.Bytes())
+}
+
+func (m *Metrics) record(operation, provider, model string, start time.Time, usage chat.Usage, err error) {
+	m.requests.add(1, operation, provider, model)
+	m.duration.observe(time.Since(start).Seconds(), operation, provider, model)
+	if err != nil {
+		m.errors.add(1, operation, provider, model, m.Classify(err))
+		return
+	}
+
+	m.promptTokens.add(float64(usage.PromptTokens), operation, provider, model)
+	m.completionTokens.add(float64(usage.CompletionTokens), operation, provider, model)
+}
+
+// ChatModel a middleware recording the calls of a chat model of the provider.
+func (m *Metrics) ChatModel(provider string) goai.ChatModelMiddleware {
+	return func(next goai.ChatModel) goai.ChatModel {
+		return &chatModel{next: next, metrics: m, provider: provider}
+	}
+}
+
+// EmbeddingModel a middleware recording the calls of an embedding model of the provider.
+func (m *Metrics) EmbeddingModel(provider string) goai.EmbeddingModelMiddleware {
+	return func(next goai.EmbeddingModel) goai.EmbeddingModel {
+		return &embeddingModel{next: next, metrics: m, provider: provider}
+	}
+}
+
+type chatModel struct {
+	next     goai.ChatModel
+	metrics  *Metrics
+	provider string
+}
+
+func (cm *chatModel) Call(ctx context.Context, p prompt.Prompt) (*chat.Response, error) {
+	start := time.Now()
+	resp, err := cm.next.Call(ctx, p)
+
+	var usage chat.Usage
+	model := p.ChatOption.Model
+	if resp != nil {
+		usage = resp.Usage
+		if resp.Model != "" {
+			model = resp.Model
+		}
+	}
+	cm.metrics.record("chat", cm.provider, model, start, usage, err)
+
+	return resp, err
+}
+
+func (cm *chatModel) Stream(ctx context.Context, p prompt.Prompt, fn func(*chat.Response) error) error {
+	start := time.Now()
+	model := p.ChatOption.Model
+	var firstChunk bool
+	var usage chat.Usage
+
+	err := cm.next.Stream(ctx, p, func(chunk *chat.Response) error {
+		if chunk.Model != "" {
+			model = chunk.Model
+		}
+		if !firstChunk {
+			firstChunk = true
+			cm.metrics.timeToFirstToken.observe(time.Since(start).Seconds(), cm.provider, model)
+		}
+		if !chunk.Usage.IsZero() {
+			usage = chunk.Usage
+		}
+
+		return fn(chunk)
+	})
+	cm.metrics.record("chat", cm.provider, model, start, usage, err)
+
+	return err
+}
+
+type embeddingModel struct {
+	next     goai.EmbeddingModel
+	metrics  *Metrics
+	provider string
+}
+
+func (em *embeddingModel) Call(ctx context.Context, request embedding.Request) (embedding.Response, error) {
+	start := time.Now()
+	resp, err := em.next.Call(ctx, request)
+
+	model := request.Option.Model
+	if resp.Model != "" {
+		model = resp.Model
+	}
+	em.metrics.record("embedding", em.provider, model, start, resp.Usage, err)
+
+	return resp, err
+}
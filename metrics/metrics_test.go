@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tech1024/goai"
+	"github.com/tech1024/goai/chat"
+	"github.com/tech1024/goai/prompt"
+)
+
+type stubChatModel struct {
+	err error
+}
+
+func (m stubChatModel) Call(context.Context, prompt.Prompt) (*chat.Response, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	return &chat.Response{Model: "llama3", Usage: chat.Usage{PromptTokens: 3, CompletionTokens: 5, TotalTokens: 8}}, nil
+}
+
+func (m stubChatModel) Stream(ctx context.Context, p prompt.Prompt, fn func(*chat.Response) error) error {
+	resp, err := m.Call(ctx, p)
+	if err != nil {
+		return err
+	}
+
+	return fn(resp)
+}
+
+func TestMetrics(t *testing.T) {
+	m := New(1)
+	ok := goai.WrapChatModel(stubChatModel{}, m.ChatModel("ollama"))
+	failed := goai.WrapChatModel(stubChatModel{err: context.DeadlineExceeded}, m.ChatModel("ollama"))
+
+	_, _ = ok.Call(context.Background(), prompt.NewPrompt())
+	_ = ok.Stream(context.Background(), prompt.NewPrompt(), func(*chat.Response) error { return nil })
+	_, _ = failed.Call(context.Background(), prompt.Prompt{ChatOption: prompt.Option{Model: "llama3"}})
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	for _, want := range []string{
+		"# TYPE goai_requests_total counter\n",
+		`goai_requests_total{operation="chat",provider="ollama",model="llama3"} 3`,
+		`goai_request_errors_total{operation="chat",provider="ollama",model="llama3",category="timeout"} 1`,
+		`goai_request_duration_seconds_bucket{operation="chat",provider="ollama",model="llama3",le="+Inf"} 3`,
+		`goai_time_to_first_token_seconds_count{provider="ollama",model="llama3"} 1`,
+		`goai_completion_tokens_total{operation="chat",provider="ollama",model="llama3"} 10`,
+	} {
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("ServeHTTP() missing %q in\n%s", want, rec.Body.String())
+		}
+	}
+}
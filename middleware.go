@@ -0,0 +1,150 @@
+package goai
+
+import (
+	"context"
+
+	"github.com/tech1024/goai/chat"
+	"github.com/tech1024/goai/embedding"
+	"github.com/tech1024/goai/prompt"
+)
+
+// ChatModelMiddleware wraps a ChatModel to extend its behavior.
+type ChatModelMiddleware func(ChatModel) ChatModel
+
+// WrapChatModel wraps the chatModel with the middlewares, the first middleware
+// is the outermost one.
+func WrapChatModel(chatModel ChatModel, middlewares ...ChatModelMiddleware) ChatModel {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		chatModel = middlewares[i](chatModel)
+	}
+
+	return chatModel
+}
+
+// EmbeddingModelMiddleware wraps an EmbeddingModel to extend its behavior.
+type EmbeddingModelMiddleware func(EmbeddingModel) EmbeddingModel
+
+// WrapEmbeddingModel wraps the embeddingModel with the middlewares, the first
+// middleware is the outermost one.
+func WrapEmbeddingModel(embeddingModel EmbeddingModel, middlewares ...EmbeddingModelMiddleware) EmbeddingModel {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		embeddingModel = middlewares[i](embeddingModel)
+	}
+
+	return embeddingModel
+}
+
+// FitPrompt a middleware fitting every prompt into the context window
+// before it is sent to the model.
+func FitPrompt(fitter *prompt.Fitter) ChatModelMiddleware {
+	return func(next ChatModel) ChatModel {
+		return &fitChatModel{next: next, fitter: fitter}
+	}
+}
+
+type fitChatModel struct {
+	next   ChatModel
+	fitter *prompt.Fitter
+}
+
+func (m *fitChatModel) Call(ctx context.Context, p prompt.Prompt) (*chat.Response, error) {
+	p, _, err := m.fitter.Fit(p)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.next.Call(ctx, p)
+}
+
+func (m *fitChatModel) Stream(ctx context.Context, p prompt.Prompt, fn func(*chat.Response) error) error {
+	p, _, err := m.fitter.Fit(p)
+	if err != nil {
+		return err
+	}
+
+	return m.next.Stream(ctx, p, fn)
+}
+
+// DefaultChatOption a middleware filling the options a prompt leaves unset
+// with the defaults, the extension of a provider is only added if the prompt
+// has none for it.
+func DefaultChatOption(defaults prompt.Option) ChatModelMiddleware {
+	return func(next ChatModel) ChatModel {
+		return &defaultChatModel{next: next, defaults: defaults}
+	}
+}
+
+type defaultChatModel struct {
+	next     ChatModel
+	defaults prompt.Option
+}
+
+func (m *defaultChatModel) Call(ctx context.Context, p prompt.Prompt) (*chat.Response, error) {
+	return m.next.Call(ctx, m.apply(p))
+}
+
+func (m *defaultChatModel) Stream(ctx context.Context, p prompt.Prompt, fn func(*chat.Response) error) error {
+	return m.next.Stream(ctx, m.apply(p), fn)
+}
+
+func (m *defaultChatModel) apply(p prompt.Prompt) prompt.Prompt {
+	option := &p.ChatOption
+	if option.Model == "" {
+		option.Model = m.defaults.Model
+	}
+	if len(option.Tools) == 0 {
+		option.Tools = m.defaults.Tools
+	}
+	if option.Candidates == 0 {
+		option.Candidates = m.defaults.Candidates
+	}
+	if !option.LogProbs {
+		option.LogProbs = m.defaults.LogProbs
+	}
+	if option.TopLogProbs == 0 {
+		option.TopLogProbs = m.defaults.TopLogProbs
+	}
+	option.Extensions = defaultExtensions(option.Extensions, m.defaults.Extensions)
+
+	return p
+}
+
+// DefaultEmbeddingOption a middleware filling the options a request leaves
+// unset with the defaults, like DefaultChatOption.
+func DefaultEmbeddingOption(defaults embedding.Option) EmbeddingModelMiddleware {
+	return func(next EmbeddingModel) EmbeddingModel {
+		return &defaultEmbeddingModel{next: next, defaults: defaults}
+	}
+}
+
+type defaultEmbeddingModel struct {
+	next     EmbeddingModel
+	defaults embedding.Option
+}
+
+func (m *defaultEmbeddingModel) Call(ctx context.Context, request embedding.Request) (embedding.Response, error) {
+	option := &request.Option
+	if option.Model == "" {
+		option.Model = m.defaults.Model
+	}
+	if option.Dimensions == 0 {
+		option.Dimensions = m.defaults.Dimensions
+	}
+	option.Extensions = defaultExtensions(option.Extensions, m.defaults.Extensions)
+
+	return m.next.Call(ctx, request)
+}
+
+// defaultExtensions returns the extensions followed by the defaults of the
+// providers they have no extension for.
+func defaultExtensions(extensions, defaults []prompt.Extension) []prompt.Extension {
+	result := extensions
+	for _, extension := range defaults {
+		if (prompt.Option{Extensions: extensions}).Extension(extension.Provider()) == nil {
+			// the extensions of the prompt are never appended to in place.
+			result = append(result[:len(result):len(result)], extension)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,60 @@
+package goai_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/tech1024/goai"
+	"github.com/tech1024/goai/chat"
+	"github.com/tech1024/goai/embedding"
+	"github.com/tech1024/goai/goaitest"
+	"github.com/tech1024/goai/prompt"
+)
+
+func TestDefaultChatOption(t *testing.T) {
+	fake := goaitest.NewChatModel(goaitest.Text("a"), goaitest.Text("b"))
+	defaults := prompt.Option{Model: "default", TopLogProbs: 2, Extensions: []prompt.Extension{testExtension("a"), testExtension("b")}}
+	chatModel := goai.DefaultChatOption(defaults)(fake)
+
+	p := prompt.NewPrompt(prompt.UserMessage("hi"))
+	p.ChatOption.Model = "custom"
+	own := []prompt.Extension{testExtension("b")}
+	p.ChatOption.Extensions = own
+	if _, err := chatModel.Call(context.Background(), p); err != nil {
+		t.Fatal(err)
+	}
+
+	option := fake.LastPrompt().ChatOption
+	if option.Model != "custom" || option.TopLogProbs != 2 || !reflect.DeepEqual(option.Extensions, []prompt.Extension{testExtension("b"), testExtension("a")}) {
+		t.Errorf("Call() option = %+v", option)
+	}
+	if len(own) != 1 {
+		t.Errorf("Call() changed the extensions of the prompt")
+	}
+
+	err := chatModel.Stream(context.Background(), prompt.NewPrompt(prompt.UserMessage("hi")), func(*chat.Response) error { return nil })
+	if err != nil || fake.LastPrompt().ChatOption.Model != "default" {
+		t.Errorf("Stream() option = %+v, error = %v", fake.LastPrompt().ChatOption, err)
+	}
+}
+
+func TestDefaultEmbeddingOption(t *testing.T) {
+	fake := goaitest.NewEmbeddingModel(8)
+	embeddingModel := goai.DefaultEmbeddingOption(embedding.Option{Dimensions: 4})(fake)
+
+	resp, err := embeddingModel.Call(context.Background(), embedding.NewRequest([]string{"a"}, embedding.Option{}))
+	if err != nil || len(resp.Embeddings[0].Embedding) != 4 {
+		t.Errorf("Call() got = %+v, error = %v", resp, err)
+	}
+	resp, err = embeddingModel.Call(context.Background(), embedding.NewRequest([]string{"a"}, embedding.Option{Dimensions: 2}))
+	if err != nil || len(resp.Embeddings[0].Embedding) != 2 {
+		t.Errorf("Call() got = %+v, error = %v", resp, err)
+	}
+}
+
+type testExtension string
+
+func (e testExtension) Provider() string {
+	return string(e)
+}
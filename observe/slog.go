@@ -0,0 +1,69 @@
+package observe
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/tech1024/goai"
+	"github.com/tech1024/goai/chat"
+)
+
+// NewSlogHook returns a hook logging the calls with the logger, chunks
+// are logged at the debug level.
+func NewSlogHook(logger *slog.Logger) goai.Hook {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return &slogHook{logger: logger}
+}
+
+type slogHook struct {
+	logger *slog.Logger
+}
+
+func (h *slogHook) OnStart(ctx context.Context, info *goai.CallInfo) context.Context {
+	h.logger.DebugContext(ctx, "chat request",
+		slog.String("model", info.Prompt.ChatOption.Model),
+		slog.Int("messages", len(info.Prompt.Messages)),
+		slog.Bool("stream", info.Stream),
+	)
+
+	return ctx
+}
+
+func (h *slogHook) OnFirstChunk(ctx context.Context, info *goai.CallInfo) {
+	h.logger.DebugContext(ctx, "chat first chunk", slog.Duration("ttft", info.TimeToFirstToken()))
+}
+
+func (h *slogHook) OnChunk(ctx context.Context, _ *goai.CallInfo, chunk *chat.Response) {
+	h.logger.DebugContext(ctx, "chat chunk", slog.Int("length", len(chunk.Text())))
+}
+
+func (h *slogHook) OnToolCall(ctx context.Context, _ *goai.CallInfo, toolCall chat.ToolCall) {
+	h.logger.InfoContext(ctx, "chat tool call",
+		slog.String("id", toolCall.ID),
+		slog.String("name", toolCall.Name),
+	)
+}
+
+func (h *slogHook) OnEnd(ctx context.Context, info *goai.CallInfo) {
+	h.logger.InfoContext(ctx, "chat response",
+		slog.String("model", info.Response.Model),
+		slog.Bool("stream", info.Stream),
+		slog.Duration("latency", info.Latency()),
+		slog.Duration("ttft", info.TimeToFirstToken()),
+		slog.Int("prompt_tokens", info.Response.Usage.PromptTokens),
+		slog.Int("completion_tokens", info.Response.Usage.CompletionTokens),
+		slog.Float64("tokens_per_second", info.TokensPerSecond()),
+	)
+}
+
+func (h *slogHook) OnError(ctx context.Context, info *goai.CallInfo, err error) {
+	h.logger.ErrorContext(ctx, "chat error",
+		slog.String("model", info.Prompt.ChatOption.Model),
+		slog.Bool("stream", info.Stream),
+		slog.Duration("latency", info.Latency()),
+		slog.Any("error", err),
+	)
+}
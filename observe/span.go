@@ -0,0 +1,98 @@
+package observe
+
+import (
+	"context"
+
+	"github.com/tech1024/goai"
+	"github.com/tech1024/goai/chat"
+)
+
+// Attribute a key value pair attached to a span, the keys follow the
+// OpenTelemetry semantic conventions for generative AI.
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+// Span the subset of an OpenTelemetry span used by the span hook.
+type Span interface {
+	SetAttributes(attributes ...Attribute)
+	AddEvent(name string, attributes ...Attribute)
+	RecordError(err error)
+	End()
+}
+
+// Tracer starts spans, adapt an OpenTelemetry tracer to it to export the
+// calls without goai depending on an exporter.
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// NewSpanHook returns a hook recording every call as a span of the tracer.
+func NewSpanHook(tracer Tracer) goai.Hook {
+	return &spanHook{tracer: tracer}
+}
+
+type spanKey struct{}
+
+type spanHook struct {
+	tracer Tracer
+}
+
+func (h *spanHook) span(ctx context.Context) Span {
+	span, _ := ctx.Value(spanKey{}).(Span)
+	return span
+}
+
+func (h *spanHook) OnStart(ctx context.Context, info *goai.CallInfo) context.Context {
+	ctx, span := h.tracer.Start(ctx, "chat "+info.Prompt.ChatOption.Model)
+	span.SetAttributes(
+		Attribute{Key: "gen_ai.operation.name", Value: "chat"},
+		Attribute{Key: "gen_ai.request.model", Value: info.Prompt.ChatOption.Model},
+	)
+
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+func (h *spanHook) OnFirstChunk(ctx context.Context, info *goai.CallInfo) {
+	if span := h.span(ctx); span != nil {
+		span.AddEvent("gen_ai.first_chunk",
+			Attribute{Key: "gen_ai.server.time_to_first_token", Value: info.TimeToFirstToken().Seconds()},
+		)
+	}
+}
+
+func (h *spanHook) OnChunk(context.Context, *goai.CallInfo, *chat.Response) {}
+
+func (h *spanHook) OnToolCall(ctx context.Context, _ *goai.CallInfo, toolCall chat.ToolCall) {
+	if span := h.span(ctx); span != nil {
+		span.AddEvent("gen_ai.tool.call",
+			Attribute{Key: "gen_ai.tool.call.id", Value: toolCall.ID},
+			Attribute{Key: "gen_ai.tool.name", Value: toolCall.Name},
+		)
+	}
+}
+
+func (h *spanHook) OnEnd(ctx context.Context, info *goai.CallInfo) {
+	span := h.span(ctx)
+	if span == nil {
+		return
+	}
+
+	span.SetAttributes(
+		Attribute{Key: "gen_ai.response.id", Value: info.Response.ID},
+		Attribute{Key: "gen_ai.response.model", Value: info.Response.Model},
+		Attribute{Key: "gen_ai.usage.input_tokens", Value: info.Response.Usage.PromptTokens},
+		Attribute{Key: "gen_ai.usage.output_tokens", Value: info.Response.Usage.CompletionTokens},
+		Attribute{Key: "gen_ai.server.time_to_first_token", Value: info.TimeToFirstToken().Seconds()},
+		Attribute{Key: "gen_ai.server.tokens_per_second", Value: info.TokensPerSecond()},
+	)
+	span.End()
+}
+
+func (h *spanHook) OnError(ctx context.Context, _ *goai.CallInfo, err error) {
+	if span := h.span(ctx); span != nil {
+		span.RecordError(err)
+		span.End()
+	}
+}
@@ -0,0 +1,93 @@
+package observe
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/tech1024/goai"
+	"github.com/tech1024/goai/chat"
+	"github.com/tech1024/goai/prompt"
+)
+
+type recordSpan struct {
+	events []string
+	attrs  map[string]any
+	err    error
+	ended  bool
+}
+
+func (s *recordSpan) SetAttributes(attributes ...Attribute) {
+	for _, attr := range attributes {
+		s.attrs[attr.Key] = attr.Value
+	}
+}
+
+func (s *recordSpan) AddEvent(name string, _ ...Attribute) { s.events = append(s.events, name) }
+
+func (s *recordSpan) RecordError(err error) { s.err = err }
+
+func (s *recordSpan) End() { s.ended = true }
+
+type recordTracer struct {
+	spans []*recordSpan
+}
+
+func (t *recordTracer) Start(ctx context.Context, _ string) (context.Context, Span) {
+	span := &recordSpan{attrs: map[string]any{}}
+	t.spans = append(t.spans, span)
+	return ctx, span
+}
+
+type streamChatModel struct {
+	err error
+}
+
+func (m streamChatModel) Call(context.Context, prompt.Prompt) (*chat.Response, error) {
+	return nil, m.err
+}
+
+func (m streamChatModel) Stream(_ context.Context, _ prompt.Prompt, fn func(*chat.Response) error) error {
+	chunks := []*chat.Response{
+		{Model: "test-model", Generations: []chat.Generation{{Content: "hello"}}},
+		{Generations: []chat.Generation{{ToolCalls: []chat.ToolCall{{ID: "1", Name: "weather"}}}}},
+		{Usage: chat.Usage{PromptTokens: 3, CompletionTokens: 2, TotalTokens: 5}},
+	}
+	for _, chunk := range chunks {
+		if err := fn(chunk); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func TestSpanHook(t *testing.T) {
+	tracer := &recordTracer{}
+	c := goai.NewChat(streamChatModel{err: errors.New("boom")}, goai.WithHooks(NewSpanHook(tracer)))
+
+	var text string
+	err := c.ChatStream(context.Background(), "hi", func(bts []byte) error {
+		text += string(bts)
+		return nil
+	})
+	if err != nil || text != "hello" {
+		t.Fatalf("ChatStream() got = %q, error = %v", text, err)
+	}
+
+	span := tracer.spans[0]
+	if !span.ended || !reflect.DeepEqual(span.events, []string{"gen_ai.first_chunk", "gen_ai.tool.call"}) {
+		t.Errorf("span events = %v, ended = %v", span.events, span.ended)
+	}
+	if span.attrs["gen_ai.response.model"] != "test-model" || span.attrs["gen_ai.usage.output_tokens"] != 2 {
+		t.Errorf("span attributes = %v", span.attrs)
+	}
+
+	if _, err = c.Chat(context.Background(), "hi"); err == nil {
+		t.Fatal("Chat() error = nil")
+	}
+	if span = tracer.spans[1]; !span.ended || span.err == nil {
+		t.Errorf("span error = %v, ended = %v", span.err, span.ended)
+	}
+}
@@ -0,0 +1,251 @@
+package prompt
+
+import (
+	"errors"
+	"sort"
+	"unicode/utf8"
+)
+
+// ErrContextExceeded is returned by [Fitter.Fit] when the prompt still does not
+// fit into the context window after all strategies have been applied.
+var ErrContextExceeded = errors.New("prompt exceeds the model context window")
+
+// messageOverhead the approximate number of tokens a chat template spends on
+// the role and delimiters of each message.
+const messageOverhead = 4
+
+// TokenCounter counts the tokens of a text.
+type TokenCounter func(text string) int
+
+// EstimateTokens a rough token estimation of about four characters per token,
+// good enough when the exact tokenizer of the model is not available.
+func EstimateTokens(text string) int {
+	return (utf8.RuneCountInString(text) + 3) / 4
+}
+
+// Strategy reduces the messages by at least excess tokens if it can, it
+// returns the remaining messages and reports what was removed or truncated.
+type Strategy func(messages []Message, excess int, count func(Message) int, result *FitResult) []Message
+
+// FitResult reports what [Fitter.Fit] did to the prompt.
+type FitResult struct {
+	// Budget the number of tokens available to the prompt.
+	Budget int
+
+	// Tokens the number of tokens of the fitted prompt.
+	Tokens int
+
+	// Removed the messages dropped from the prompt.
+	Removed []Message
+
+	// Truncated the original messages whose text has been shortened.
+	Truncated []Message
+}
+
+// Fitted reports whether the prompt has been changed.
+func (r FitResult) Fitted() bool {
+	return len(r.Removed) > 0 || len(r.Truncated) > 0
+}
+
+// Fitter trims a Prompt to the context window of a model.
+type Fitter struct {
+	// ContextSize the context length of the model in tokens.
+	ContextSize int
+
+	// ReservedTokens the number of tokens kept free for the output.
+	ReservedTokens int
+
+	// Strategies are applied in order until the prompt fits,
+	// defaults to DropOldest.
+	Strategies []Strategy
+
+	// Counter counts the tokens of a text, defaults to EstimateTokens.
+	Counter TokenCounter
+}
+
+// NewFitter returns a Fitter for the given context size and reserved output tokens.
+func NewFitter(contextSize, reservedTokens int, strategies ...Strategy) *Fitter {
+	return &Fitter{
+		ContextSize:    contextSize,
+		ReservedTokens: reservedTokens,
+		Strategies:     strategies,
+	}
+}
+
+// Count returns the number of tokens of the messages.
+func (f *Fitter) Count(messages []Message) int {
+	var tokens int
+	for _, message := range messages {
+		tokens += f.countMessage(message)
+	}
+
+	return tokens
+}
+
+func (f *Fitter) countMessage(message Message) int {
+	counter := f.Counter
+	if counter == nil {
+		counter = EstimateTokens
+	}
+
+	return counter(message.Text()) + messageOverhead
+}
+
+// Fit trims the prompt until it fits into the context window, the given
+// prompt is never modified.
+func (f *Fitter) Fit(p Prompt) (Prompt, FitResult, error) {
+	result := FitResult{Budget: f.ContextSize - f.ReservedTokens}
+	messages := append([]Message(nil), p.Messages...)
+	result.Tokens = f.Count(messages)
+	if f.ContextSize <= 0 || result.Tokens <= result.Budget {
+		return p, result, nil
+	}
+
+	strategies := f.Strategies
+	if len(strategies) == 0 {
+		strategies = []Strategy{DropOldest}
+	}
+
+	for _, strategy := range strategies {
+		messages = strategy(messages, result.Tokens-result.Budget, f.countMessage, &result)
+		result.Tokens = f.Count(messages)
+		if result.Tokens <= result.Budget {
+			break
+		}
+	}
+
+	p.Messages = messages
+	if result.Tokens > result.Budget {
+		return p, result, ErrContextExceeded
+	}
+
+	return p, result, nil
+}
+
+// unit returns the messages [start, end) dropped together with the message at
+// i, an assistant message calling tools is never separated from the results
+// of its calls as providers reject either without the other.
+func unit(messages []Message, i int) (start, end int) {
+	start = i
+	for start > 0 && messages[start].Type() == MessageTypeTool {
+		start--
+	}
+	if messages[start].Type() != MessageTypeAssistant || len(ToolCalls(messages[start])) == 0 {
+		return i, i + 1
+	}
+
+	end = start + 1
+	for end < len(messages) && messages[end].Type() == MessageTypeTool {
+		end++
+	}
+
+	return start, end
+}
+
+// removable reports whether a strategy may drop the messages [start, end),
+// system and pinned messages as well as the last message of the conversation
+// are always kept.
+func removable(messages []Message, start, end int) bool {
+	for i := start; i < end; i++ {
+		if i == len(messages)-1 || messages[i].Type() == MessageTypeSystem || IsPinned(messages[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// DropOldest drops the oldest messages first.
+func DropOldest(messages []Message, excess int, count func(Message) int, result *FitResult) []Message {
+	kept := make([]Message, 0, len(messages))
+	for i := 0; i < len(messages); {
+		start, end := unit(messages, i)
+		if excess > 0 && removable(messages, start, end) {
+			for _, message := range messages[start:end] {
+				excess -= count(message)
+				result.Removed = append(result.Removed, message)
+			}
+		} else {
+			kept = append(kept, messages[start:end]...)
+		}
+		i = end
+	}
+
+	return kept
+}
+
+// MiddleOut drops the messages in the middle of the conversation first, keeping
+// its beginning and its most recent messages.
+func MiddleOut(messages []Message, excess int, count func(Message) int, result *FitResult) []Message {
+	removed := make([]bool, len(messages))
+	for lo, hi := (len(messages)-1)/2, len(messages)/2; excess > 0 && (lo >= 0 || hi < len(messages)); lo, hi = lo-1, hi+1 {
+		for _, i := range []int{lo, hi} {
+			if i < 0 || i >= len(messages) || removed[i] || excess <= 0 {
+				continue
+			}
+			start, end := unit(messages, i)
+			if !removable(messages, start, end) {
+				continue
+			}
+			for j := start; j < end; j++ {
+				removed[j] = true
+				excess -= count(messages[j])
+			}
+		}
+	}
+
+	kept := make([]Message, 0, len(messages))
+	for i, message := range messages {
+		if removed[i] {
+			result.Removed = append(result.Removed, message)
+			continue
+		}
+		kept = append(kept, message)
+	}
+
+	return kept
+}
+
+// TruncateToolOutputs returns a Strategy which shortens the text of tool
+// messages to maxTokens as counted by the Fitter, messages wrapped by another
+// type, e.g. pinned or cached ones, are kept as they are.
+func TruncateToolOutputs(maxTokens int) Strategy {
+	const marker = "\n...[truncated]"
+
+	return func(messages []Message, excess int, count func(Message) int, result *FitResult) []Message {
+		kept := make([]Message, len(messages))
+		for i, message := range messages {
+			kept[i] = message
+			original, ok := message.(*defaultMessage)
+			if excess <= 0 || !ok || original.Type() != MessageTypeTool {
+				continue
+			}
+
+			text := []rune(original.text)
+			n := fitRunes(text, maxTokens, func(text string) int {
+				return count(&defaultMessage{_type: original._type, text: text})
+			})
+			if n == len(text) {
+				continue
+			}
+
+			truncated := *original
+			truncated.text = string(text[:n]) + marker
+			excess -= count(original) - count(&truncated)
+			kept[i] = &truncated
+			result.Truncated = append(result.Truncated, message)
+		}
+
+		return kept
+	}
+}
+
+// fitRunes returns the length of the longest prefix of text counted to at most
+// maxTokens, the tokens of the empty text are not counted.
+func fitRunes(text []rune, maxTokens int, count func(string) int) int {
+	base := count("")
+
+	return sort.Search(len(text), func(n int) bool {
+		return count(string(text[:n+1]))-base > maxTokens
+	})
+}
@@ -0,0 +1,167 @@
+package prompt
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/tech1024/goai/chat"
+)
+
+func TestFitter_Fit(t *testing.T) {
+	long := strings.Repeat("a", 400) // 100 tokens + 4 overhead
+	messages := []Message{
+		SystemMessage("sys"),    // 1 + 4
+		UserMessage(long),       // 104
+		AssistantMessage(long),  // 104
+		Pin(UserMessage(long)),  // 104
+		ToolMessage(long),       // 104
+		AssistantMessage("ok"),  // 1 + 4
+		UserMessage("question"), // 2 + 4
+	}
+
+	tests := []struct {
+		name        string
+		fitter      *Fitter
+		wantTexts   []string
+		wantRemoved int
+		wantTrunc   int
+		wantErr     error
+	}{
+		{
+			name:      "fits already",
+			fitter:    NewFitter(1000, 100),
+			wantTexts: []string{"sys", long, long, long, long, "ok", "question"},
+		},
+		{
+			name:        "drop oldest",
+			fitter:      NewFitter(300, 50),
+			wantTexts:   []string{"sys", long, long, "ok", "question"},
+			wantRemoved: 2,
+		},
+		{
+			name:        "middle out",
+			fitter:      NewFitter(300, 50, MiddleOut),
+			wantTexts:   []string{"sys", long, long, "ok", "question"},
+			wantRemoved: 2,
+		},
+		{
+			name:      "truncate tool outputs",
+			fitter:    NewFitter(400, 0, TruncateToolOutputs(10)),
+			wantTexts: []string{"sys", long, long, long, long[:40] + "\n...[truncated]", "ok", "question"},
+			wantTrunc: 1,
+		},
+		{
+			name:        "pinned and system messages are kept",
+			fitter:      NewFitter(100, 0),
+			wantTexts:   []string{"sys", long, "question"},
+			wantRemoved: 4,
+			wantErr:     ErrContextExceeded,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, result, err := tt.fitter.Fit(NewPrompt(messages...))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Fit() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			var texts []string
+			for _, message := range got.Messages {
+				texts = append(texts, message.Text())
+			}
+			if strings.Join(texts, "|") != strings.Join(tt.wantTexts, "|") {
+				t.Errorf("Fit() got = %v, want %v", texts, tt.wantTexts)
+			}
+
+			if len(result.Removed) != tt.wantRemoved || len(result.Truncated) != tt.wantTrunc {
+				t.Errorf("Fit() removed = %d, truncated = %d", len(result.Removed), len(result.Truncated))
+			}
+
+			if len(messages) != 7 {
+				t.Errorf("Fit() modified the given prompt")
+			}
+		})
+	}
+}
+
+func TestFitter_FitToolCalls(t *testing.T) {
+	long := strings.Repeat("a", 400) // 100 tokens + 4 overhead
+	weather := chat.ToolCall{ID: "call_1", Name: "weather", Arguments: `{}`}
+	now := chat.ToolCall{ID: "call_2", Name: "now", Arguments: `{}`}
+	messages := []Message{
+		SystemMessage("sys"),                       // 1 + 4
+		UserMessage(long),                          // 104
+		AssistantToolCallMessage("", weather, now), // 0 + 4
+		ToolResultMessage(weather, long),           // 104
+		ToolResultMessage(now, "noon"),             // 1 + 4
+		AssistantMessage("ok"),                     // 1 + 4
+		UserMessage("question"),                    // 2 + 4
+	}
+
+	// the calls and their results are dropped as one, a result is never
+	// left without its call.
+	tests := []struct {
+		name        string
+		strategy    Strategy
+		wantRemoved int
+	}{
+		{"drop oldest", DropOldest, 4},
+		{"middle out", MiddleOut, 3},
+	}
+	for _, tt := range tests {
+		got, result, err := NewFitter(125, 0, tt.strategy).Fit(NewPrompt(messages...))
+		if err != nil || len(result.Removed) != tt.wantRemoved {
+			t.Errorf("%s: Fit() removed = %d, error = %v", tt.name, len(result.Removed), err)
+		}
+		for _, message := range got.Messages {
+			if message.Type() == MessageTypeTool || len(ToolCalls(message)) > 0 {
+				t.Errorf("%s: Fit() kept %v of a dropped call", tt.name, message.Type())
+			}
+		}
+	}
+
+	// the results of the last call are kept with the call.
+	got, _, err := NewFitter(125, 0).Fit(NewPrompt(messages[:5]...))
+	if err != nil || len(got.Messages) != 4 || len(ToolCalls(got.Messages[1])) != 2 {
+		t.Errorf("Fit() got = %v, error = %v", got.Messages, err)
+	}
+}
+
+// cachedMessage wraps a message like the cache marker of a provider.
+type cachedMessage struct {
+	Message
+}
+
+func (cachedMessage) Cached() bool {
+	return true
+}
+
+func TestTruncateToolOutputs(t *testing.T) {
+	long := strings.Repeat("word ", 100)
+	words := func(text string) int {
+		return len(strings.Fields(text))
+	}
+	weather := chat.ToolCall{ID: "call_1", Name: "weather"}
+	messages := []Message{
+		cachedMessage{ToolMessage(long)},
+		ToolResultMessage(weather, long),
+		UserMessage("question"),
+	}
+
+	// the cut is derived from the counter, the wrapped message is kept.
+	fitter := NewFitter(150, 0, TruncateToolOutputs(10))
+	fitter.Counter = words
+	got, result, err := fitter.Fit(NewPrompt(messages...))
+	if err != nil || len(result.Truncated) != 1 {
+		t.Fatalf("Fit() truncated = %d, error = %v", len(result.Truncated), err)
+	}
+
+	if _, ok := got.Messages[0].(cachedMessage); !ok || got.Messages[0].Text() != long {
+		t.Errorf("Fit() got = %T %q, want the cached message", got.Messages[0], got.Messages[0].Text())
+	}
+	if text := got.Messages[1].Text(); words(text) != 11 || !strings.HasSuffix(text, "\n...[truncated]") || ToolCallID(got.Messages[1]) != "call_1" {
+		t.Errorf("Fit() got = %q", text)
+	}
+}
@@ -0,0 +1,31 @@
+package prompt
+
+// MetadataImages the metadata key of the images attached to a message.
+const MetadataImages = "images"
+
+// Image an image attached to a message, either its data or its url.
+type Image struct {
+	// MimeType the media type of the data, e.g. "image/png".
+	MimeType string
+
+	// Data the encoded image, e.g. the content of a png file.
+	Data []byte
+
+	// URL the url of the image, for providers fetching images themselves.
+	URL string
+}
+
+// UserImageMessage a message of the type 'user' with attached images
+func UserImageMessage(message string, images ...Image) *defaultMessage {
+	return &defaultMessage{
+		_type:    MessageTypeUser,
+		text:     message,
+		metadata: map[string]any{MetadataImages: images},
+	}
+}
+
+// Images returns the images attached to a message.
+func Images(message Message) []Image {
+	images, _ := message.Metadata()[MetadataImages].([]Image)
+	return images
+}
@@ -43,6 +43,14 @@ func (m *defaultMessage) Metadata() map[string]any {
 	return m.metadata
 }
 
+// NewMessage a message of the given type
+func NewMessage(messageType MessageType, message string) *defaultMessage {
+	return &defaultMessage{
+		_type: messageType,
+		text:  message,
+	}
+}
+
 // UserMessage a message of the type 'user'
 func UserMessage(message string) *defaultMessage {
 	return &defaultMessage{
@@ -74,3 +82,27 @@ func ToolMessage(message string) *defaultMessage {
 		text:  message,
 	}
 }
+
+type pinnedMessage struct {
+	Message
+}
+
+func (m pinnedMessage) Pinned() bool {
+	return true
+}
+
+// Pin marks the message to be always kept when the prompt is fitted
+// into the context window of a model.
+func Pin(message Message) Message {
+	if IsPinned(message) {
+		return message
+	}
+
+	return pinnedMessage{Message: message}
+}
+
+// IsPinned reports whether the message has been pinned.
+func IsPinned(message Message) bool {
+	pinned, ok := message.(interface{ Pinned() bool })
+	return ok && pinned.Pinned()
+}
@@ -3,4 +3,38 @@ package prompt
 type Option struct {
 	// Model the model to use for the chat.
 	Model string
+
+	// Tools the functions the model may call.
+	Tools []Tool
+
+	// Candidates the number of generations to return, zero returns one.
+	Candidates int
+
+	// LogProbs returns the log probabilities of the generated tokens.
+	LogProbs bool
+
+	// TopLogProbs the number of most likely tokens returned at each position,
+	// it implies LogProbs.
+	TopLogProbs int
+
+	// Extensions the provider specific options, providers ignore the
+	// extensions of other providers.
+	Extensions []Extension
+}
+
+// Extension provider specific options of a prompt.
+type Extension interface {
+	// Provider the name of the provider the options are meant for.
+	Provider() string
+}
+
+// Extension returns the extension of the provider, nil if there is none.
+func (o Option) Extension(provider string) Extension {
+	for _, extension := range o.Extensions {
+		if extension.Provider() == provider {
+			return extension
+		}
+	}
+
+	return nil
 }
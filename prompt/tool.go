@@ -0,0 +1,85 @@
+package prompt
+
+import (
+	"encoding/json"
+
+	"github.com/tech1024/goai/chat"
+)
+
+// Metadata keys of the messages of a tool calling conversation.
+const (
+	MetadataToolCalls  = "tool_calls"
+	MetadataToolCallID = "tool_call_id"
+	MetadataToolName   = "tool_name"
+)
+
+// Tool a function the model may call.
+type Tool struct {
+	// Name the name of the function.
+	Name string
+
+	// Description what the function does, used by the model to choose when to call it.
+	Description string
+
+	// Parameters the JSON schema of the arguments.
+	Parameters json.RawMessage
+}
+
+// AssistantToolCallMessage a message of the type 'assistant' requesting tool calls,
+// add it to the prompt before the results of the calls
+func AssistantToolCallMessage(message string, toolCalls ...chat.ToolCall) *defaultMessage {
+	return &defaultMessage{
+		_type:    MessageTypeAssistant,
+		text:     message,
+		metadata: map[string]any{MetadataToolCalls: toolCalls},
+	}
+}
+
+// AssistantGenerationMessage a message of the type 'assistant' with the content,
+// the tool calls and the metadata of a generation, add it to the prompt to
+// continue the conversation, e.g. with the results of the calls.
+func AssistantGenerationMessage(generation chat.Generation) *defaultMessage {
+	metadata := make(map[string]any, len(generation.Metadata)+1)
+	for key, value := range generation.Metadata {
+		metadata[key] = value
+	}
+	if len(generation.ToolCalls) > 0 {
+		metadata[MetadataToolCalls] = generation.ToolCalls
+	}
+
+	return &defaultMessage{
+		_type:    MessageTypeAssistant,
+		text:     generation.Content,
+		metadata: metadata,
+	}
+}
+
+// ToolResultMessage a message of the type 'tool' with the result of a tool call
+func ToolResultMessage(toolCall chat.ToolCall, result string) *defaultMessage {
+	return &defaultMessage{
+		_type: MessageTypeTool,
+		text:  result,
+		metadata: map[string]any{
+			MetadataToolCallID: toolCall.ID,
+			MetadataToolName:   toolCall.Name,
+		},
+	}
+}
+
+// ToolCalls returns the tool calls requested by an assistant message.
+func ToolCalls(message Message) []chat.ToolCall {
+	toolCalls, _ := message.Metadata()[MetadataToolCalls].([]chat.ToolCall)
+	return toolCalls
+}
+
+// ToolCallID returns the id of the tool call a tool message answers.
+func ToolCallID(message Message) string {
+	id, _ := message.Metadata()[MetadataToolCallID].(string)
+	return id
+}
+
+// ToolName returns the name of the tool a tool message answers.
+func ToolName(message Message) string {
+	name, _ := message.Metadata()[MetadataToolName].(string)
+	return name
+}
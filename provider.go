@@ -0,0 +1,140 @@
+package goai
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/tech1024/goai/embedding"
+)
+
+var (
+	// ErrUnknownProvider is returned for a DSN of a provider which has not
+	// been registered, usually its package is not imported.
+	ErrUnknownProvider = errors.New("goai: unknown provider")
+
+	// ErrUnsupportedModel is returned for a DSN of a provider which does not
+	// create the requested kind of model.
+	ErrUnsupportedModel = errors.New("goai: unsupported model")
+)
+
+// Provider creates the models of DSNs, either factory may be nil if the
+// provider does not support the kind of model. The factories read the
+// parameters of the DSN they support, any other parameter fails.
+type Provider struct {
+	ChatModel      func(dsn *DSN) (ChatModel, error)
+	EmbeddingModel func(dsn *DSN) (EmbeddingModel, error)
+}
+
+var (
+	providersMu sync.RWMutex
+	providers   = make(map[string]Provider)
+)
+
+// RegisterProvider makes a provider available by the name to OpenChatModel
+// and OpenEmbeddingModel, the provider packages register themselves when
+// imported. It panics if the name is registered twice.
+func RegisterProvider(name string, provider Provider) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+
+	if name == "" {
+		panic("goai: RegisterProvider with an empty name")
+	}
+	if _, ok := providers[name]; ok {
+		panic("goai: RegisterProvider called twice for provider " + name)
+	}
+
+	providers[name] = provider
+}
+
+// Providers returns the sorted names of the registered providers.
+func Providers() []string {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func lookupProvider(name string) (Provider, error) {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+
+	provider, ok := providers[name]
+	if !ok {
+		return Provider{}, fmt.Errorf("%w %q, is its package imported?", ErrUnknownProvider, name)
+	}
+
+	return provider, nil
+}
+
+// OpenChatModel returns the chat model of the DSN, e.g.
+// "ollama://gpu1:11434/llama3.1" or "openai:gpt-4o-mini?temperature=0.2".
+func OpenChatModel(dsn string) (ChatModel, error) {
+	d, err := ParseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	provider, err := lookupProvider(d.Provider)
+	if err != nil {
+		return nil, err
+	}
+	if provider.ChatModel == nil {
+		return nil, fmt.Errorf("%w: %s has no chat models", ErrUnsupportedModel, d.Provider)
+	}
+
+	chatModel, err := provider.ChatModel(d)
+	if err != nil {
+		return nil, err
+	}
+	if err = d.checkRead(); err != nil {
+		return nil, err
+	}
+
+	return chatModel, nil
+}
+
+// OpenEmbeddingModel returns the embedding model of the DSN, e.g.
+// "openai:text-embedding-3-small?dimensions=256". The dimensions parameter is
+// supported by all providers.
+func OpenEmbeddingModel(dsn string) (EmbeddingModel, error) {
+	d, err := ParseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	provider, err := lookupProvider(d.Provider)
+	if err != nil {
+		return nil, err
+	}
+	if provider.EmbeddingModel == nil {
+		return nil, fmt.Errorf("%w: %s has no embedding models", ErrUnsupportedModel, d.Provider)
+	}
+
+	dimensions, err := d.Int("dimensions")
+	if err != nil {
+		return nil, err
+	}
+
+	embeddingModel, err := provider.EmbeddingModel(d)
+	if err != nil {
+		return nil, err
+	}
+	if err = d.checkRead(); err != nil {
+		return nil, err
+	}
+
+	if dimensions != nil {
+		embeddingModel = DefaultEmbeddingOption(embedding.Option{Dimensions: *dimensions})(embeddingModel)
+	}
+
+	return embeddingModel, nil
+}
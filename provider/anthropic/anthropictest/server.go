@@ -0,0 +1,350 @@
+// Package anthropictest provides an in-process fake Anthropic server, whose
+// Messages API is answered by a goai chat model, e.g. the scripted fake of
+// the goaitest package.
+package anthropictest
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/tech1024/goai"
+	"github.com/tech1024/goai/chat"
+	"github.com/tech1024/goai/goaitest"
+	"github.com/tech1024/goai/prompt"
+	"github.com/tech1024/goai/provider/anthropic"
+)
+
+// Server a fake Anthropic server.
+type Server struct {
+	*goaitest.Server
+
+	// ChatModel answers /v1/messages.
+	ChatModel goai.ChatModel
+}
+
+// NewServer starts a fake Anthropic server answering with the chat model.
+func NewServer(chatModel goai.ChatModel) *Server {
+	s := &Server{
+		Server:    goaitest.NewServer(),
+		ChatModel: chatModel,
+	}
+	s.HandleFunc("POST /v1/messages", s.handleMessages)
+
+	return s
+}
+
+// AnthropicClient returns a client talking to the server.
+func (s *Server) AnthropicClient(options ...anthropic.ClientOption) *anthropic.Client {
+	options = append([]anthropic.ClientOption{
+		anthropic.WithBaseURL(s.URL),
+		anthropic.WithHTTPClient(s.Client()),
+	}, options...)
+
+	return anthropic.NewClient("test-key", options...)
+}
+
+func errorBody(err error) map[string]any {
+	return map[string]any{
+		"type":  "error",
+		"error": anthropic.ErrorDetail{Type: errorType(goaitest.HTTPStatus(err)), Message: err.Error()},
+	}
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	goaitest.WriteJSON(w, goaitest.HTTPStatus(err), errorBody(err))
+}
+
+// errorType returns the type of the API error of a status code.
+func errorType(code int) string {
+	switch code {
+	case http.StatusBadRequest:
+		return "invalid_request_error"
+	case http.StatusUnauthorized:
+		return "authentication_error"
+	case http.StatusNotFound:
+		return "not_found_error"
+	case http.StatusTooManyRequests:
+		return "rate_limit_error"
+	case 529:
+		return "overloaded_error"
+	default:
+		return "api_error"
+	}
+}
+
+func (s *Server) handleMessages(w http.ResponseWriter, r *http.Request) {
+	var req anthropic.MessagesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, &goaitest.StatusError{Code: http.StatusBadRequest, Message: err.Error()})
+		return
+	}
+	if req.MaxTokens <= 0 {
+		writeError(w, &goaitest.StatusError{Code: http.StatusBadRequest, Message: "max_tokens: Field required"})
+		return
+	}
+	if s.ChatModel == nil {
+		writeError(w, &goaitest.StatusError{Code: http.StatusNotFound, Message: "model: " + req.Model})
+		return
+	}
+
+	if err := checkThinking(req); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	p := toPrompt(req)
+	if req.Stream {
+		s.streamMessages(r.Context(), w, req, p)
+		return
+	}
+
+	resp, err := s.ChatModel.Call(r.Context(), p)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	message := anthropic.MessagesResponse{
+		ID:    messageID(),
+		Type:  "message",
+		Role:  anthropic.RoleAssistant,
+		Model: req.Model,
+		Usage: toUsage(resp.Usage),
+	}
+	var generation chat.Generation
+	if len(resp.Generations) > 0 {
+		generation = resp.Generations[0]
+	}
+	if generation.Thinking != "" {
+		message.Content = append(message.Content, anthropic.ContentBlock{Type: anthropic.BlockThinking, Thinking: generation.Thinking, Signature: signature})
+	}
+	if generation.Content != "" {
+		message.Content = append(message.Content, anthropic.ContentBlock{Type: anthropic.BlockText, Text: generation.Content})
+	}
+	for _, tc := range generation.ToolCalls {
+		message.Content = append(message.Content, anthropic.ContentBlock{Type: anthropic.BlockToolUse, ID: tc.ID, Name: tc.Name, Input: json.RawMessage(tc.Arguments)})
+	}
+	message.StopReason = stopReason(generation)
+
+	goaitest.WriteJSON(w, http.StatusOK, message)
+}
+
+// signature the signature of the thinking blocks of the fake.
+const signature = "goaitest"
+
+func (s *Server) streamMessages(ctx context.Context, w http.ResponseWriter, req anthropic.MessagesRequest, p prompt.Prompt) {
+	events := goaitest.NewEventStream(w)
+	send := func(event anthropic.Event) error {
+		return events.Send(event.Type, event)
+	}
+	streamed := &chat.Response{}
+
+	// the blocks are streamed like the API does, a block of the current type
+	// stays open until a chunk of another type arrives.
+	index, open := -1, ""
+	stop := func() error {
+		if open == "" {
+			return nil
+		}
+		if open == anthropic.BlockThinking {
+			if err := send(anthropic.Event{Type: "content_block_delta", Index: index, Delta: &anthropic.Delta{Type: "signature_delta", Signature: signature}}); err != nil {
+				return err
+			}
+		}
+		open = ""
+		return send(anthropic.Event{Type: "content_block_stop", Index: index})
+	}
+	start := func(block anthropic.ContentBlock) error {
+		if err := stop(); err != nil {
+			return err
+		}
+		index, open = index+1, block.Type
+		return send(anthropic.Event{Type: "content_block_start", Index: index, ContentBlock: &block})
+	}
+
+	err := send(anthropic.Event{Type: "message_start", Message: &anthropic.MessagesResponse{
+		ID:      messageID(),
+		Type:    "message",
+		Role:    anthropic.RoleAssistant,
+		Model:   req.Model,
+		Content: []anthropic.ContentBlock{},
+	}})
+	if err == nil {
+		err = send(anthropic.Event{Type: "ping"})
+	}
+	if err == nil {
+		err = s.ChatModel.Stream(ctx, p, func(resp *chat.Response) error {
+			streamed.Append(resp)
+			if len(resp.Generations) == 0 {
+				return nil
+			}
+			generation := resp.Generations[0]
+
+			if generation.Thinking != "" {
+				if open != anthropic.BlockThinking {
+					if err := start(anthropic.ContentBlock{Type: anthropic.BlockThinking}); err != nil {
+						return err
+					}
+				}
+				if err := send(anthropic.Event{Type: "content_block_delta", Index: index, Delta: &anthropic.Delta{Type: "thinking_delta", Thinking: generation.Thinking}}); err != nil {
+					return err
+				}
+			}
+			if generation.Content != "" {
+				if open != anthropic.BlockText {
+					if err := start(anthropic.ContentBlock{Type: anthropic.BlockText}); err != nil {
+						return err
+					}
+				}
+				if err := send(anthropic.Event{Type: "content_block_delta", Index: index, Delta: &anthropic.Delta{Type: "text_delta", Text: generation.Content}}); err != nil {
+					return err
+				}
+			}
+
+			// the input of a tool call is streamed in fragments.
+			for _, tc := range generation.ToolCalls {
+				if err := start(anthropic.ContentBlock{Type: anthropic.BlockToolUse, ID: tc.ID, Name: tc.Name, Input: json.RawMessage("{}")}); err != nil {
+					return err
+				}
+				for _, fragment := range goaitest.Fragments(tc.Arguments) {
+					if err := send(anthropic.Event{Type: "content_block_delta", Index: index, Delta: &anthropic.Delta{Type: "input_json_delta", PartialJSON: fragment}}); err != nil {
+						return err
+					}
+				}
+				if err := stop(); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+	}
+
+	if err != nil {
+		if !events.Started() {
+			writeError(w, err)
+			return
+		}
+		detail := errorBody(err)["error"].(anthropic.ErrorDetail)
+		_ = send(anthropic.Event{Type: "error", Error: &detail})
+		return
+	}
+
+	u := toUsage(streamed.Usage)
+	_ = stop()
+	_ = send(anthropic.Event{Type: "message_delta", Delta: &anthropic.Delta{StopReason: stopReason(chat.Generation{FinishReason: streamed.FinishReason(), ToolCalls: streamed.ToolCalls()})}, Usage: &u})
+	_ = send(anthropic.Event{Type: "message_stop"})
+}
+
+// checkThinking fails like the API if thinking is enabled and the last
+// assistant message calling tools does not start with a signed thinking block.
+func checkThinking(req anthropic.MessagesRequest) error {
+	if req.Thinking == nil {
+		return nil
+	}
+
+	for i := len(req.Messages) - 1; i >= 0; i-- {
+		m := req.Messages[i]
+		if m.Role != anthropic.RoleAssistant {
+			continue
+		}
+		if !slices.ContainsFunc(m.Content, func(block anthropic.ContentBlock) bool { return block.Type == anthropic.BlockToolUse }) {
+			return nil
+		}
+
+		switch first := m.Content[0]; {
+		case first.Type == anthropic.BlockRedactedThinking:
+			return nil
+		case first.Type != anthropic.BlockThinking:
+			return &goaitest.StatusError{Code: http.StatusBadRequest, Message: fmt.Sprintf(
+				"messages.%d.content.0.type: Expected `thinking` or `redacted_thinking`, but found `%s`. "+
+					"When `thinking` is enabled, a final `assistant` message must start with a thinking block", i, first.Type)}
+		case first.Signature != signature:
+			return &goaitest.StatusError{Code: http.StatusBadRequest, Message: fmt.Sprintf("messages.%d.content.0: Invalid `signature` in `thinking` block", i)}
+		}
+
+		return nil
+	}
+
+	return nil
+}
+
+func toPrompt(req anthropic.MessagesRequest) prompt.Prompt {
+	p := prompt.Prompt{ChatOption: prompt.Option{Model: req.Model}}
+	for _, tool := range req.Tools {
+		p.ChatOption.Tools = append(p.ChatOption.Tools, prompt.Tool{
+			Name:        tool.Name,
+			Description: tool.Description,
+			Parameters:  tool.InputSchema,
+		})
+	}
+
+	for _, block := range req.System {
+		p.Messages = append(p.Messages, prompt.SystemMessage(block.Text))
+	}
+
+	for _, m := range req.Messages {
+		var text string
+		var images []prompt.Image
+		var toolCalls []chat.ToolCall
+		for _, block := range m.Content {
+			switch block.Type {
+			case anthropic.BlockText:
+				text += block.Text
+			case anthropic.BlockImage:
+				if block.Source == nil {
+					continue
+				}
+				data, _ := base64.StdEncoding.DecodeString(block.Source.Data)
+				images = append(images, prompt.Image{MimeType: block.Source.MediaType, Data: data, URL: block.Source.URL})
+			case anthropic.BlockToolUse:
+				toolCalls = append(toolCalls, chat.ToolCall{ID: block.ID, Name: block.Name, Arguments: string(block.Input)})
+			case anthropic.BlockToolResult:
+				p.Messages = append(p.Messages, prompt.ToolResultMessage(chat.ToolCall{ID: block.ToolUseID}, block.Content))
+			}
+		}
+
+		switch {
+		case len(toolCalls) > 0:
+			p.Messages = append(p.Messages, prompt.AssistantToolCallMessage(text, toolCalls...))
+		case len(images) > 0:
+			p.Messages = append(p.Messages, prompt.UserImageMessage(text, images...))
+		case text != "":
+			p.Messages = append(p.Messages, prompt.NewMessage(prompt.MessageType(m.Role), text))
+		}
+	}
+
+	return p
+}
+
+func toUsage(usage chat.Usage) anthropic.Usage {
+	return anthropic.Usage{
+		InputTokens:  usage.PromptTokens,
+		OutputTokens: usage.CompletionTokens,
+	}
+}
+
+func stopReason(generation chat.Generation) string {
+	switch generation.FinishReason {
+	case chat.FinishReasonLength:
+		return anthropic.StopMaxTokens
+	case chat.FinishReasonContentFilter:
+		return anthropic.StopRefusal
+	case chat.FinishReasonToolCalls:
+		return anthropic.StopToolUse
+	}
+	if len(generation.ToolCalls) > 0 {
+		return anthropic.StopToolUse
+	}
+
+	return anthropic.StopEndTurn
+}
+
+func messageID() string {
+	return fmt.Sprintf("msg_%d", time.Now().UnixNano())
+}
@@ -0,0 +1,330 @@
+package anthropic
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/tech1024/goai/chat"
+	"github.com/tech1024/goai/internal/wire"
+	"github.com/tech1024/goai/prompt"
+)
+
+// DefaultMaxTokens the maximum number of tokens generated unless set by the
+// ChatModel or the Extension, the Messages API requires a limit.
+const DefaultMaxTokens = 4096
+
+func NewChatModel(client *Client, model string) *ChatModel {
+	return &ChatModel{
+		client:    client,
+		model:     model,
+		MaxTokens: DefaultMaxTokens,
+	}
+}
+
+type ChatModel struct {
+	client *Client
+	model  string
+
+	// MaxTokens the maximum number of tokens generated, the Extension
+	// overrides it per prompt.
+	MaxTokens int
+}
+
+func (chatModel *ChatModel) Call(ctx context.Context, prompt prompt.Prompt) (*chat.Response, error) {
+	req, err := chatModel.buildMessagesRequest(prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := chatModel.client.Messages(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return toChatResponse(resp), nil
+}
+
+func (chatModel *ChatModel) Stream(ctx context.Context, prompt prompt.Prompt, fn func(*chat.Response) error) error {
+	req, err := chatModel.buildMessagesRequest(prompt)
+	if err != nil {
+		return err
+	}
+
+	var id, model string
+	var usage Usage
+
+	// the input of a tool use block is streamed as fragments, the call is
+	// emitted once its block stopped.
+	toolIndexes := make(map[int]int)
+	toolCalls := make(map[int]*chat.ToolCall)
+	arguments := make(map[int]*strings.Builder)
+
+	// the signed thinking blocks are passed on with the last chunk.
+	thinking := make(map[int]*ContentBlock)
+	var thinkingBlocks []ContentBlock
+
+	_, err = chatModel.client.MessagesStream(ctx, req, func(event *Event) error {
+		chunk := &chat.Response{ID: id, Model: model}
+		generation := chat.Generation{}
+
+		switch event.Type {
+		case "message_start":
+			if event.Message == nil {
+				return nil
+			}
+			id, model, usage = event.Message.ID, wire.ModelOf(event.Message.Model, req.Model), event.Message.Usage
+			return nil
+		case "content_block_start":
+			block := event.ContentBlock
+			if block != nil && (block.Type == BlockThinking || block.Type == BlockRedactedThinking) {
+				thinking[event.Index] = &ContentBlock{Type: block.Type, Thinking: block.Thinking, Signature: block.Signature, Data: block.Data}
+				return nil
+			}
+			if block == nil || block.Type != BlockToolUse {
+				return nil
+			}
+			index := len(toolIndexes)
+			toolIndexes[event.Index] = index
+			toolCalls[event.Index] = &chat.ToolCall{ID: block.ID, Name: block.Name}
+			arguments[event.Index] = &strings.Builder{}
+			generation.ToolCallDeltas = []chat.ToolCallDelta{{Index: index, ID: block.ID, Name: block.Name}}
+		case "content_block_delta":
+			if event.Delta == nil {
+				return nil
+			}
+			generation.Content = event.Delta.Text
+			generation.Thinking = event.Delta.Thinking
+			if block := thinking[event.Index]; block != nil {
+				block.Thinking += event.Delta.Thinking
+				block.Signature += event.Delta.Signature
+			}
+			if builder := arguments[event.Index]; builder != nil && event.Delta.PartialJSON != "" {
+				builder.WriteString(event.Delta.PartialJSON)
+				generation.ToolCallDeltas = []chat.ToolCallDelta{{Index: toolIndexes[event.Index], Arguments: event.Delta.PartialJSON}}
+			}
+		case "content_block_stop":
+			if block := thinking[event.Index]; block != nil {
+				thinkingBlocks = append(thinkingBlocks, *block)
+				return nil
+			}
+			toolCall := toolCalls[event.Index]
+			if toolCall == nil {
+				return nil
+			}
+			input, err := toolInput(toolCall.Name, arguments[event.Index].String())
+			if err != nil {
+				return err
+			}
+			toolCall.Arguments = string(input)
+			generation.ToolCalls = []chat.ToolCall{*toolCall}
+		case "message_delta":
+			if event.Delta != nil {
+				generation.FinishReason = toFinishReason(event.Delta.StopReason)
+			}
+			if event.Usage != nil {
+				usage.OutputTokens = event.Usage.OutputTokens
+			}
+			chunk.Usage = toUsage(usage)
+			if len(thinkingBlocks) > 0 {
+				generation.Metadata = map[string]any{MetadataThinkingBlocks: thinkingBlocks}
+			}
+		default:
+			return nil
+		}
+
+		if generation.Content == "" && generation.Thinking == "" && len(generation.ToolCalls) == 0 &&
+			len(generation.ToolCallDeltas) == 0 && generation.FinishReason == "" && chunk.Usage.IsZero() {
+			return nil
+		}
+		chunk.Generations = []chat.Generation{generation}
+
+		return fn(chunk)
+	})
+
+	return err
+}
+
+func (chatModel *ChatModel) buildMessagesRequest(p prompt.Prompt) (*MessagesRequest, error) {
+	extension := extensionOf(p.ChatOption)
+	cacheControl := &CacheControl{Type: "ephemeral", TTL: extension.CacheTTL}
+
+	request := &MessagesRequest{
+		Model:         chatModel.model,
+		MaxTokens:     chatModel.MaxTokens,
+		StopSequences: extension.StopSequences,
+		Temperature:   extension.Temperature,
+		TopP:          extension.TopP,
+		TopK:          extension.TopK,
+	}
+	if p.ChatOption.Model != "" {
+		request.Model = p.ChatOption.Model
+	}
+	if extension.MaxTokens > 0 {
+		request.MaxTokens = extension.MaxTokens
+	}
+	if request.MaxTokens <= 0 {
+		request.MaxTokens = DefaultMaxTokens
+	}
+
+	for _, message := range p.Messages {
+		// the system prompt is no message of the Messages API.
+		if message.Type() == prompt.MessageTypeSystem {
+			block := ContentBlock{Type: BlockText, Text: message.Text()}
+			if IsCached(message) {
+				block.CacheControl = cacheControl
+			}
+			request.System = append(request.System, block)
+			continue
+		}
+
+		role, blocks, err := toContentBlocks(message)
+		if err != nil {
+			return nil, err
+		}
+		if len(blocks) == 0 {
+			continue
+		}
+		if IsCached(message) {
+			blocks[len(blocks)-1].CacheControl = cacheControl
+		}
+
+		// consecutive messages of a role, e.g. the results of parallel tool
+		// calls, are merged into one message.
+		if last := len(request.Messages) - 1; last >= 0 && request.Messages[last].Role == role {
+			request.Messages[last].Content = append(request.Messages[last].Content, blocks...)
+			continue
+		}
+		request.Messages = append(request.Messages, Message{Role: role, Content: blocks})
+	}
+	if extension.CacheSystem && len(request.System) > 0 {
+		request.System[len(request.System)-1].CacheControl = cacheControl
+	}
+
+	for _, tool := range p.ChatOption.Tools {
+		schema := tool.Parameters
+		if len(schema) == 0 {
+			schema = json.RawMessage(`{"type":"object","properties":{}}`)
+		}
+		request.Tools = append(request.Tools, Tool{
+			Name:        tool.Name,
+			Description: tool.Description,
+			InputSchema: schema,
+		})
+	}
+	if extension.CacheTools && len(request.Tools) > 0 {
+		request.Tools[len(request.Tools)-1].CacheControl = cacheControl
+	}
+
+	if extension.ThinkingBudget > 0 {
+		if extension.ThinkingBudget < MinThinkingBudget {
+			return nil, fmt.Errorf("anthropic thinking budget: %d is less than %d", extension.ThinkingBudget, MinThinkingBudget)
+		}
+		if extension.ThinkingBudget >= request.MaxTokens {
+			return nil, fmt.Errorf("anthropic thinking budget: %d is not less than the max tokens %d", extension.ThinkingBudget, request.MaxTokens)
+		}
+		request.Thinking = &Thinking{Type: "enabled", BudgetTokens: extension.ThinkingBudget}
+	}
+
+	return request, nil
+}
+
+// toContentBlocks returns the role and the content blocks of a message.
+func toContentBlocks(message prompt.Message) (string, []ContentBlock, error) {
+	switch message.Type() {
+	case prompt.MessageTypeTool:
+		return RoleUser, []ContentBlock{{
+			Type:      BlockToolResult,
+			ToolUseID: prompt.ToolCallID(message),
+			Content:   message.Text(),
+		}}, nil
+	case prompt.MessageTypeAssistant:
+		// the thinking blocks precede the other blocks of the message.
+		blocks := append([]ContentBlock(nil), ThinkingBlocks(message)...)
+		if message.Text() != "" {
+			blocks = append(blocks, ContentBlock{Type: BlockText, Text: message.Text()})
+		}
+		for _, toolCall := range prompt.ToolCalls(message) {
+			input, err := toolInput(toolCall.Name, toolCall.Arguments)
+			if err != nil {
+				return "", nil, err
+			}
+			blocks = append(blocks, ContentBlock{Type: BlockToolUse, ID: toolCall.ID, Name: toolCall.Name, Input: input})
+		}
+		return RoleAssistant, blocks, nil
+	default:
+		var blocks []ContentBlock
+		for _, image := range prompt.Images(message) {
+			source := &ImageSource{Type: "url", URL: image.URL}
+			if len(image.Data) > 0 {
+				source = &ImageSource{Type: "base64", MediaType: image.MimeType, Data: base64.StdEncoding.EncodeToString(image.Data)}
+			}
+			blocks = append(blocks, ContentBlock{Type: BlockImage, Source: source})
+		}
+		if message.Text() != "" {
+			blocks = append(blocks, ContentBlock{Type: BlockText, Text: message.Text()})
+		}
+		return RoleUser, blocks, nil
+	}
+}
+
+func toChatResponse(resp *MessagesResponse) *chat.Response {
+	generation := chat.Generation{FinishReason: toFinishReason(resp.StopReason)}
+	var thinkingBlocks []ContentBlock
+	for _, block := range resp.Content {
+		switch block.Type {
+		case BlockText:
+			generation.Content += block.Text
+		case BlockThinking, BlockRedactedThinking:
+			generation.Thinking += block.Thinking
+			thinkingBlocks = append(thinkingBlocks, block)
+		case BlockToolUse:
+			arguments := string(block.Input)
+			if arguments == "" {
+				arguments = "{}"
+			}
+			generation.ToolCalls = append(generation.ToolCalls, chat.ToolCall{ID: block.ID, Name: block.Name, Arguments: arguments})
+		}
+	}
+
+	if len(thinkingBlocks) > 0 {
+		generation.Metadata = map[string]any{MetadataThinkingBlocks: thinkingBlocks}
+	}
+
+	return &chat.Response{
+		ID:          resp.ID,
+		Model:       resp.Model,
+		Generations: []chat.Generation{generation},
+		Usage:       toUsage(resp.Usage),
+	}
+}
+
+// toUsage returns the usage of a request, the prompt tokens include the
+// tokens written to and read from the cache.
+func toUsage(usage Usage) chat.Usage {
+	promptTokens := usage.InputTokens + usage.CacheCreationInputTokens + usage.CacheReadInputTokens
+	return chat.Usage{
+		PromptTokens:     promptTokens,
+		CompletionTokens: usage.OutputTokens,
+		TotalTokens:      promptTokens + usage.OutputTokens,
+	}
+}
+
+func toFinishReason(stopReason string) chat.FinishReason {
+	switch stopReason {
+	case "":
+		return ""
+	case StopEndTurn, StopSequence, StopPauseTurn:
+		return chat.FinishReasonStop
+	case StopMaxTokens, StopContextLimit:
+		return chat.FinishReasonLength
+	case StopToolUse:
+		return chat.FinishReasonToolCalls
+	case StopRefusal:
+		return chat.FinishReasonContentFilter
+	default:
+		return chat.FinishReason(stopReason)
+	}
+}
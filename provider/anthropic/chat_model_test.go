@@ -0,0 +1,310 @@
+package anthropic_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tech1024/goai"
+	"github.com/tech1024/goai/chat"
+	"github.com/tech1024/goai/goaitest"
+	"github.com/tech1024/goai/prompt"
+	"github.com/tech1024/goai/provider/anthropic"
+	"github.com/tech1024/goai/provider/anthropic/anthropictest"
+)
+
+func TestChatModel(t *testing.T) {
+	fake := goaitest.NewChatModel(
+		goaitest.Text("hello").WithUsage(chat.Usage{PromptTokens: 2, CompletionTokens: 1, TotalTokens: 3}),
+		goaitest.Chunks("a", "b", "c"),
+		goaitest.Error(&goaitest.StatusError{Code: http.StatusTooManyRequests, Message: "rate limited"}),
+	)
+	ts := anthropictest.NewServer(fake)
+	defer ts.Close()
+
+	c := goai.NewChat(anthropic.NewChatModel(ts.AnthropicClient(), "claude-test"))
+
+	resp, err := c.Call(context.Background(), prompt.NewPrompt(prompt.UserMessage("request 1")))
+	if err != nil || resp.Text() != "hello" || resp.Model != "claude-test" || resp.Usage.TotalTokens != 3 || resp.FinishReason() != chat.FinishReasonStop {
+		t.Errorf("Call() got = %+v, error = %v", resp, err)
+	}
+
+	var chunks []string
+	err = c.ChatStream(context.Background(), "request 2", func(bts []byte) error {
+		chunks = append(chunks, string(bts))
+		return nil
+	})
+	if err != nil || strings.Join(chunks, "|") != "a|b|c" {
+		t.Errorf("ChatStream() got = %v, error = %v", chunks, err)
+	}
+
+	_, err = c.Chat(context.Background(), "request 3")
+	var statusErr *anthropic.StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode() != http.StatusTooManyRequests ||
+		statusErr.Type != "rate_limit_error" || !strings.Contains(statusErr.Message, "rate limited") {
+		t.Errorf("Chat() error = %v", err)
+	}
+
+	header := ts.Requests()[0].Header
+	if header.Get("x-api-key") != "test-key" || header.Get("anthropic-version") != anthropic.DefaultVersion {
+		t.Errorf("Call() header = %v", header)
+	}
+}
+
+func TestChatModel_System(t *testing.T) {
+	fake := goaitest.NewChatModel(goaitest.Text("ok"))
+	ts := anthropictest.NewServer(fake)
+	defer ts.Close()
+
+	p := prompt.NewPrompt(
+		prompt.SystemMessage("be brief"),
+		prompt.UserMessage("a"),
+		anthropic.Cache(prompt.UserMessage("b")),
+		prompt.AssistantMessage("c"),
+		prompt.UserMessage("d"),
+	)
+	p.ChatOption.Tools = []prompt.Tool{{Name: "now"}, {Name: "weather", Parameters: json.RawMessage(`{"type":"object"}`)}}
+	p.ChatOption.Extensions = []prompt.Extension{&anthropic.Extension{MaxTokens: 100, CacheSystem: true, CacheTools: true, CacheTTL: "1h"}}
+
+	model := anthropic.NewChatModel(ts.AnthropicClient(), "claude-test")
+	if _, err := model.Call(context.Background(), p); err != nil {
+		t.Fatalf("Call() error = %v", err)
+	}
+
+	var req anthropic.MessagesRequest
+	if err := json.Unmarshal(ts.Requests()[0].Body, &req); err != nil {
+		t.Fatal(err)
+	}
+	cacheControl := &anthropic.CacheControl{Type: "ephemeral", TTL: "1h"}
+	wantSystem := []anthropic.ContentBlock{{Type: anthropic.BlockText, Text: "be brief", CacheControl: cacheControl}}
+	if req.MaxTokens != 100 || !reflect.DeepEqual(req.System, wantSystem) {
+		t.Errorf("Call() request = %+v", req)
+	}
+
+	// the consecutive user messages are merged, the cached one is marked.
+	wantMessages := []anthropic.Message{
+		{Role: anthropic.RoleUser, Content: []anthropic.ContentBlock{
+			{Type: anthropic.BlockText, Text: "a"},
+			{Type: anthropic.BlockText, Text: "b", CacheControl: cacheControl},
+		}},
+		{Role: anthropic.RoleAssistant, Content: []anthropic.ContentBlock{{Type: anthropic.BlockText, Text: "c"}}},
+		{Role: anthropic.RoleUser, Content: []anthropic.ContentBlock{{Type: anthropic.BlockText, Text: "d"}}},
+	}
+	if !reflect.DeepEqual(req.Messages, wantMessages) {
+		t.Errorf("Call() messages = %+v, want %+v", req.Messages, wantMessages)
+	}
+
+	if len(req.Tools) != 2 || string(req.Tools[0].InputSchema) != `{"type":"object","properties":{}}` ||
+		req.Tools[0].CacheControl != nil || !reflect.DeepEqual(req.Tools[1].CacheControl, cacheControl) {
+		t.Errorf("Call() tools = %+v", req.Tools)
+	}
+
+	// the fake receives the system prompt as a message again, the merged
+	// user messages as one.
+	if got := fake.LastPrompt().Messages; len(got) != 4 || got[0].Type() != prompt.MessageTypeSystem || got[0].Text() != "be brief" {
+		t.Errorf("Call() prompt = %v", got)
+	}
+}
+
+func TestChatModel_Tools(t *testing.T) {
+	weather := chat.ToolCall{ID: "toolu_1", Name: "weather", Arguments: `{"city":"Paris"}`}
+	now := chat.ToolCall{ID: "toolu_2", Name: "now", Arguments: `{}`}
+	fake := goaitest.NewChatModel(
+		goaitest.ToolCalls(weather, now),
+		goaitest.ToolCalls(weather, now),
+		goaitest.Text("sunny"),
+	)
+	ts := anthropictest.NewServer(fake)
+	defer ts.Close()
+
+	p := prompt.NewPrompt(prompt.UserMessage("weather?"))
+	p.ChatOption.Tools = []prompt.Tool{{Name: "weather"}, {Name: "now"}}
+	model := anthropic.NewChatModel(ts.AnthropicClient(), "claude-test")
+
+	resp, err := model.Call(context.Background(), p)
+	if err != nil || !reflect.DeepEqual(resp.ToolCalls(), []chat.ToolCall{weather, now}) || resp.FinishReason() != chat.FinishReasonToolCalls {
+		t.Errorf("Call() got = %+v, error = %v", resp, err)
+	}
+
+	streamed := &chat.Response{}
+	var deltas []chat.ToolCallDelta
+	err = model.Stream(context.Background(), p, func(chunk *chat.Response) error {
+		for _, generation := range chunk.Generations {
+			deltas = append(deltas, generation.ToolCallDeltas...)
+		}
+		streamed.Append(chunk)
+		return nil
+	})
+	if err != nil || !reflect.DeepEqual(streamed.ToolCalls(), []chat.ToolCall{weather, now}) || streamed.FinishReason() != chat.FinishReasonToolCalls {
+		t.Errorf("Stream() got = %+v, error = %v", streamed, err)
+	}
+	var arguments string
+	for _, delta := range deltas {
+		if delta.Index == 0 {
+			arguments += delta.Arguments
+		}
+	}
+	if len(deltas) != 6 || deltas[0].ID != weather.ID || deltas[3].Index != 1 || arguments != weather.Arguments {
+		t.Errorf("Stream() deltas = %+v", deltas)
+	}
+
+	// the results of the parallel calls are sent in a single user message.
+	p.Messages = append(p.Messages,
+		prompt.AssistantToolCallMessage("", weather, now),
+		prompt.ToolResultMessage(weather, "sunny"),
+		prompt.ToolResultMessage(now, "noon"),
+	)
+	if resp, err = model.Call(context.Background(), p); err != nil || resp.Text() != "sunny" {
+		t.Errorf("Call() got = %+v, error = %v", resp, err)
+	}
+
+	var req anthropic.MessagesRequest
+	if err = json.Unmarshal(ts.Requests()[2].Body, &req); err != nil {
+		t.Fatal(err)
+	}
+	if len(req.Messages) != 3 || len(req.Messages[1].Content) != 2 || req.Messages[1].Content[0].Type != anthropic.BlockToolUse ||
+		string(req.Messages[1].Content[0].Input) != weather.Arguments {
+		t.Fatalf("Call() messages = %+v", req.Messages)
+	}
+	results := req.Messages[2]
+	if results.Role != anthropic.RoleUser || len(results.Content) != 2 || results.Content[1].ToolUseID != now.ID || results.Content[1].Content != "noon" {
+		t.Errorf("Call() results = %+v", results)
+	}
+
+	p.Messages = append(p.Messages, prompt.AssistantToolCallMessage("", chat.ToolCall{ID: "toolu_3", Name: "now", Arguments: "{"}))
+	if _, err = model.Call(context.Background(), p); !errors.Is(err, anthropic.ErrInvalidToolInput) {
+		t.Errorf("Call() error = %v", err)
+	}
+}
+
+func TestChatModel_Thinking(t *testing.T) {
+	reply := goaitest.Reply{Chunks: []*chat.Response{
+		{Generations: []chat.Generation{{Thinking: "let me "}}},
+		{Generations: []chat.Generation{{Thinking: "think"}}},
+		{Generations: []chat.Generation{{Content: "42"}}},
+	}}
+	fake := goaitest.NewChatModel(reply, reply)
+	ts := anthropictest.NewServer(fake)
+	defer ts.Close()
+
+	p := prompt.NewPrompt(prompt.UserMessage("answer?"))
+	p.ChatOption.Extensions = []prompt.Extension{&anthropic.Extension{ThinkingBudget: 2048}}
+	model := anthropic.NewChatModel(ts.AnthropicClient(), "claude-test")
+
+	resp, err := model.Call(context.Background(), p)
+	if err != nil || resp.Text() != "42" || resp.Generations[0].Thinking != "let me think" {
+		t.Errorf("Call() got = %+v, error = %v", resp, err)
+	}
+
+	streamed, _, err := goaitest.Stream(context.Background(), model, p)
+	if err != nil || streamed.Text() != "42" || streamed.Generations[0].Thinking != "let me think" {
+		t.Errorf("Stream() got = %+v, error = %v", streamed, err)
+	}
+
+	var req anthropic.MessagesRequest
+	if err = json.Unmarshal(ts.Requests()[0].Body, &req); err != nil {
+		t.Fatal(err)
+	}
+	if req.Thinking == nil || req.Thinking.Type != "enabled" || req.Thinking.BudgetTokens != 2048 {
+		t.Errorf("Call() thinking = %+v", req.Thinking)
+	}
+
+	for _, extension := range []*anthropic.Extension{{ThinkingBudget: 100}, {ThinkingBudget: 2048, MaxTokens: 2048}} {
+		p.ChatOption.Extensions = []prompt.Extension{extension}
+		if _, err = model.Call(context.Background(), p); err == nil {
+			t.Errorf("Call(%+v) error = nil", extension)
+		}
+	}
+}
+
+func TestChatModel_ThinkingTools(t *testing.T) {
+	weather := chat.ToolCall{ID: "toolu_1", Name: "weather", Arguments: `{"city":"Paris"}`}
+	thinkingToolCall := goaitest.Reply{Chunks: []*chat.Response{
+		{Generations: []chat.Generation{{Thinking: "I need the weather."}}},
+		{Generations: []chat.Generation{{ToolCalls: []chat.ToolCall{weather}}}},
+	}}
+	fake := goaitest.NewChatModel(thinkingToolCall, goaitest.Text("sunny"), thinkingToolCall, goaitest.Text("sunny"))
+	ts := anthropictest.NewServer(fake)
+	defer ts.Close()
+
+	model := anthropic.NewChatModel(ts.AnthropicClient(), "claude-test")
+	p := prompt.NewPrompt(prompt.UserMessage("weather in Paris?"))
+	p.ChatOption.Tools = []prompt.Tool{{Name: "weather"}}
+	p.ChatOption.Extensions = []prompt.Extension{&anthropic.Extension{ThinkingBudget: 1024}}
+
+	// the thinking block has to be sent back with the tool calls.
+	next := func(generation chat.Generation) prompt.Prompt {
+		next := p
+		next.Messages = append(append([]prompt.Message(nil), p.Messages...),
+			prompt.AssistantGenerationMessage(generation),
+			prompt.ToolResultMessage(weather, "sunny"),
+		)
+		return next
+	}
+
+	resp, err := model.Call(context.Background(), p)
+	if err != nil || len(resp.ToolCalls()) != 1 {
+		t.Fatalf("Call() got = %+v, error = %v", resp, err)
+	}
+	blocks, _ := resp.Generations[0].Metadata[anthropic.MetadataThinkingBlocks].([]anthropic.ContentBlock)
+	if len(blocks) != 1 || blocks[0].Thinking != "I need the weather." || blocks[0].Signature == "" {
+		t.Fatalf("Call() thinking blocks = %+v", blocks)
+	}
+	if resp, err = model.Call(context.Background(), next(resp.Generations[0])); err != nil || resp.Text() != "sunny" {
+		t.Errorf("Call() of the second turn got = %+v, error = %v", resp, err)
+	}
+
+	streamed, _, err := goaitest.Stream(context.Background(), model, p)
+	if err != nil || len(streamed.ToolCalls()) != 1 {
+		t.Fatalf("Stream() got = %+v, error = %v", streamed, err)
+	}
+	blocks, _ = streamed.Generations[0].Metadata[anthropic.MetadataThinkingBlocks].([]anthropic.ContentBlock)
+	if len(blocks) != 1 || blocks[0].Thinking != "I need the weather." || blocks[0].Signature == "" {
+		t.Fatalf("Stream() thinking blocks = %+v", blocks)
+	}
+	if resp, err = model.Call(context.Background(), next(streamed.Generations[0])); err != nil || resp.Text() != "sunny" {
+		t.Errorf("Call() after a stream got = %+v, error = %v", resp, err)
+	}
+
+	// without the thinking block the fake rejects the prompt like the API.
+	_, err = model.Call(context.Background(), next(chat.Generation{ToolCalls: []chat.ToolCall{weather}}))
+	var statusErr *anthropic.StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode() != http.StatusBadRequest {
+		t.Errorf("Call() without thinking blocks error = %v", err)
+	}
+}
+
+func TestChatModel_Images(t *testing.T) {
+	fake := goaitest.NewChatModel(goaitest.Text("a cat"))
+	ts := anthropictest.NewServer(fake)
+	defer ts.Close()
+
+	p := prompt.NewPrompt(prompt.UserImageMessage("what is it?",
+		prompt.Image{MimeType: "image/png", Data: []byte("png")},
+		prompt.Image{URL: "https://example.com/cat.jpg"},
+	))
+	if _, err := anthropic.NewChatModel(ts.AnthropicClient(), "claude-test").Call(context.Background(), p); err != nil {
+		t.Fatalf("Call() error = %v", err)
+	}
+
+	var req anthropic.MessagesRequest
+	if err := json.Unmarshal(ts.Requests()[0].Body, &req); err != nil {
+		t.Fatal(err)
+	}
+	want := []anthropic.ContentBlock{
+		{Type: anthropic.BlockImage, Source: &anthropic.ImageSource{Type: "base64", MediaType: "image/png", Data: "cG5n"}},
+		{Type: anthropic.BlockImage, Source: &anthropic.ImageSource{Type: "url", URL: "https://example.com/cat.jpg"}},
+		{Type: anthropic.BlockText, Text: "what is it?"},
+	}
+	if !reflect.DeepEqual(req.Messages[0].Content, want) {
+		t.Errorf("Call() content = %+v", req.Messages[0].Content)
+	}
+
+	if images := prompt.Images(fake.LastPrompt().Messages[0]); len(images) != 2 || string(images[0].Data) != "png" {
+		t.Errorf("Call() images = %+v", images)
+	}
+}
@@ -0,0 +1,204 @@
+// Package anthropic implements goai models with the Anthropic Messages API.
+package anthropic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/tech1024/goai/internal/wire"
+)
+
+// NewClient returns a Client of the Anthropic API, an empty apiKey is read
+// from the ANTHROPIC_API_KEY environment variable and the base url from
+// ANTHROPIC_BASE_URL unless set by an option.
+func NewClient(apiKey string, options ...ClientOption) *Client {
+	if apiKey == "" {
+		apiKey = os.Getenv("ANTHROPIC_API_KEY")
+	}
+
+	client := Client{
+		baseURL:    DefaultBaseURL,
+		httpClient: http.DefaultClient,
+		header:     make(http.Header),
+	}
+	if baseURL := os.Getenv("ANTHROPIC_BASE_URL"); baseURL != "" {
+		client.baseURL = strings.TrimSuffix(baseURL, "/")
+	}
+	client.header.Set("x-api-key", apiKey)
+	client.header.Set("anthropic-version", DefaultVersion)
+
+	for _, option := range options {
+		option(&client)
+	}
+
+	return &client
+}
+
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	header     http.Header // header The default headers of every request.
+}
+
+// Messages sends a request and returns the whole response.
+func (c *Client) Messages(ctx context.Context, req *MessagesRequest) (*MessagesResponse, error) {
+	req.Stream = false
+
+	httpResp, err := c.do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	if httpResp.StatusCode >= http.StatusBadRequest {
+		return nil, newStatusError(httpResp)
+	}
+
+	var resp MessagesResponse
+	if err = json.NewDecoder(httpResp.Body).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("unmarshal: %w", err)
+	}
+
+	return &resp, nil
+}
+
+// MessagesStream sends a request streaming the response, fn receives every
+// event except "ping". It returns the final response assembled from the
+// events, the input of its tool use blocks is validated JSON. A stream ending
+// without "message_stop" returns the partial response and ErrIncompleteStream,
+// an "error" event is returned as *ErrorDetail.
+func (c *Client) MessagesStream(ctx context.Context, req *MessagesRequest, fn func(*Event) error) (*MessagesResponse, error) {
+	req.Stream = true
+
+	httpResp, err := c.do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	if httpResp.StatusCode >= http.StatusBadRequest {
+		return nil, newStatusError(httpResp)
+	}
+
+	var final MessagesResponse
+	partialJSON := make(map[int]*strings.Builder)
+	var stopped bool
+
+	err = wire.ReadEvents(httpResp.Body, func(data []byte) error {
+		var event Event
+		if err := json.Unmarshal(data, &event); err != nil {
+			return fmt.Errorf("unmarshal: %w", err)
+		}
+
+		switch event.Type {
+		case "ping":
+			return nil
+		case "error":
+			return event.Error
+		case "message_start":
+			if event.Message != nil {
+				final = *event.Message
+			}
+		case "content_block_start":
+			for len(final.Content) <= event.Index {
+				final.Content = append(final.Content, ContentBlock{})
+			}
+			partialJSON[event.Index] = &strings.Builder{}
+			if event.ContentBlock != nil {
+				final.Content[event.Index] = *event.ContentBlock
+			}
+		case "content_block_delta":
+			if event.Index >= len(final.Content) || event.Delta == nil {
+				return fmt.Errorf("anthropic: delta of unknown content block %d", event.Index)
+			}
+			block := &final.Content[event.Index]
+			block.Text += event.Delta.Text
+			block.Thinking += event.Delta.Thinking
+			block.Signature += event.Delta.Signature
+			if builder := partialJSON[event.Index]; builder != nil {
+				builder.WriteString(event.Delta.PartialJSON)
+			}
+		case "content_block_stop":
+			if event.Index >= len(final.Content) {
+				return fmt.Errorf("anthropic: stop of unknown content block %d", event.Index)
+			}
+			if block := &final.Content[event.Index]; block.Type == BlockToolUse {
+				var input json.RawMessage
+				var err error
+				if builder := partialJSON[event.Index]; builder != nil {
+					input, err = toolInput(block.Name, builder.String())
+				}
+				if err != nil {
+					return err
+				}
+				block.Input = input
+			}
+		case "message_delta":
+			if event.Delta != nil {
+				final.StopReason = event.Delta.StopReason
+				final.StopSequence = event.Delta.StopSequence
+			}
+			if event.Usage != nil {
+				final.Usage.OutputTokens = event.Usage.OutputTokens
+			}
+		case "message_stop":
+			stopped = true
+		}
+
+		if fn == nil {
+			return nil
+		}
+
+		return fn(&event)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !stopped {
+		return &final, ErrIncompleteStream
+	}
+
+	return &final, nil
+}
+
+// toolInput returns the validated input of a streamed tool use block, an
+// empty input is an empty object.
+func toolInput(name, partialJSON string) (json.RawMessage, error) {
+	if strings.TrimSpace(partialJSON) == "" {
+		return json.RawMessage("{}"), nil
+	}
+	if !json.Valid([]byte(partialJSON)) {
+		return nil, fmt.Errorf("%w: %s: %s", ErrInvalidToolInput, name, partialJSON)
+	}
+
+	return json.RawMessage(partialJSON), nil
+}
+
+func (c *Client) do(ctx context.Context, req *MessagesRequest) (*http.Response, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/v1/messages", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Accept", "application/json")
+	if req.Stream {
+		request.Header.Set("Accept", "text/event-stream")
+	}
+	for key, values := range c.header {
+		request.Header[key] = values
+	}
+
+	return c.httpClient.Do(request)
+}
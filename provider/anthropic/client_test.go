@@ -0,0 +1,83 @@
+package anthropic
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewClient(t *testing.T) {
+	var got *http.Request
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		_, _ = io.WriteString(w, `{"id":"msg_1","content":[{"type":"text","text":"hi"}],"usage":{"input_tokens":1,"output_tokens":1}}`)
+	}))
+	defer ts.Close()
+
+	t.Setenv("ANTHROPIC_API_KEY", "env-key")
+	t.Setenv("ANTHROPIC_BASE_URL", ts.URL+"/")
+
+	client := NewClient("", WithHTTPClient(ts.Client()), WithVersion("2024-01-01"), WithBeta("a", "b"), WithHeader("X-Test", "1"))
+	resp, err := client.Messages(context.Background(), &MessagesRequest{Model: "claude-test", MaxTokens: 1})
+	if err != nil || resp.Content[0].Text != "hi" {
+		t.Fatalf("Messages() got = %+v, error = %v", resp, err)
+	}
+	if got.URL.Path != "/v1/messages" || got.Header.Get("x-api-key") != "env-key" || got.Header.Get("anthropic-version") != "2024-01-01" ||
+		len(got.Header.Values("anthropic-beta")) != 2 || got.Header.Get("X-Test") != "1" {
+		t.Errorf("Messages() request = %s %v", got.URL, got.Header)
+	}
+}
+
+func TestClient_MessagesStream(t *testing.T) {
+	tests := []struct {
+		name   string
+		events string
+		want   error
+	}{
+		{"incomplete", "event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"id\":\"msg_1\"}}\n\n", ErrIncompleteStream},
+		{"invalid tool input", "data: {\"type\":\"content_block_start\",\"index\":0,\"content_block\":{\"type\":\"tool_use\",\"id\":\"toolu_1\",\"name\":\"now\"}}\n\n" +
+			"data: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"input_json_delta\",\"partial_json\":\"{\\\"a\\\"\"}}\n\n" +
+			"data: {\"type\":\"content_block_stop\",\"index\":0}\n\n", ErrInvalidToolInput},
+		{"error event", "data: {\"type\":\"error\",\"error\":{\"type\":\"overloaded_error\",\"message\":\"Overloaded\"}}\n\n", &ErrorDetail{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/event-stream")
+				_, _ = io.WriteString(w, tt.events)
+			}))
+			defer ts.Close()
+
+			client := NewClient("key", WithBaseURL(ts.URL), WithHTTPClient(ts.Client()))
+			_, err := client.MessagesStream(context.Background(), &MessagesRequest{Model: "claude-test", MaxTokens: 1}, nil)
+
+			var detail *ErrorDetail
+			if _, ok := tt.want.(*ErrorDetail); ok {
+				if !errors.As(err, &detail) || detail.Type != "overloaded_error" {
+					t.Errorf("MessagesStream() error = %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.want) {
+				t.Errorf("MessagesStream() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestClient_StatusError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+		_, _ = io.WriteString(w, "<html>bad gateway</html>")
+	}))
+	defer ts.Close()
+
+	_, err := NewClient("key", WithBaseURL(ts.URL)).Messages(context.Background(), &MessagesRequest{Model: "claude-test", MaxTokens: 1})
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode() != http.StatusBadGateway || statusErr.Body != "<html>bad gateway</html>" || statusErr.Type != "" {
+		t.Errorf("Messages() error = %v", err)
+	}
+}
@@ -0,0 +1,71 @@
+package anthropic
+
+import (
+	"errors"
+
+	"github.com/tech1024/goai"
+	"github.com/tech1024/goai/prompt"
+)
+
+// the anthropic provider serves DSNs like
+// "anthropic:claude-sonnet-4-5?max_tokens=8192&thinking_budget=2048".
+func init() {
+	goai.RegisterProvider(ProviderName, goai.Provider{
+		ChatModel: func(dsn *goai.DSN) (goai.ChatModel, error) {
+			if dsn.Model == "" {
+				return nil, errors.New("anthropic dsn: missing model")
+			}
+
+			apiKey, err := dsn.APIKey()
+			if err != nil {
+				return nil, err
+			}
+			var options []ClientOption
+			if baseURL := dsn.BaseURL("https", ""); baseURL != "" {
+				options = append(options, WithBaseURL(baseURL))
+			}
+
+			extension := Extension{
+				CacheTTL:      dsn.Param("cache_ttl"),
+				StopSequences: dsn.Values("stop"),
+			}
+			if extension.Temperature, err = dsn.Float("temperature"); err != nil {
+				return nil, err
+			}
+			if extension.TopP, err = dsn.Float("top_p"); err != nil {
+				return nil, err
+			}
+			if extension.TopK, err = dsn.Int("top_k"); err != nil {
+				return nil, err
+			}
+			maxTokens, err := dsn.Int("max_tokens")
+			if err != nil {
+				return nil, err
+			}
+			if maxTokens != nil {
+				extension.MaxTokens = *maxTokens
+			}
+			thinkingBudget, err := dsn.Int("thinking_budget")
+			if err != nil {
+				return nil, err
+			}
+			if thinkingBudget != nil {
+				extension.ThinkingBudget = *thinkingBudget
+			}
+			cacheSystem, err := dsn.Bool("cache_system")
+			if err != nil {
+				return nil, err
+			}
+			cacheTools, err := dsn.Bool("cache_tools")
+			if err != nil {
+				return nil, err
+			}
+			extension.CacheSystem = cacheSystem != nil && *cacheSystem
+			extension.CacheTools = cacheTools != nil && *cacheTools
+
+			return goai.DefaultChatOption(prompt.Option{
+				Extensions: []prompt.Extension{&extension},
+			})(NewChatModel(NewClient(apiKey, options...), dsn.Model)), nil
+		},
+	})
+}
@@ -0,0 +1,42 @@
+package anthropic_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/tech1024/goai"
+	"github.com/tech1024/goai/goaitest"
+	"github.com/tech1024/goai/prompt"
+	"github.com/tech1024/goai/provider/anthropic"
+	"github.com/tech1024/goai/provider/anthropic/anthropictest"
+)
+
+func TestOpenChatModel(t *testing.T) {
+	fake := goaitest.NewChatModel(goaitest.Text("hello"))
+	ts := anthropictest.NewServer(fake)
+	defer ts.Close()
+	t.Setenv("ANTHROPIC_API_KEY", "env-key")
+
+	chatModel, err := goai.OpenChatModel("anthropic:claude-test?max_tokens=2048&temperature=0.5&top_k=20&cache_system=true&base_url=" + ts.URL)
+	if err != nil {
+		t.Fatalf("OpenChatModel() error = %v", err)
+	}
+
+	resp, err := chatModel.Call(context.Background(), prompt.NewPrompt(prompt.SystemMessage("be brief"), prompt.UserMessage("hi")))
+	if err != nil || resp.Text() != "hello" {
+		t.Fatalf("Call() got = %+v, error = %v", resp, err)
+	}
+
+	request := ts.Requests()[0]
+	var req anthropic.MessagesRequest
+	_ = json.Unmarshal(request.Body, &req)
+	if req.Model != "claude-test" || req.MaxTokens != 2048 || *req.Temperature != 0.5 || *req.TopK != 20 ||
+		req.System[0].CacheControl == nil || request.Header.Get("x-api-key") != "env-key" {
+		t.Errorf("Call() request = %s %v", request.Body, request.Header)
+	}
+
+	if _, err = goai.OpenEmbeddingModel("anthropic:claude-test"); err == nil {
+		t.Errorf("OpenEmbeddingModel() error = nil")
+	}
+}
@@ -0,0 +1,82 @@
+package anthropic
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/tech1024/goai/internal/wire"
+)
+
+var (
+	// ErrIncompleteStream is returned when a stream ended without "message_stop".
+	ErrIncompleteStream = errors.New("anthropic: stream ended before message_stop")
+
+	// ErrInvalidToolInput is returned when the streamed input of a tool use
+	// block is no valid JSON.
+	ErrInvalidToolInput = errors.New("anthropic: invalid tool input")
+)
+
+// ErrorDetail is the error reported by the API, e.g. of type "overloaded_error".
+type ErrorDetail struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+func (e *ErrorDetail) Error() string {
+	return fmt.Sprintf("anthropic: %s: %s", e.Type, e.Message)
+}
+
+// StatusError is returned when the API responds with an error status.
+type StatusError struct {
+	// Code the http status code.
+	Code int
+
+	// Status the http status, e.g. "429 Too Many Requests".
+	Status string
+
+	// Type and Message the error reported by the API, empty if the body is no
+	// API error.
+	Type    string
+	Message string
+
+	// Body the beginning of the response body.
+	Body string
+}
+
+func (e *StatusError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("http status code: %s, %s: %s", e.Status, e.Type, e.Message)
+	}
+	if e.Body != "" {
+		return fmt.Sprintf("http status code: %s, body: %s", e.Status, e.Body)
+	}
+
+	return fmt.Sprintf("http status code: %s", e.Status)
+}
+
+// StatusCode returns the http status code.
+func (e *StatusError) StatusCode() int {
+	return e.Code
+}
+
+func newStatusError(httpResp *http.Response) *StatusError {
+	bts, body := wire.ReadErrorBody(httpResp)
+
+	statusErr := StatusError{
+		Code:   httpResp.StatusCode,
+		Status: httpResp.Status,
+		Body:   body,
+	}
+
+	var e struct {
+		Error ErrorDetail `json:"error"`
+	}
+	if json.Unmarshal(bts, &e) == nil {
+		statusErr.Type = e.Error.Type
+		statusErr.Message = e.Error.Message
+	}
+
+	return &statusErr
+}
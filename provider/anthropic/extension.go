@@ -0,0 +1,95 @@
+package anthropic
+
+import (
+	"github.com/tech1024/goai/prompt"
+)
+
+// ProviderName the name of the anthropic provider.
+const ProviderName = "anthropic"
+
+// MinThinkingBudget the smallest thinking budget accepted by the API.
+const MinThinkingBudget = 1024
+
+// MetadataThinkingBlocks the metadata key of the signed thinking and redacted
+// thinking blocks of a generation, the API requires them back in the
+// assistant message of a tool calling conversation with thinking enabled.
+const MetadataThinkingBlocks = "anthropic_thinking_blocks"
+
+// Extension the anthropic specific options of a prompt, attach it to
+// prompt.Option.Extensions.
+type Extension struct {
+	// MaxTokens the maximum number of tokens generated, zero uses the
+	// MaxTokens of the ChatModel.
+	MaxTokens int
+
+	// ThinkingBudget enables the extended thinking with the number of tokens
+	// used for thinking, at least MinThinkingBudget.
+	ThinkingBudget int
+
+	// CacheSystem caches the prompt up to the end of the system prompt.
+	CacheSystem bool
+
+	// CacheTools caches the prompt up to the end of the tools.
+	CacheTools bool
+
+	// CacheTTL the lifetime of the cache entries, "5m" or "1h", empty uses "5m".
+	CacheTTL string
+
+	// StopSequences the sequences ending the generation.
+	StopSequences []string
+
+	// Temperature, TopP and TopK the sampling of the generation.
+	Temperature *float64
+	TopP        *float64
+	TopK        *int
+}
+
+func (e *Extension) Provider() string {
+	return ProviderName
+}
+
+// extensionOf returns the anthropic extension of the prompt, an empty one if
+// there is none.
+func extensionOf(option prompt.Option) *Extension {
+	if extension, ok := option.Extension(ProviderName).(*Extension); ok && extension != nil {
+		return extension
+	}
+
+	return &Extension{}
+}
+
+type cachedMessage struct {
+	prompt.Message
+}
+
+func (m cachedMessage) Cached() bool {
+	return true
+}
+
+// Pinned keeps a pinned message pinned once it is cached.
+func (m cachedMessage) Pinned() bool {
+	return prompt.IsPinned(m.Message)
+}
+
+// Cache marks the end of a cacheable prefix of the prompt at the message,
+// the prompt up to and including it is cached by the API.
+func Cache(message prompt.Message) prompt.Message {
+	if IsCached(message) {
+		return message
+	}
+
+	return cachedMessage{Message: message}
+}
+
+// IsCached reports whether the message has been marked by Cache.
+func IsCached(message prompt.Message) bool {
+	cached, ok := message.(interface{ Cached() bool })
+	return ok && cached.Cached()
+}
+
+// ThinkingBlocks returns the thinking blocks of an assistant message, see
+// MetadataThinkingBlocks.
+func ThinkingBlocks(message prompt.Message) []ContentBlock {
+	blocks, _ := message.Metadata()[MetadataThinkingBlocks].([]ContentBlock)
+	return blocks
+}
@@ -0,0 +1,54 @@
+package anthropic
+
+import (
+	"net/http"
+	"strings"
+)
+
+const (
+	// DefaultBaseURL the address of the Anthropic API.
+	DefaultBaseURL = "https://api.anthropic.com"
+
+	// DefaultVersion the anthropic-version sent with every request.
+	DefaultVersion = "2023-06-01"
+)
+
+// ClientOption configures a Client.
+type ClientOption func(*Client)
+
+// WithBaseURL sets the base url of the API, e.g. of a proxy.
+func WithBaseURL(baseURL string) ClientOption {
+	return func(c *Client) {
+		c.baseURL = strings.TrimSuffix(baseURL, "/")
+	}
+}
+
+// WithHTTPClient sets the http client sending the requests, defaults to http.DefaultClient.
+func WithHTTPClient(httpClient *http.Client) ClientOption {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithHeader adds a header sent with every request.
+func WithHeader(key, value string) ClientOption {
+	return func(c *Client) {
+		c.header.Add(key, value)
+	}
+}
+
+// WithVersion sets the anthropic-version header, defaults to DefaultVersion.
+func WithVersion(version string) ClientOption {
+	return func(c *Client) {
+		c.header.Set("anthropic-version", version)
+	}
+}
+
+// WithBeta enables beta features of the API, e.g. "interleaved-thinking-2025-05-14".
+func WithBeta(features ...string) ClientOption {
+	return func(c *Client) {
+		for _, feature := range features {
+			c.header.Add("anthropic-beta", feature)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package anthropic
+
+import "encoding/json"
+
+// Roles of the messages.
+const (
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// Types of the content blocks.
+const (
+	BlockText             = "text"
+	BlockImage            = "image"
+	BlockToolUse          = "tool_use"
+	BlockToolResult       = "tool_result"
+	BlockThinking         = "thinking"
+	BlockRedactedThinking = "redacted_thinking"
+)
+
+// Stop reasons of a message.
+const (
+	StopEndTurn      = "end_turn"
+	StopMaxTokens    = "max_tokens"
+	StopSequence     = "stop_sequence"
+	StopToolUse      = "tool_use"
+	StopPauseTurn    = "pause_turn"
+	StopRefusal      = "refusal"
+	StopContextLimit = "model_context_window_exceeded"
+)
+
+// CacheControl marks the end of a cacheable prefix of the prompt.
+type CacheControl struct {
+	// Type is always "ephemeral".
+	Type string `json:"type"`
+
+	// TTL the lifetime of the cache entry, "5m" or "1h", empty uses "5m".
+	TTL string `json:"ttl,omitempty"`
+}
+
+// ImageSource is the source of an image block, base64 data or an url.
+type ImageSource struct {
+	// Type is "base64" or "url".
+	Type      string `json:"type"`
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+// ContentBlock is a block of the content of a message, its Type selects the
+// fields in use.
+type ContentBlock struct {
+	Type string `json:"type"`
+
+	// Text is the text of a "text" block.
+	Text string `json:"text,omitempty"`
+
+	// Source is the image of an "image" block.
+	Source *ImageSource `json:"source,omitempty"`
+
+	// ID, Name and Input describe a "tool_use" block.
+	ID    string          `json:"id,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
+
+	// ToolUseID, Content and IsError describe a "tool_result" block.
+	ToolUseID string `json:"tool_use_id,omitempty"`
+	Content   string `json:"content,omitempty"`
+	IsError   bool   `json:"is_error,omitempty"`
+
+	// Thinking and Signature describe a "thinking" block, Data a
+	// "redacted_thinking" block.
+	Thinking  string `json:"thinking,omitempty"`
+	Signature string `json:"signature,omitempty"`
+	Data      string `json:"data,omitempty"`
+
+	CacheControl *CacheControl `json:"cache_control,omitempty"`
+}
+
+// Message is a message of the conversation.
+type Message struct {
+	Role    string         `json:"role"`
+	Content []ContentBlock `json:"content"`
+}
+
+// Tool is a tool the model may use.
+type Tool struct {
+	Name         string          `json:"name"`
+	Description  string          `json:"description,omitempty"`
+	InputSchema  json.RawMessage `json:"input_schema"`
+	CacheControl *CacheControl   `json:"cache_control,omitempty"`
+}
+
+// Thinking configures the extended thinking.
+type Thinking struct {
+	// Type is "enabled" or "disabled".
+	Type string `json:"type"`
+
+	// BudgetTokens is the maximum number of tokens used for thinking, at least
+	// 1024 and less than MaxTokens.
+	BudgetTokens int `json:"budget_tokens,omitempty"`
+}
+
+// MessagesRequest is the request passed to [Client.Messages].
+type MessagesRequest struct {
+	Model     string    `json:"model"`
+	Messages  []Message `json:"messages"`
+	MaxTokens int       `json:"max_tokens"`
+
+	// System is the system prompt, kept out of Messages by the Messages API.
+	System []ContentBlock `json:"system,omitempty"`
+
+	Tools         []Tool    `json:"tools,omitempty"`
+	Thinking      *Thinking `json:"thinking,omitempty"`
+	StopSequences []string  `json:"stop_sequences,omitempty"`
+	Temperature   *float64  `json:"temperature,omitempty"`
+	TopP          *float64  `json:"top_p,omitempty"`
+	TopK          *int      `json:"top_k,omitempty"`
+
+	// Stream streams the response, set by [Client.MessagesStream].
+	Stream bool `json:"stream,omitempty"`
+}
+
+// Usage is the tokens used by a request.
+type Usage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens,omitempty"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens,omitempty"`
+}
+
+// MessagesResponse is the response from [Client.Messages].
+type MessagesResponse struct {
+	ID           string         `json:"id"`
+	Type         string         `json:"type"`
+	Role         string         `json:"role"`
+	Model        string         `json:"model"`
+	Content      []ContentBlock `json:"content"`
+	StopReason   string         `json:"stop_reason,omitempty"`
+	StopSequence string         `json:"stop_sequence,omitempty"`
+	Usage        Usage          `json:"usage"`
+}
+
+// Delta is the delta of a "content_block_delta" or "message_delta" event.
+type Delta struct {
+	// Type is "text_delta", "input_json_delta", "thinking_delta" or
+	// "signature_delta" for a content block.
+	Type        string `json:"type,omitempty"`
+	Text        string `json:"text,omitempty"`
+	PartialJSON string `json:"partial_json,omitempty"`
+	Thinking    string `json:"thinking,omitempty"`
+	Signature   string `json:"signature,omitempty"`
+
+	// StopReason and StopSequence are set by a "message_delta" event.
+	StopReason   string `json:"stop_reason,omitempty"`
+	StopSequence string `json:"stop_sequence,omitempty"`
+}
+
+// Event is an event of a streamed response.
+type Event struct {
+	// Type is "message_start", "content_block_start", "content_block_delta",
+	// "content_block_stop", "message_delta", "message_stop", "ping" or "error".
+	Type string `json:"type"`
+
+	// Message is set by "message_start".
+	Message *MessagesResponse `json:"message,omitempty"`
+
+	// Index is the index of the content block of a content block event.
+	Index int `json:"index"`
+
+	// ContentBlock is set by "content_block_start".
+	ContentBlock *ContentBlock `json:"content_block,omitempty"`
+
+	// Delta is set by "content_block_delta" and "message_delta".
+	Delta *Delta `json:"delta,omitempty"`
+
+	// Usage is the cumulative usage of "message_delta".
+	Usage *Usage `json:"usage,omitempty"`
+
+	// Error is set by "error".
+	Error *ErrorDetail `json:"error,omitempty"`
+}
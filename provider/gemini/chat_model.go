@@ -0,0 +1,334 @@
+package gemini
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/tech1024/goai/chat"
+	"github.com/tech1024/goai/internal/wire"
+	"github.com/tech1024/goai/prompt"
+)
+
+func NewChatModel(client *Client, model string) *ChatModel {
+	return &ChatModel{
+		client: client,
+		model:  model,
+	}
+}
+
+type ChatModel struct {
+	client *Client
+	model  string
+}
+
+func (chatModel *ChatModel) Call(ctx context.Context, prompt prompt.Prompt) (*chat.Response, error) {
+	model, req, err := chatModel.buildGenerateContentRequest(prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := chatModel.client.GenerateContent(ctx, model, req)
+	if err != nil {
+		return nil, err
+	}
+	if err = blocked(resp); err != nil {
+		return nil, err
+	}
+
+	response := &chat.Response{
+		ID:    resp.ResponseID,
+		Model: wire.ModelOf(resp.ModelVersion, model),
+		Usage: toUsage(resp.UsageMetadata),
+	}
+	candidates := append([]Candidate(nil), resp.Candidates...)
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].Index < candidates[j].Index })
+	for _, candidate := range candidates {
+		generation := toGeneration(candidate, 0)
+		generation.FinishReason = toFinishReason(candidate.FinishReason, len(generation.ToolCalls) > 0)
+		response.Generations = append(response.Generations, generation)
+	}
+
+	return response, nil
+}
+
+func (chatModel *ChatModel) Stream(ctx context.Context, prompt prompt.Prompt, fn func(*chat.Response) error) error {
+	model, req, err := chatModel.buildGenerateContentRequest(prompt)
+	if err != nil {
+		return err
+	}
+
+	// the number of calls of every candidate so far, the finish reason of a
+	// candidate calling functions is reported by a later chunk than the calls.
+	toolCalls := make(map[int]int)
+
+	// every chunk reports the usage so far, the latest one is passed on by a
+	// final chunk once the stream ended as a stream reports the usage with its
+	// last chunk.
+	var usage *UsageMetadata
+	var id, reported string
+
+	err = chatModel.client.StreamGenerateContent(ctx, model, req, func(resp *GenerateContentResponse) error {
+		if err := blocked(resp); err != nil {
+			return err
+		}
+		if resp.UsageMetadata != nil {
+			usage = resp.UsageMetadata
+		}
+		id, reported = resp.ResponseID, resp.ModelVersion
+
+		chunk := &chat.Response{
+			ID:    resp.ResponseID,
+			Model: wire.ModelOf(resp.ModelVersion, model),
+		}
+		for _, candidate := range resp.Candidates {
+			if candidate.Index < 0 {
+				continue
+			}
+			for len(chunk.Generations) <= candidate.Index {
+				chunk.Generations = append(chunk.Generations, chat.Generation{})
+			}
+
+			generation := toGeneration(candidate, toolCalls[candidate.Index])
+			toolCalls[candidate.Index] += len(generation.ToolCalls)
+			generation.FinishReason = toFinishReason(candidate.FinishReason, toolCalls[candidate.Index] > 0)
+			chunk.Generations[candidate.Index] = generation
+		}
+
+		return fn(chunk)
+	})
+	if err != nil || usage == nil {
+		return err
+	}
+
+	return fn(&chat.Response{ID: id, Model: wire.ModelOf(reported, model), Usage: toUsage(usage)})
+}
+
+func (chatModel *ChatModel) buildGenerateContentRequest(p prompt.Prompt) (string, *GenerateContentRequest, error) {
+	extension, err := extensionOf(p.ChatOption.Extensions)
+	if err != nil {
+		return "", nil, err
+	}
+
+	model := chatModel.model
+	if p.ChatOption.Model != "" {
+		model = p.ChatOption.Model
+	}
+
+	request := &GenerateContentRequest{
+		SafetySettings: extension.SafetySettings,
+		GenerationConfig: &GenerationConfig{
+			MaxOutputTokens:  extension.MaxOutputTokens,
+			StopSequences:    extension.StopSequences,
+			Temperature:      extension.Temperature,
+			TopP:             extension.TopP,
+			TopK:             extension.TopK,
+			ResponseMIMEType: extension.ResponseMIMEType,
+			ResponseSchema:   extension.ResponseSchema,
+			ResponseLogprobs: p.ChatOption.LogProbs || p.ChatOption.TopLogProbs > 0,
+			Logprobs:         p.ChatOption.TopLogProbs,
+		},
+	}
+	if p.ChatOption.Candidates > 1 {
+		request.GenerationConfig.CandidateCount = p.ChatOption.Candidates
+	}
+	if extension.ThinkingBudget != nil || extension.IncludeThoughts {
+		request.GenerationConfig.ThinkingConfig = &ThinkingConfig{
+			ThinkingBudget:  extension.ThinkingBudget,
+			IncludeThoughts: extension.IncludeThoughts,
+		}
+	}
+
+	// a function response names the function, the tool messages may only
+	// know the id of the call.
+	names := make(map[string]string)
+	for _, message := range p.Messages {
+		// the system prompt is no content of the Gemini API.
+		if message.Type() == prompt.MessageTypeSystem {
+			if request.SystemInstruction == nil {
+				request.SystemInstruction = &Content{}
+			}
+			request.SystemInstruction.Parts = append(request.SystemInstruction.Parts, Part{Text: message.Text()})
+			continue
+		}
+
+		content, err := toContent(message, names)
+		if err != nil {
+			return "", nil, err
+		}
+		if len(content.Parts) == 0 {
+			continue
+		}
+
+		// consecutive contents of a role, e.g. the results of parallel
+		// function calls, are merged into one content.
+		if last := len(request.Contents) - 1; last >= 0 && request.Contents[last].Role == content.Role {
+			request.Contents[last].Parts = append(request.Contents[last].Parts, content.Parts...)
+			continue
+		}
+		request.Contents = append(request.Contents, content)
+	}
+
+	if len(p.ChatOption.Tools) > 0 {
+		tool := Tool{}
+		for _, t := range p.ChatOption.Tools {
+			tool.FunctionDeclarations = append(tool.FunctionDeclarations, FunctionDeclaration{
+				Name:        t.Name,
+				Description: t.Description,
+				Parameters:  t.Parameters,
+			})
+		}
+		request.Tools = []Tool{tool}
+	}
+
+	return model, request, nil
+}
+
+// toContent returns the content of a message, names maps the ids of the
+// function calls to their names.
+func toContent(message prompt.Message, names map[string]string) (Content, error) {
+	switch message.Type() {
+	case prompt.MessageTypeTool:
+		name := prompt.ToolName(message)
+		if name == "" {
+			name = names[prompt.ToolCallID(message)]
+		}
+		if name == "" {
+			return Content{}, fmt.Errorf("gemini: unknown function of tool call %q", prompt.ToolCallID(message))
+		}
+		return Content{Role: RoleUser, Parts: []Part{{
+			FunctionResponse: &FunctionResponse{Name: name, Response: functionResponse(message.Text())},
+		}}}, nil
+	case prompt.MessageTypeAssistant:
+		content := Content{Role: RoleModel}
+		if message.Text() != "" {
+			content.Parts = append(content.Parts, Part{Text: message.Text()})
+		}
+		for _, toolCall := range prompt.ToolCalls(message) {
+			args := json.RawMessage(toolCall.Arguments)
+			if strings.TrimSpace(toolCall.Arguments) == "" {
+				args = json.RawMessage("{}")
+			}
+			if !json.Valid(args) {
+				return Content{}, fmt.Errorf("gemini: invalid arguments of %s: %s", toolCall.Name, toolCall.Arguments)
+			}
+			names[toolCall.ID] = toolCall.Name
+			content.Parts = append(content.Parts, Part{FunctionCall: &FunctionCall{Name: toolCall.Name, Args: args}})
+		}
+		return content, nil
+	default:
+		content := Content{Role: RoleUser}
+		for _, image := range prompt.Images(message) {
+			if len(image.Data) > 0 {
+				content.Parts = append(content.Parts, Part{InlineData: &Blob{MIMEType: image.MimeType, Data: base64.StdEncoding.EncodeToString(image.Data)}})
+				continue
+			}
+			content.Parts = append(content.Parts, Part{FileData: &FileData{MIMEType: image.MimeType, FileURI: image.URL}})
+		}
+		if message.Text() != "" {
+			content.Parts = append(content.Parts, Part{Text: message.Text()})
+		}
+		return content, nil
+	}
+}
+
+// functionResponse returns the response of a function, a result which is no
+// JSON object is wrapped into one.
+func functionResponse(result string) json.RawMessage {
+	var object map[string]any
+	if json.Unmarshal([]byte(result), &object) == nil && object != nil {
+		return json.RawMessage(result)
+	}
+
+	bts, _ := json.Marshal(map[string]string{"result": result})
+	return bts
+}
+
+// blocked returns a *BlockedError if the prompt has been blocked.
+func blocked(resp *GenerateContentResponse) error {
+	if resp.PromptFeedback == nil || resp.PromptFeedback.BlockReason == "" {
+		return nil
+	}
+
+	return &BlockedError{Reason: resp.PromptFeedback.BlockReason, SafetyRatings: resp.PromptFeedback.SafetyRatings}
+}
+
+// toGeneration returns the generation of the candidate, calls the number of
+// calls of the candidate in the previous chunks of a stream.
+func toGeneration(candidate Candidate, calls int) chat.Generation {
+	var generation chat.Generation
+	for _, part := range candidate.Content.Parts {
+		switch {
+		case part.FunctionCall != nil:
+			arguments := string(part.FunctionCall.Args)
+			if arguments == "" {
+				arguments = "{}"
+			}
+			// gemini identifies the calls by their function, an id is made up
+			// if the model returns none.
+			id := part.FunctionCall.ID
+			if id == "" {
+				id = fmt.Sprintf("call_%d_%d", candidate.Index, calls+len(generation.ToolCalls))
+			}
+			generation.ToolCalls = append(generation.ToolCalls, chat.ToolCall{ID: id, Name: part.FunctionCall.Name, Arguments: arguments})
+		case part.Thought:
+			generation.Thinking += part.Text
+		default:
+			generation.Content += part.Text
+		}
+	}
+	if candidate.LogprobsResult != nil {
+		generation.LogProbs = toLogProbs(candidate.LogprobsResult)
+	}
+
+	return generation
+}
+
+func toLogProbs(result *LogprobsResult) []chat.LogProb {
+	logProbs := make([]chat.LogProb, len(result.ChosenCandidates))
+	for i, chosen := range result.ChosenCandidates {
+		logProbs[i] = chat.LogProb{Token: chosen.Token, LogProb: chosen.LogProbability}
+		if i < len(result.TopCandidates) {
+			for _, top := range result.TopCandidates[i].Candidates {
+				logProbs[i].TopLogProbs = append(logProbs[i].TopLogProbs, chat.LogProb{Token: top.Token, LogProb: top.LogProbability})
+			}
+		}
+	}
+
+	return logProbs
+}
+
+// toUsage returns the usage of a request, the completion tokens include the
+// tokens used for thinking.
+func toUsage(usage *UsageMetadata) chat.Usage {
+	if usage == nil {
+		return chat.Usage{}
+	}
+
+	return chat.Usage{
+		PromptTokens:     usage.PromptTokenCount,
+		CompletionTokens: usage.CandidatesTokenCount + usage.ThoughtsTokenCount,
+		TotalTokens:      usage.TotalTokenCount,
+	}
+}
+
+func toFinishReason(reason string, toolCalls bool) chat.FinishReason {
+	switch reason {
+	case "":
+		return ""
+	case FinishStop:
+		if toolCalls {
+			return chat.FinishReasonToolCalls
+		}
+		return chat.FinishReasonStop
+	case FinishMaxTokens:
+		return chat.FinishReasonLength
+	case FinishSafety, FinishRecitation, FinishBlocklist, FinishProhibitedContent, FinishSPII:
+		return chat.FinishReasonContentFilter
+	default:
+		return chat.FinishReason(strings.ToLower(reason))
+	}
+}
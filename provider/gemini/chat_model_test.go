@@ -0,0 +1,275 @@
+package gemini_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tech1024/goai"
+	"github.com/tech1024/goai/chat"
+	"github.com/tech1024/goai/goaitest"
+	"github.com/tech1024/goai/prompt"
+	"github.com/tech1024/goai/provider/gemini"
+	"github.com/tech1024/goai/provider/gemini/geminitest"
+	"github.com/tech1024/goai/usage"
+)
+
+func TestChatModel(t *testing.T) {
+	fake := goaitest.NewChatModel(
+		goaitest.Text("hello").WithUsage(chat.Usage{PromptTokens: 2, CompletionTokens: 1, TotalTokens: 3}),
+		goaitest.Chunks("a", "b", "c").WithUsage(chat.Usage{PromptTokens: 2, CompletionTokens: 3, TotalTokens: 5}),
+		goaitest.Error(&goaitest.StatusError{Code: http.StatusTooManyRequests, Message: "quota exceeded"}),
+	)
+	ts := geminitest.NewServer(fake, nil)
+	defer ts.Close()
+
+	model := gemini.NewChatModel(ts.GeminiClient(), "gemini-test")
+	c := goai.NewChat(model)
+
+	resp, err := c.Call(context.Background(), prompt.NewPrompt(prompt.UserMessage("request 1")))
+	if err != nil || resp.Text() != "hello" || resp.Model != "gemini-test" || resp.Usage.TotalTokens != 3 || resp.FinishReason() != chat.FinishReasonStop {
+		t.Errorf("Call() got = %+v, error = %v", resp, err)
+	}
+
+	streamed := &chat.Response{}
+	var chunks []string
+	err = model.Stream(context.Background(), prompt.NewPrompt(prompt.UserMessage("request 2")), func(chunk *chat.Response) error {
+		if text := chunk.Text(); text != "" {
+			chunks = append(chunks, text)
+		}
+		streamed.Append(chunk)
+		return nil
+	})
+	if err != nil || strings.Join(chunks, "|") != "a|b|c" || streamed.Usage.TotalTokens != 5 || streamed.FinishReason() != chat.FinishReasonStop {
+		t.Errorf("Stream() got = %v, %+v, error = %v", chunks, streamed, err)
+	}
+
+	_, err = c.Chat(context.Background(), "request 3")
+	var statusErr *gemini.StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode() != http.StatusTooManyRequests || statusErr.Reason != "RESOURCE_EXHAUSTED" {
+		t.Errorf("Chat() error = %v", err)
+	}
+
+	requests := ts.Requests()
+	if requests[0].Path != "/v1beta/models/gemini-test:generateContent" || requests[0].Header.Get("x-goog-api-key") != "test-key" {
+		t.Errorf("Call() request = %s %v", requests[0].Path, requests[0].Header)
+	}
+	if requests[1].Path != "/v1beta/models/gemini-test:streamGenerateContent" {
+		t.Errorf("Stream() path = %s", requests[1].Path)
+	}
+}
+
+func TestChatModel_Contents(t *testing.T) {
+	fake := goaitest.NewChatModel(goaitest.Text("ok"))
+	ts := geminitest.NewServer(fake, nil)
+	defer ts.Close()
+
+	weather := chat.ToolCall{ID: "call_0_0", Name: "weather", Arguments: `{"city":"Paris"}`}
+	p := prompt.NewPrompt(
+		prompt.SystemMessage("be brief"),
+		prompt.UserImageMessage("what is it?",
+			prompt.Image{MimeType: "image/png", Data: []byte("png")},
+			prompt.Image{MimeType: "image/jpeg", URL: "gs://bucket/cat.jpg"},
+		),
+		prompt.UserMessage("and the weather?"),
+		prompt.AssistantToolCallMessage("", weather),
+		prompt.ToolResultMessage(chat.ToolCall{ID: weather.ID}, "sunny"),
+	)
+	p.ChatOption.Tools = []prompt.Tool{{Name: "weather", Description: "the weather", Parameters: json.RawMessage(`{"type":"object"}`)}}
+	temperature := 0.2
+	p.ChatOption.Extensions = []prompt.Extension{&gemini.Extension{
+		Temperature:    &temperature,
+		SafetySettings: []gemini.SafetySetting{{Category: "HARM_CATEGORY_HARASSMENT", Threshold: "BLOCK_ONLY_HIGH"}},
+	}}
+
+	if _, err := gemini.NewChatModel(ts.GeminiClient(), "gemini-test").Call(context.Background(), p); err != nil {
+		t.Fatalf("Call() error = %v", err)
+	}
+
+	var req gemini.GenerateContentRequest
+	if err := json.Unmarshal(ts.Requests()[0].Body, &req); err != nil {
+		t.Fatal(err)
+	}
+	if req.SystemInstruction == nil || req.SystemInstruction.Parts[0].Text != "be brief" {
+		t.Errorf("Call() system instruction = %+v", req.SystemInstruction)
+	}
+
+	// the consecutive user messages are merged.
+	want := []gemini.Content{
+		{Role: gemini.RoleUser, Parts: []gemini.Part{
+			{InlineData: &gemini.Blob{MIMEType: "image/png", Data: "cG5n"}},
+			{FileData: &gemini.FileData{MIMEType: "image/jpeg", FileURI: "gs://bucket/cat.jpg"}},
+			{Text: "what is it?"},
+			{Text: "and the weather?"},
+		}},
+		{Role: gemini.RoleModel, Parts: []gemini.Part{{FunctionCall: &gemini.FunctionCall{Name: "weather", Args: json.RawMessage(weather.Arguments)}}}},
+		{Role: gemini.RoleUser, Parts: []gemini.Part{{FunctionResponse: &gemini.FunctionResponse{Name: "weather", Response: json.RawMessage(`{"result":"sunny"}`)}}}},
+	}
+	if !reflect.DeepEqual(req.Contents, want) {
+		t.Errorf("Call() contents = %+v, want %+v", req.Contents, want)
+	}
+
+	if len(req.Tools) != 1 || len(req.Tools[0].FunctionDeclarations) != 1 || req.Tools[0].FunctionDeclarations[0].Name != "weather" {
+		t.Errorf("Call() tools = %+v", req.Tools)
+	}
+	if *req.GenerationConfig.Temperature != temperature || len(req.SafetySettings) != 1 {
+		t.Errorf("Call() config = %+v, safety = %+v", req.GenerationConfig, req.SafetySettings)
+	}
+
+	if _, err := gemini.NewChatModel(ts.GeminiClient(), "gemini-test").Call(context.Background(),
+		prompt.NewPrompt(prompt.ToolResultMessage(chat.ToolCall{ID: "unknown"}, "sunny"))); err == nil {
+		t.Errorf("Call() error = nil")
+	}
+}
+
+func TestChatModel_Tools(t *testing.T) {
+	weather := chat.ToolCall{Name: "weather", Arguments: `{"city":"Paris"}`}
+	now := chat.ToolCall{Name: "now", Arguments: `{}`}
+	fake := goaitest.NewChatModel(goaitest.ToolCalls(weather, now), goaitest.ToolCalls(weather, now))
+	ts := geminitest.NewServer(fake, nil)
+	defer ts.Close()
+
+	p := prompt.NewPrompt(prompt.UserMessage("weather?"))
+	p.ChatOption.Tools = []prompt.Tool{{Name: "weather"}, {Name: "now"}}
+	model := gemini.NewChatModel(ts.GeminiClient(), "gemini-test")
+
+	// gemini returns no ids, they are made up per candidate.
+	weather.ID, now.ID = "call_0_0", "call_0_1"
+
+	resp, err := model.Call(context.Background(), p)
+	if err != nil || !reflect.DeepEqual(resp.ToolCalls(), []chat.ToolCall{weather, now}) || resp.FinishReason() != chat.FinishReasonToolCalls {
+		t.Errorf("Call() got = %+v, error = %v", resp, err)
+	}
+
+	streamed, _, err := goaitest.Stream(context.Background(), model, p)
+	if err != nil || !reflect.DeepEqual(streamed.ToolCalls(), []chat.ToolCall{weather, now}) || streamed.FinishReason() != chat.FinishReasonToolCalls {
+		t.Errorf("Stream() got = %+v, error = %v", streamed, err)
+	}
+}
+
+func TestChatModel_StreamToolCalls(t *testing.T) {
+	// the calls of a candidate are streamed in separate chunks.
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `data: {"candidates":[{"index":0,"content":{"role":"model","parts":[{"functionCall":{"name":"weather","args":{"city":"Paris"}}}]}}]}`+"\n\n"+
+			`data: {"candidates":[{"index":0,"content":{"role":"model","parts":[{"functionCall":{"name":"now","args":{}}}]}}]}`+"\n\n"+
+			`data: {"candidates":[{"index":0,"content":{"role":"model","parts":[]},"finishReason":"STOP"}]}`+"\n\n")
+	}))
+	defer ts.Close()
+
+	p := prompt.NewPrompt(prompt.UserMessage("weather?"))
+	p.ChatOption.Tools = []prompt.Tool{{Name: "weather"}, {Name: "now"}}
+	model := gemini.NewChatModel(gemini.NewClient("test-key", gemini.WithBaseURL(ts.URL)), "gemini-test")
+
+	streamed, _, err := goaitest.Stream(context.Background(), model, p)
+	want := []chat.ToolCall{{ID: "call_0_0", Name: "weather", Arguments: `{"city":"Paris"}`}, {ID: "call_0_1", Name: "now", Arguments: `{}`}}
+	if err != nil || !reflect.DeepEqual(streamed.ToolCalls(), want) || streamed.FinishReason() != chat.FinishReasonToolCalls {
+		t.Errorf("Stream() got = %+v, error = %v", streamed, err)
+	}
+}
+
+func TestChatModel_Safety(t *testing.T) {
+	fake := goaitest.NewChatModel(goaitest.Text("partial").WithFinishReason(chat.FinishReasonContentFilter))
+	ts := geminitest.NewServer(fake, nil)
+	defer ts.Close()
+
+	model := gemini.NewChatModel(ts.GeminiClient(), "gemini-test")
+	resp, err := model.Call(context.Background(), prompt.NewPrompt(prompt.UserMessage("hi")))
+	if err != nil || resp.Text() != "partial" || resp.FinishReason() != chat.FinishReasonContentFilter {
+		t.Errorf("Call() got = %+v, error = %v", resp, err)
+	}
+
+	ts.BlockReason = "SAFETY"
+	_, err = model.Call(context.Background(), prompt.NewPrompt(prompt.UserMessage("hi")))
+	var blockedErr *gemini.BlockedError
+	if !errors.As(err, &blockedErr) || blockedErr.Reason != "SAFETY" || !strings.Contains(err.Error(), "HARM_CATEGORY_DANGEROUS_CONTENT") {
+		t.Errorf("Call() error = %v", err)
+	}
+
+	err = model.Stream(context.Background(), prompt.NewPrompt(prompt.UserMessage("hi")), func(*chat.Response) error { return nil })
+	if !errors.As(err, &blockedErr) {
+		t.Errorf("Stream() error = %v", err)
+	}
+}
+
+func TestChatModel_Options(t *testing.T) {
+	thinking := goaitest.Reply{Chunks: []*chat.Response{
+		{Generations: []chat.Generation{{Thinking: "hmm", Content: "42", LogProbs: []chat.LogProb{
+			{Token: "42", LogProb: -0.1, TopLogProbs: []chat.LogProb{{Token: "42", LogProb: -0.1}, {Token: "41", LogProb: -2}}},
+		}}}},
+	}}
+	fake := goaitest.NewChatModel(thinking, goaitest.Text("b"))
+	ts := geminitest.NewServer(fake, nil)
+	defer ts.Close()
+
+	budget := 512
+	p := prompt.NewPrompt(prompt.UserMessage("answer?"))
+	p.ChatOption.Candidates = 2
+	p.ChatOption.TopLogProbs = 2
+	p.ChatOption.Extensions = []prompt.Extension{&gemini.Extension{ThinkingBudget: &budget, IncludeThoughts: true}}
+
+	resp, err := gemini.NewChatModel(ts.GeminiClient(), "gemini-test").Call(context.Background(), p)
+	if err != nil || len(resp.Generations) != 2 || resp.Text() != "42" || resp.Generations[0].Thinking != "hmm" || resp.Generations[1].Content != "b" {
+		t.Fatalf("Call() got = %+v, error = %v", resp, err)
+	}
+	if logProbs := resp.Generations[0].LogProbs; len(logProbs) != 1 || len(logProbs[0].TopLogProbs) != 2 || logProbs[0].TopLogProbs[1].Token != "41" {
+		t.Errorf("Call() logprobs = %+v", logProbs)
+	}
+
+	var req gemini.GenerateContentRequest
+	if err = json.Unmarshal(ts.Requests()[0].Body, &req); err != nil {
+		t.Fatal(err)
+	}
+	config := req.GenerationConfig
+	if config.CandidateCount != 2 || !config.ResponseLogprobs || config.Logprobs != 2 ||
+		config.ThinkingConfig == nil || *config.ThinkingConfig.ThinkingBudget != 512 || !config.ThinkingConfig.IncludeThoughts {
+		t.Errorf("Call() config = %+v", config)
+	}
+}
+
+func TestChatModel_StreamUsage(t *testing.T) {
+	fake := goaitest.NewChatModel(goaitest.Chunks("a", "b", "c").WithUsage(chat.Usage{PromptTokens: 2, CompletionTokens: 3, TotalTokens: 5}))
+	ts := geminitest.NewServer(fake, nil)
+	defer ts.Close()
+
+	// the API reports the usage so far with every chunk, it is recorded once.
+	tracker := usage.NewTracker(nil, nil)
+	model := goai.WrapChatModel(gemini.NewChatModel(ts.GeminiClient(), "gemini-test"), usage.Track(tracker))
+	p := prompt.NewPrompt(prompt.UserMessage("hi"))
+	p.ChatOption.Model = "gemini-test"
+	if err := model.Stream(context.Background(), p, func(*chat.Response) error { return nil }); err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+
+	if total := tracker.Total("gemini-test"); total.Calls != 1 || total.TotalTokens != 5 {
+		t.Errorf("Stream() recorded = %+v", total)
+	}
+}
+
+func TestChatModel_StreamUsageUnfinished(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `data: {"candidates":[{"index":0,"content":{"role":"model","parts":[{"text":"a"}]}}],"usageMetadata":{"promptTokenCount":2,"candidatesTokenCount":1,"totalTokenCount":3}}`+"\n\n"+
+			`data: {"candidates":[{"index":0,"content":{"role":"model","parts":[{"text":"b"}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":2,"candidatesTokenCount":2,"totalTokenCount":4}}`+"\n\n")
+	}))
+	defer ts.Close()
+
+	// two candidates are requested but only one finishes, the usage of the
+	// last chunk is still recorded.
+	tracker := usage.NewTracker(nil, nil)
+	model := goai.WrapChatModel(gemini.NewChatModel(gemini.NewClient("test-key", gemini.WithBaseURL(ts.URL)), "gemini-test"), usage.Track(tracker))
+	p := prompt.NewPrompt(prompt.UserMessage("hi"))
+	p.ChatOption.Candidates = 2
+
+	streamed, _, err := goaitest.Stream(context.Background(), model, p)
+	if err != nil || streamed.Text() != "ab" || streamed.Usage.TotalTokens != 4 {
+		t.Errorf("Stream() got = %+v, error = %v", streamed, err)
+	}
+	if total := tracker.Total(""); total.Calls != 1 || total.TotalTokens != 4 {
+		t.Errorf("Stream() recorded = %+v", total)
+	}
+}
@@ -0,0 +1,139 @@
+// Package gemini implements goai models with the Google Gemini API.
+package gemini
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/tech1024/goai/internal/wire"
+)
+
+// NewClient returns a Client of the Gemini API, an empty apiKey is read from
+// the GEMINI_API_KEY or the GOOGLE_API_KEY environment variable.
+func NewClient(apiKey string, options ...ClientOption) *Client {
+	if apiKey == "" {
+		apiKey = os.Getenv("GEMINI_API_KEY")
+	}
+	if apiKey == "" {
+		apiKey = os.Getenv("GOOGLE_API_KEY")
+	}
+
+	client := Client{
+		baseURL:    DefaultBaseURL,
+		httpClient: http.DefaultClient,
+		header:     make(http.Header),
+	}
+	client.header.Set("x-goog-api-key", apiKey)
+
+	for _, option := range options {
+		option(&client)
+	}
+
+	return &client
+}
+
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	header     http.Header // header The default headers of every request.
+}
+
+// GenerateContent generates a response of the model.
+func (c *Client) GenerateContent(ctx context.Context, model string, req *GenerateContentRequest) (*GenerateContentResponse, error) {
+	var resp GenerateContentResponse
+	if err := c.post(ctx, model, "generateContent", req, &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// StreamGenerateContent generates a response of the model streaming it, fn
+// receives every chunk.
+func (c *Client) StreamGenerateContent(ctx context.Context, model string, req *GenerateContentRequest, fn func(*GenerateContentResponse) error) error {
+	httpResp, err := c.do(ctx, model, "streamGenerateContent", url.Values{"alt": {"sse"}}, req)
+	if err != nil {
+		return err
+	}
+	defer httpResp.Body.Close()
+
+	if httpResp.StatusCode >= http.StatusBadRequest {
+		return newStatusError(httpResp)
+	}
+
+	return wire.ReadEvents(httpResp.Body, func(data []byte) error {
+		var resp GenerateContentResponse
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return fmt.Errorf("unmarshal: %w", err)
+		}
+
+		return fn(&resp)
+	})
+}
+
+// BatchEmbedContents embeds the contents of the requests with the model.
+func (c *Client) BatchEmbedContents(ctx context.Context, model string, req *BatchEmbedContentsRequest) (*BatchEmbedContentsResponse, error) {
+	var resp BatchEmbedContentsResponse
+	if err := c.post(ctx, model, "batchEmbedContents", req, &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+func (c *Client) post(ctx context.Context, model, method string, req, resp any) error {
+	httpResp, err := c.do(ctx, model, method, nil, req)
+	if err != nil {
+		return err
+	}
+	defer httpResp.Body.Close()
+
+	if httpResp.StatusCode >= http.StatusBadRequest {
+		return newStatusError(httpResp)
+	}
+
+	if err = json.NewDecoder(httpResp.Body).Decode(resp); err != nil {
+		return fmt.Errorf("unmarshal: %w", err)
+	}
+
+	return nil
+}
+
+func (c *Client) do(ctx context.Context, model, method string, query url.Values, req any) (*http.Response, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := fmt.Sprintf("%s/%s:%s", c.baseURL, modelName(model), method)
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	for key, values := range c.header {
+		request.Header[key] = values
+	}
+
+	return c.httpClient.Do(request)
+}
+
+// modelName returns the resource name of the model, e.g. "models/gemini-2.0-flash".
+func modelName(model string) string {
+	if strings.Contains(model, "/") {
+		return model
+	}
+
+	return "models/" + model
+}
@@ -0,0 +1,88 @@
+package gemini
+
+import (
+	"errors"
+
+	"github.com/tech1024/goai"
+	"github.com/tech1024/goai/embedding"
+	"github.com/tech1024/goai/prompt"
+)
+
+// the gemini provider serves DSNs like "gemini:gemini-2.5-flash?thinking_budget=0"
+// and "gemini:gemini-embedding-001?task_type=RETRIEVAL_QUERY".
+func init() {
+	goai.RegisterProvider(ProviderName, goai.Provider{
+		ChatModel: func(dsn *goai.DSN) (goai.ChatModel, error) {
+			client, err := clientOf(dsn)
+			if err != nil {
+				return nil, err
+			}
+
+			extension := Extension{
+				StopSequences:    dsn.Values("stop"),
+				ResponseMIMEType: dsn.Param("response_mime_type"),
+			}
+			if extension.Temperature, err = dsn.Float("temperature"); err != nil {
+				return nil, err
+			}
+			if extension.TopP, err = dsn.Float("top_p"); err != nil {
+				return nil, err
+			}
+			if extension.TopK, err = dsn.Int("top_k"); err != nil {
+				return nil, err
+			}
+			if extension.ThinkingBudget, err = dsn.Int("thinking_budget"); err != nil {
+				return nil, err
+			}
+			maxTokens, err := dsn.Int("max_tokens")
+			if err != nil {
+				return nil, err
+			}
+			if maxTokens != nil {
+				extension.MaxOutputTokens = *maxTokens
+			}
+			includeThoughts, err := dsn.Bool("include_thoughts")
+			if err != nil {
+				return nil, err
+			}
+			extension.IncludeThoughts = includeThoughts != nil && *includeThoughts
+
+			return goai.DefaultChatOption(prompt.Option{
+				Extensions: []prompt.Extension{&extension},
+			})(NewChatModel(client, dsn.Model)), nil
+		},
+		EmbeddingModel: func(dsn *goai.DSN) (goai.EmbeddingModel, error) {
+			client, err := clientOf(dsn)
+			if err != nil {
+				return nil, err
+			}
+
+			return goai.DefaultEmbeddingOption(embedding.Option{
+				Extensions: []prompt.Extension{&Extension{
+					TaskType: dsn.Param("task_type"),
+					Title:    dsn.Param("title"),
+				}},
+			})(NewEmbeddingModel(client, dsn.Model)), nil
+		},
+	})
+}
+
+// clientOf returns the client of the DSN, whose base url is https://host/v1beta
+// unless set by base_url.
+func clientOf(dsn *goai.DSN) (*Client, error) {
+	if dsn.Model == "" {
+		return nil, errors.New("gemini dsn: missing model")
+	}
+
+	apiKey, err := dsn.APIKey()
+	if err != nil {
+		return nil, err
+	}
+
+	var options []ClientOption
+	if baseURL := dsn.BaseURL("https", "/v1beta"); baseURL != "" {
+		options = append(options, WithBaseURL(baseURL))
+	}
+
+	return NewClient(apiKey, options...), nil
+}
@@ -0,0 +1,52 @@
+package gemini_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/tech1024/goai"
+	"github.com/tech1024/goai/embedding"
+	"github.com/tech1024/goai/goaitest"
+	"github.com/tech1024/goai/prompt"
+	"github.com/tech1024/goai/provider/gemini"
+	"github.com/tech1024/goai/provider/gemini/geminitest"
+)
+
+func TestOpenChatModel(t *testing.T) {
+	fake := goaitest.NewChatModel(goaitest.Text("hello"))
+	ts := geminitest.NewServer(fake, goaitest.NewEmbeddingModel(8))
+	defer ts.Close()
+
+	chatModel, err := goai.OpenChatModel("gemini:gemini-test?thinking_budget=0&max_tokens=128&base_url=" + ts.URL + "/v1beta")
+	if err != nil {
+		t.Fatalf("OpenChatModel() error = %v", err)
+	}
+
+	resp, err := chatModel.Call(context.Background(), prompt.NewPrompt(prompt.UserMessage("hi")))
+	if err != nil || resp.Text() != "hello" {
+		t.Fatalf("Call() got = %+v, error = %v", resp, err)
+	}
+
+	var req gemini.GenerateContentRequest
+	_ = json.Unmarshal(ts.Requests()[0].Body, &req)
+	if req.GenerationConfig == nil || req.GenerationConfig.MaxOutputTokens != 128 || req.GenerationConfig.ThinkingConfig == nil ||
+		*req.GenerationConfig.ThinkingConfig.ThinkingBudget != 0 {
+		t.Errorf("Call() request = %s", ts.Requests()[0].Body)
+	}
+
+	embeddingModel, err := goai.OpenEmbeddingModel("gemini:embedding-test?dimensions=4&task_type=RETRIEVAL_QUERY&base_url=" + ts.URL + "/v1beta")
+	if err != nil {
+		t.Fatalf("OpenEmbeddingModel() error = %v", err)
+	}
+	embeddings, err := embeddingModel.Call(context.Background(), embedding.NewRequest([]string{"a"}, embedding.Option{}))
+	if err != nil || len(embeddings.Embeddings[0].Embedding) != 4 {
+		t.Errorf("Call() got = %+v, error = %v", embeddings, err)
+	}
+
+	var embedReq gemini.BatchEmbedContentsRequest
+	_ = json.Unmarshal(ts.Requests()[1].Body, &embedReq)
+	if embedReq.Requests[0].TaskType != "RETRIEVAL_QUERY" || embedReq.Requests[0].OutputDimensionality != 4 {
+		t.Errorf("Call() request = %s", ts.Requests()[1].Body)
+	}
+}
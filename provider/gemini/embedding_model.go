@@ -0,0 +1,82 @@
+package gemini
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tech1024/goai/embedding"
+)
+
+// DefaultMaxInputs the maximum number of inputs of a single batchEmbedContents
+// request accepted by Gemini.
+const DefaultMaxInputs = 100
+
+func NewEmbeddingModel(client *Client, model string) *EmbeddingModel {
+	return &EmbeddingModel{
+		client: client,
+		model:  model,
+	}
+}
+
+// EmbeddingModel a goai.EmbeddingModel using the batchEmbedContents endpoint.
+type EmbeddingModel struct {
+	client *Client
+	model  string
+
+	// MaxInputs the maximum number of inputs of a request, larger requests are
+	// split. Zero uses DefaultMaxInputs.
+	MaxInputs int
+}
+
+func (embeddingModel *EmbeddingModel) Call(ctx context.Context, request embedding.Request) (embedding.Response, error) {
+	var embeddingResponse embedding.Response
+
+	extension, err := extensionOf(request.Option.Extensions)
+	if err != nil {
+		return embeddingResponse, err
+	}
+
+	model := embeddingModel.model
+	if request.Option.Model != "" {
+		model = request.Option.Model
+	}
+
+	maxInputs := embeddingModel.MaxInputs
+	if maxInputs <= 0 {
+		maxInputs = DefaultMaxInputs
+	}
+
+	embeddingResponse.Model = model
+	embeddingResponse.Embeddings = make([]embedding.Embedding, 0, len(request.Inputs))
+	for offset := 0; offset < len(request.Inputs); offset += maxInputs {
+		inputs := request.Inputs[offset:min(offset+maxInputs, len(request.Inputs))]
+
+		req := &BatchEmbedContentsRequest{Requests: make([]EmbedContentRequest, len(inputs))}
+		for i, input := range inputs {
+			req.Requests[i] = EmbedContentRequest{
+				Model:                modelName(model),
+				Content:              Content{Parts: []Part{{Text: input}}},
+				TaskType:             extension.TaskType,
+				Title:                extension.Title,
+				OutputDimensionality: request.Option.Dimensions,
+			}
+		}
+
+		resp, err := embeddingModel.client.BatchEmbedContents(ctx, model, req)
+		if err != nil {
+			return embeddingResponse, err
+		}
+		if len(resp.Embeddings) != len(inputs) {
+			return embeddingResponse, fmt.Errorf("gemini: %d embeddings of %d inputs", len(resp.Embeddings), len(inputs))
+		}
+
+		for i, e := range resp.Embeddings {
+			embeddingResponse.Embeddings = append(embeddingResponse.Embeddings, embedding.Embedding{
+				Embedding: e.Values,
+				Index:     offset + i,
+			})
+		}
+	}
+
+	return embeddingResponse, nil
+}
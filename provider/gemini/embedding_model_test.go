@@ -0,0 +1,48 @@
+package gemini_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/tech1024/goai/embedding"
+	"github.com/tech1024/goai/goaitest"
+	"github.com/tech1024/goai/prompt"
+	"github.com/tech1024/goai/provider/gemini"
+	"github.com/tech1024/goai/provider/gemini/geminitest"
+)
+
+func TestEmbeddingModel(t *testing.T) {
+	ts := geminitest.NewServer(nil, goaitest.NewEmbeddingModel(8))
+	defer ts.Close()
+
+	model := gemini.NewEmbeddingModel(ts.GeminiClient(), "text-embedding-test")
+	model.MaxInputs = 2
+
+	inputs := []string{"a", "b", "c"}
+	resp, err := model.Call(context.Background(), embedding.NewRequest(inputs, embedding.Option{
+		Dimensions: 4,
+		Extensions: []prompt.Extension{&gemini.Extension{TaskType: "RETRIEVAL_DOCUMENT", Title: "doc"}},
+	}))
+	if err != nil || len(resp.Embeddings) != 3 || resp.Model != "text-embedding-test" {
+		t.Fatalf("Call() got = %+v, error = %v", resp, err)
+	}
+	for i, e := range resp.Embeddings {
+		if e.Index != i || len(e.Embedding) != 4 {
+			t.Errorf("Call() embedding %d = %+v", i, e)
+		}
+	}
+
+	requests := ts.Requests()
+	if len(requests) != 2 || requests[0].Path != "/v1beta/models/text-embedding-test:batchEmbedContents" {
+		t.Fatalf("Call() requests = %+v", requests)
+	}
+	var req gemini.BatchEmbedContentsRequest
+	if err = json.Unmarshal(requests[1].Body, &req); err != nil {
+		t.Fatal(err)
+	}
+	if len(req.Requests) != 1 || req.Requests[0].Model != "models/text-embedding-test" || req.Requests[0].Content.Parts[0].Text != "c" ||
+		req.Requests[0].TaskType != "RETRIEVAL_DOCUMENT" || req.Requests[0].Title != "doc" || req.Requests[0].OutputDimensionality != 4 {
+		t.Errorf("Call() request = %+v", req)
+	}
+}
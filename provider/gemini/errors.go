@@ -0,0 +1,85 @@
+package gemini
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/tech1024/goai/internal/wire"
+)
+
+// StatusError is returned when the API responds with an error status.
+type StatusError struct {
+	// Code the http status code.
+	Code int
+
+	// Status the http status, e.g. "429 Too Many Requests".
+	Status string
+
+	// Reason and Message the error reported by the API, e.g. the reason
+	// "RESOURCE_EXHAUSTED", empty if the body is no API error.
+	Reason  string
+	Message string
+
+	// Body the beginning of the response body.
+	Body string
+}
+
+func (e *StatusError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("http status code: %s, %s: %s", e.Status, e.Reason, e.Message)
+	}
+	if e.Body != "" {
+		return fmt.Sprintf("http status code: %s, body: %s", e.Status, e.Body)
+	}
+
+	return fmt.Sprintf("http status code: %s", e.Status)
+}
+
+// StatusCode returns the http status code.
+func (e *StatusError) StatusCode() int {
+	return e.Code
+}
+
+func newStatusError(httpResp *http.Response) *StatusError {
+	bts, body := wire.ReadErrorBody(httpResp)
+
+	statusErr := StatusError{
+		Code:   httpResp.StatusCode,
+		Status: httpResp.Status,
+		Body:   body,
+	}
+
+	var e struct {
+		Error struct {
+			Message string `json:"message"`
+			Status  string `json:"status"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(bts, &e) == nil {
+		statusErr.Reason = e.Error.Status
+		statusErr.Message = e.Error.Message
+	}
+
+	return &statusErr
+}
+
+// BlockedError is returned when the prompt has been blocked, e.g. by the
+// safety settings.
+type BlockedError struct {
+	// Reason the block reason, e.g. "SAFETY".
+	Reason string
+
+	// SafetyRatings the ratings of the prompt.
+	SafetyRatings []SafetyRating
+}
+
+func (e *BlockedError) Error() string {
+	for _, rating := range e.SafetyRatings {
+		if rating.Blocked {
+			return fmt.Sprintf("gemini: prompt blocked: %s: %s", e.Reason, rating.Category)
+		}
+	}
+
+	return fmt.Sprintf("gemini: prompt blocked: %s", e.Reason)
+}
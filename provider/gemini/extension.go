@@ -0,0 +1,69 @@
+package gemini
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/tech1024/goai/prompt"
+)
+
+// ProviderName the name of the gemini provider.
+const ProviderName = "gemini"
+
+// Extension the gemini specific options of a prompt, attach it to
+// prompt.Option.Extensions or embedding.Option.Extensions.
+type Extension struct {
+	// MaxOutputTokens the maximum number of tokens generated.
+	MaxOutputTokens int
+
+	// Temperature, TopP and TopK the sampling of the generation.
+	Temperature *float64
+	TopP        *float64
+	TopK        *int
+
+	// StopSequences the sequences ending the generation.
+	StopSequences []string
+
+	// ResponseMIMEType the media type of the response, e.g. "application/json",
+	// and ResponseSchema the schema it must conform to.
+	ResponseMIMEType string
+	ResponseSchema   json.RawMessage
+
+	// SafetySettings the blocking thresholds replacing the default ones.
+	SafetySettings []SafetySetting
+
+	// ThinkingBudget the number of tokens used for thinking, zero disables the
+	// thinking and -1 lets the model decide.
+	ThinkingBudget *int
+
+	// IncludeThoughts returns the thinking of the model.
+	IncludeThoughts bool
+
+	// TaskType the task the embeddings are used for, e.g. "RETRIEVAL_QUERY",
+	// and Title the title of the embedded documents.
+	TaskType string
+	Title    string
+}
+
+func (e *Extension) Provider() string {
+	return ProviderName
+}
+
+// extensionOf returns the gemini extension of the options, an empty one if
+// there is none.
+func extensionOf(extensions []prompt.Extension) (*Extension, error) {
+	for _, extension := range extensions {
+		if extension.Provider() != ProviderName {
+			continue
+		}
+
+		e, ok := extension.(*Extension)
+		if !ok || e == nil {
+			return nil, fmt.Errorf("gemini: unsupported extension %T", extension)
+		}
+
+		return e, nil
+	}
+
+	return &Extension{}, nil
+}
@@ -0,0 +1,358 @@
+// Package geminitest provides an in-process fake Gemini server, whose
+// generateContent, streamGenerateContent and batchEmbedContents endpoints are
+// answered by goai models, e.g. the scripted fakes of the goaitest package.
+package geminitest
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/tech1024/goai"
+	"github.com/tech1024/goai/chat"
+	"github.com/tech1024/goai/embedding"
+	"github.com/tech1024/goai/goaitest"
+	"github.com/tech1024/goai/prompt"
+	"github.com/tech1024/goai/provider/gemini"
+)
+
+// Server a fake Gemini server.
+type Server struct {
+	*goaitest.Server
+
+	// ChatModel answers generateContent and streamGenerateContent.
+	ChatModel goai.ChatModel
+
+	// EmbeddingModel answers batchEmbedContents.
+	EmbeddingModel goai.EmbeddingModel
+
+	// BlockReason blocks every prompt with the reason, e.g. "SAFETY".
+	BlockReason string
+}
+
+// NewServer starts a fake Gemini server answering with the models, either may be nil.
+func NewServer(chatModel goai.ChatModel, embeddingModel goai.EmbeddingModel) *Server {
+	s := &Server{
+		Server:         goaitest.NewServer(),
+		ChatModel:      chatModel,
+		EmbeddingModel: embeddingModel,
+	}
+	s.HandleFunc("POST /v1beta/models/{method}", s.handleModels)
+
+	return s
+}
+
+// GeminiClient returns a client talking to the server.
+func (s *Server) GeminiClient(options ...gemini.ClientOption) *gemini.Client {
+	options = append([]gemini.ClientOption{
+		gemini.WithBaseURL(s.URL + "/v1beta"),
+		gemini.WithHTTPClient(s.Client()),
+	}, options...)
+
+	return gemini.NewClient("test-key", options...)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	code := goaitest.HTTPStatus(err)
+	goaitest.WriteJSON(w, code, map[string]any{"error": map[string]any{
+		"code":    code,
+		"message": err.Error(),
+		"status":  errorStatus(code),
+	}})
+}
+
+// errorStatus returns the status of the API error of a status code.
+func errorStatus(code int) string {
+	switch code {
+	case http.StatusBadRequest:
+		return "INVALID_ARGUMENT"
+	case http.StatusForbidden:
+		return "PERMISSION_DENIED"
+	case http.StatusNotFound:
+		return "NOT_FOUND"
+	case http.StatusTooManyRequests:
+		return "RESOURCE_EXHAUSTED"
+	case http.StatusServiceUnavailable:
+		return "UNAVAILABLE"
+	default:
+		return "INTERNAL"
+	}
+}
+
+func (s *Server) handleModels(w http.ResponseWriter, r *http.Request) {
+	model, method, _ := strings.Cut(r.PathValue("method"), ":")
+
+	switch method {
+	case "generateContent":
+		s.handleGenerateContent(w, r, model, false)
+	case "streamGenerateContent":
+		s.handleGenerateContent(w, r, model, true)
+	case "batchEmbedContents":
+		s.handleBatchEmbedContents(w, r, model)
+	default:
+		writeError(w, &goaitest.StatusError{Code: http.StatusNotFound, Message: "unknown method " + method})
+	}
+}
+
+func (s *Server) handleGenerateContent(w http.ResponseWriter, r *http.Request, model string, stream bool) {
+	var req gemini.GenerateContentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, &goaitest.StatusError{Code: http.StatusBadRequest, Message: err.Error()})
+		return
+	}
+	if s.ChatModel == nil {
+		writeError(w, &goaitest.StatusError{Code: http.StatusNotFound, Message: "models/" + model + " is not found"})
+		return
+	}
+	if s.BlockReason != "" {
+		resp := gemini.GenerateContentResponse{PromptFeedback: &gemini.PromptFeedback{
+			BlockReason:   s.BlockReason,
+			SafetyRatings: []gemini.SafetyRating{{Category: "HARM_CATEGORY_DANGEROUS_CONTENT", Probability: "HIGH", Blocked: true}},
+		}}
+		if !stream {
+			goaitest.WriteJSON(w, http.StatusOK, resp)
+			return
+		}
+		_ = newEventStream(w).Send("", resp)
+		return
+	}
+
+	p := toPrompt(model, req)
+	if stream {
+		s.streamGenerateContent(r.Context(), w, model, p)
+		return
+	}
+
+	resp := gemini.GenerateContentResponse{ModelVersion: model, ResponseID: "goaitest"}
+
+	// each of the candidates is a call of the chat model.
+	var usage chat.Usage
+	candidates := 1
+	if req.GenerationConfig != nil {
+		candidates = max(req.GenerationConfig.CandidateCount, 1)
+	}
+	for range candidates {
+		chatResp, err := s.ChatModel.Call(r.Context(), p)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		usage = usage.Add(chatResp.Usage)
+		for _, generation := range chatResp.Generations {
+			candidate := toCandidate(generation, len(resp.Candidates))
+			candidate.FinishReason = finishReason(generation)
+			if req.GenerationConfig != nil && req.GenerationConfig.ResponseLogprobs {
+				candidate.LogprobsResult = toLogprobsResult(generation.LogProbs)
+			}
+			resp.Candidates = append(resp.Candidates, candidate)
+		}
+	}
+	resp.UsageMetadata = toUsageMetadata(usage)
+
+	goaitest.WriteJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) streamGenerateContent(ctx context.Context, w http.ResponseWriter, model string, p prompt.Prompt) {
+	events := newEventStream(w)
+	streamed := &chat.Response{}
+
+	// like the API every chunk reports the usage so far, the fake estimates
+	// it until the model reports the final usage.
+	var promptTokens, completionTokens int
+	for _, message := range p.Messages {
+		promptTokens += prompt.EstimateTokens(message.Text())
+	}
+
+	send := func(resp gemini.GenerateContentResponse) error {
+		resp.ModelVersion = model
+		return events.Send("", resp)
+	}
+
+	err := s.ChatModel.Stream(ctx, p, func(resp *chat.Response) error {
+		streamed.Append(resp)
+		if len(resp.Generations) == 0 {
+			return nil
+		}
+		generation := resp.Generations[0]
+
+		candidate := toCandidate(generation, 0)
+		if len(candidate.Content.Parts) == 0 {
+			return nil
+		}
+
+		completionTokens += prompt.EstimateTokens(generation.Content + generation.Thinking)
+		return send(gemini.GenerateContentResponse{
+			Candidates: []gemini.Candidate{candidate},
+			UsageMetadata: toUsageMetadata(chat.Usage{
+				PromptTokens:     promptTokens,
+				CompletionTokens: completionTokens,
+				TotalTokens:      promptTokens + completionTokens,
+			}),
+		})
+	})
+
+	if err != nil {
+		// the API reports errors of a started stream by ending it.
+		if !events.Started() {
+			writeError(w, err)
+		}
+		return
+	}
+
+	_ = send(gemini.GenerateContentResponse{
+		Candidates:    []gemini.Candidate{{Content: gemini.Content{Role: gemini.RoleModel, Parts: []gemini.Part{{Text: ""}}}, FinishReason: finishReason(chat.Generation{FinishReason: streamed.FinishReason()})}},
+		UsageMetadata: toUsageMetadata(streamed.Usage),
+	})
+}
+
+func (s *Server) handleBatchEmbedContents(w http.ResponseWriter, r *http.Request, model string) {
+	var req gemini.BatchEmbedContentsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, &goaitest.StatusError{Code: http.StatusBadRequest, Message: err.Error()})
+		return
+	}
+	if s.EmbeddingModel == nil {
+		writeError(w, &goaitest.StatusError{Code: http.StatusNotFound, Message: "models/" + model + " is not found"})
+		return
+	}
+
+	var inputs []string
+	var dimensions int
+	for _, embedReq := range req.Requests {
+		if embedReq.Model != "models/"+model {
+			writeError(w, &goaitest.StatusError{Code: http.StatusBadRequest, Message: "model of the request must be models/" + model})
+			return
+		}
+		var text string
+		for _, part := range embedReq.Content.Parts {
+			text += part.Text
+		}
+		inputs = append(inputs, text)
+		dimensions = embedReq.OutputDimensionality
+	}
+
+	resp, err := s.EmbeddingModel.Call(r.Context(), embedding.NewRequest(inputs, embedding.Option{Model: model, Dimensions: dimensions}))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	embeddings := make([]gemini.ContentEmbedding, len(resp.Embeddings))
+	for i, e := range resp.Embeddings {
+		embeddings[i] = gemini.ContentEmbedding{Values: e.Embedding}
+	}
+
+	goaitest.WriteJSON(w, http.StatusOK, gemini.BatchEmbedContentsResponse{Embeddings: embeddings})
+}
+
+func toPrompt(model string, req gemini.GenerateContentRequest) prompt.Prompt {
+	p := prompt.Prompt{ChatOption: prompt.Option{Model: model}}
+	for _, tool := range req.Tools {
+		for _, declaration := range tool.FunctionDeclarations {
+			p.ChatOption.Tools = append(p.ChatOption.Tools, prompt.Tool{
+				Name:        declaration.Name,
+				Description: declaration.Description,
+				Parameters:  declaration.Parameters,
+			})
+		}
+	}
+
+	if req.SystemInstruction != nil {
+		for _, part := range req.SystemInstruction.Parts {
+			p.Messages = append(p.Messages, prompt.SystemMessage(part.Text))
+		}
+	}
+
+	for _, content := range req.Contents {
+		var text string
+		var images []prompt.Image
+		var toolCalls []chat.ToolCall
+		for _, part := range content.Parts {
+			switch {
+			case part.InlineData != nil:
+				data, _ := base64.StdEncoding.DecodeString(part.InlineData.Data)
+				images = append(images, prompt.Image{MimeType: part.InlineData.MIMEType, Data: data})
+			case part.FileData != nil:
+				images = append(images, prompt.Image{MimeType: part.FileData.MIMEType, URL: part.FileData.FileURI})
+			case part.FunctionCall != nil:
+				toolCalls = append(toolCalls, chat.ToolCall{Name: part.FunctionCall.Name, Arguments: string(part.FunctionCall.Args)})
+			case part.FunctionResponse != nil:
+				p.Messages = append(p.Messages, prompt.ToolResultMessage(chat.ToolCall{Name: part.FunctionResponse.Name}, string(part.FunctionResponse.Response)))
+			default:
+				text += part.Text
+			}
+		}
+
+		switch {
+		case len(toolCalls) > 0:
+			p.Messages = append(p.Messages, prompt.AssistantToolCallMessage(text, toolCalls...))
+		case len(images) > 0:
+			p.Messages = append(p.Messages, prompt.UserImageMessage(text, images...))
+		case text != "" && content.Role == gemini.RoleModel:
+			p.Messages = append(p.Messages, prompt.AssistantMessage(text))
+		case text != "":
+			p.Messages = append(p.Messages, prompt.UserMessage(text))
+		}
+	}
+
+	return p
+}
+
+func toCandidate(generation chat.Generation, index int) gemini.Candidate {
+	candidate := gemini.Candidate{Index: index, Content: gemini.Content{Role: gemini.RoleModel, Parts: []gemini.Part{}}}
+	if generation.Thinking != "" {
+		candidate.Content.Parts = append(candidate.Content.Parts, gemini.Part{Text: generation.Thinking, Thought: true})
+	}
+	if generation.Content != "" {
+		candidate.Content.Parts = append(candidate.Content.Parts, gemini.Part{Text: generation.Content})
+	}
+	for _, tc := range generation.ToolCalls {
+		candidate.Content.Parts = append(candidate.Content.Parts, gemini.Part{FunctionCall: &gemini.FunctionCall{Name: tc.Name, Args: json.RawMessage(tc.Arguments)}})
+	}
+
+	return candidate
+}
+
+func toLogprobsResult(logProbs []chat.LogProb) *gemini.LogprobsResult {
+	result := &gemini.LogprobsResult{}
+	for _, logProb := range logProbs {
+		result.ChosenCandidates = append(result.ChosenCandidates, gemini.LogprobsCandidate{Token: logProb.Token, LogProbability: logProb.LogProb})
+		top := gemini.TopCandidates{}
+		for _, t := range logProb.TopLogProbs {
+			top.Candidates = append(top.Candidates, gemini.LogprobsCandidate{Token: t.Token, LogProbability: t.LogProb})
+		}
+		result.TopCandidates = append(result.TopCandidates, top)
+	}
+
+	return result
+}
+
+func toUsageMetadata(usage chat.Usage) *gemini.UsageMetadata {
+	return &gemini.UsageMetadata{
+		PromptTokenCount:     usage.PromptTokens,
+		CandidatesTokenCount: usage.CompletionTokens,
+		TotalTokenCount:      usage.TotalTokens,
+	}
+}
+
+func finishReason(generation chat.Generation) string {
+	switch generation.FinishReason {
+	case chat.FinishReasonLength:
+		return gemini.FinishMaxTokens
+	case chat.FinishReasonContentFilter:
+		return gemini.FinishSafety
+	}
+
+	return gemini.FinishStop
+}
+
+// newEventStream returns an EventStream ending the lines with CRLF like the API.
+func newEventStream(w http.ResponseWriter) *goaitest.EventStream {
+	events := goaitest.NewEventStream(w)
+	events.LineBreak = "\r\n"
+
+	return events
+}
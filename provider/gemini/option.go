@@ -0,0 +1,33 @@
+package gemini
+
+import (
+	"net/http"
+	"strings"
+)
+
+// DefaultBaseURL the address of the Gemini API, including its version.
+const DefaultBaseURL = "https://generativelanguage.googleapis.com/v1beta"
+
+// ClientOption configures a Client.
+type ClientOption func(*Client)
+
+// WithBaseURL sets the base url of the API including its version, e.g. of a proxy.
+func WithBaseURL(baseURL string) ClientOption {
+	return func(c *Client) {
+		c.baseURL = strings.TrimSuffix(baseURL, "/")
+	}
+}
+
+// WithHTTPClient sets the http client sending the requests, defaults to http.DefaultClient.
+func WithHTTPClient(httpClient *http.Client) ClientOption {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithHeader adds a header sent with every request.
+func WithHeader(key, value string) ClientOption {
+	return func(c *Client) {
+		c.header.Add(key, value)
+	}
+}
@@ -0,0 +1,212 @@
+package gemini
+
+import "encoding/json"
+
+// Roles of the contents.
+const (
+	RoleUser  = "user"
+	RoleModel = "model"
+)
+
+// Finish reasons of a candidate.
+const (
+	FinishStop              = "STOP"
+	FinishMaxTokens         = "MAX_TOKENS"
+	FinishSafety            = "SAFETY"
+	FinishRecitation        = "RECITATION"
+	FinishBlocklist         = "BLOCKLIST"
+	FinishProhibitedContent = "PROHIBITED_CONTENT"
+	FinishSPII              = "SPII"
+	FinishMalformedCall     = "MALFORMED_FUNCTION_CALL"
+	FinishOther             = "OTHER"
+)
+
+// Blob is inline data of a part, e.g. an image.
+type Blob struct {
+	MIMEType string `json:"mimeType"`
+
+	// Data the base64 encoded data.
+	Data string `json:"data"`
+}
+
+// FileData is a file referenced by a part.
+type FileData struct {
+	MIMEType string `json:"mimeType,omitempty"`
+	FileURI  string `json:"fileUri"`
+}
+
+// FunctionCall is a call of a function declared in the tools.
+type FunctionCall struct {
+	ID   string          `json:"id,omitempty"`
+	Name string          `json:"name"`
+	Args json.RawMessage `json:"args,omitempty"`
+}
+
+// FunctionResponse is the result of a function call.
+type FunctionResponse struct {
+	ID       string          `json:"id,omitempty"`
+	Name     string          `json:"name"`
+	Response json.RawMessage `json:"response"`
+}
+
+// Part is a part of a content, one of its fields is set.
+type Part struct {
+	Text             string            `json:"text,omitempty"`
+	InlineData       *Blob             `json:"inlineData,omitempty"`
+	FileData         *FileData         `json:"fileData,omitempty"`
+	FunctionCall     *FunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *FunctionResponse `json:"functionResponse,omitempty"`
+
+	// Thought marks a text part as thinking of the model.
+	Thought          bool   `json:"thought,omitempty"`
+	ThoughtSignature string `json:"thoughtSignature,omitempty"`
+}
+
+// Content is a message of the conversation.
+type Content struct {
+	Role  string `json:"role,omitempty"`
+	Parts []Part `json:"parts"`
+}
+
+// FunctionDeclaration is a function the model may call.
+type FunctionDeclaration struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"`
+}
+
+// Tool is a set of functions the model may call.
+type Tool struct {
+	FunctionDeclarations []FunctionDeclaration `json:"functionDeclarations,omitempty"`
+}
+
+// SafetySetting is the blocking threshold of a harm category, e.g. the
+// category "HARM_CATEGORY_HARASSMENT" with the threshold "BLOCK_ONLY_HIGH".
+type SafetySetting struct {
+	Category  string `json:"category"`
+	Threshold string `json:"threshold"`
+}
+
+// SafetyRating is the probability of a harm category of the content.
+type SafetyRating struct {
+	Category    string `json:"category"`
+	Probability string `json:"probability"`
+	Blocked     bool   `json:"blocked,omitempty"`
+}
+
+// ThinkingConfig configures the thinking of thinking models.
+type ThinkingConfig struct {
+	// ThinkingBudget the number of tokens used for thinking, zero disables
+	// the thinking and -1 lets the model decide.
+	ThinkingBudget *int `json:"thinkingBudget,omitempty"`
+
+	// IncludeThoughts returns the thoughts as parts marked as Thought.
+	IncludeThoughts bool `json:"includeThoughts,omitempty"`
+}
+
+// GenerationConfig configures the generation.
+type GenerationConfig struct {
+	CandidateCount   int             `json:"candidateCount,omitempty"`
+	MaxOutputTokens  int             `json:"maxOutputTokens,omitempty"`
+	StopSequences    []string        `json:"stopSequences,omitempty"`
+	Temperature      *float64        `json:"temperature,omitempty"`
+	TopP             *float64        `json:"topP,omitempty"`
+	TopK             *int            `json:"topK,omitempty"`
+	ResponseMIMEType string          `json:"responseMimeType,omitempty"`
+	ResponseSchema   json.RawMessage `json:"responseSchema,omitempty"`
+	ResponseLogprobs bool            `json:"responseLogprobs,omitempty"`
+	Logprobs         int             `json:"logprobs,omitempty"`
+	ThinkingConfig   *ThinkingConfig `json:"thinkingConfig,omitempty"`
+}
+
+// GenerateContentRequest is the request passed to [Client.GenerateContent].
+type GenerateContentRequest struct {
+	Contents []Content `json:"contents"`
+
+	// SystemInstruction is the system prompt, kept out of Contents by the API.
+	SystemInstruction *Content `json:"systemInstruction,omitempty"`
+
+	Tools            []Tool            `json:"tools,omitempty"`
+	SafetySettings   []SafetySetting   `json:"safetySettings,omitempty"`
+	GenerationConfig *GenerationConfig `json:"generationConfig,omitempty"`
+}
+
+// LogprobsCandidate is a token and its log probability.
+type LogprobsCandidate struct {
+	Token          string  `json:"token"`
+	LogProbability float64 `json:"logProbability"`
+}
+
+// TopCandidates is the most likely tokens of a position.
+type TopCandidates struct {
+	Candidates []LogprobsCandidate `json:"candidates"`
+}
+
+// LogprobsResult is the log probabilities of the generated tokens.
+type LogprobsResult struct {
+	ChosenCandidates []LogprobsCandidate `json:"chosenCandidates,omitempty"`
+	TopCandidates    []TopCandidates     `json:"topCandidates,omitempty"`
+}
+
+// Candidate is a generated response.
+type Candidate struct {
+	Content        Content         `json:"content"`
+	FinishReason   string          `json:"finishReason,omitempty"`
+	Index          int             `json:"index"`
+	SafetyRatings  []SafetyRating  `json:"safetyRatings,omitempty"`
+	LogprobsResult *LogprobsResult `json:"logprobsResult,omitempty"`
+}
+
+// PromptFeedback reports whether the prompt has been blocked.
+type PromptFeedback struct {
+	// BlockReason is e.g. "SAFETY", "BLOCKLIST" or "PROHIBITED_CONTENT", empty
+	// if the prompt has not been blocked.
+	BlockReason   string         `json:"blockReason,omitempty"`
+	SafetyRatings []SafetyRating `json:"safetyRatings,omitempty"`
+}
+
+// UsageMetadata is the tokens used by a request.
+type UsageMetadata struct {
+	PromptTokenCount        int `json:"promptTokenCount"`
+	CandidatesTokenCount    int `json:"candidatesTokenCount"`
+	ThoughtsTokenCount      int `json:"thoughtsTokenCount,omitempty"`
+	CachedContentTokenCount int `json:"cachedContentTokenCount,omitempty"`
+	TotalTokenCount         int `json:"totalTokenCount"`
+}
+
+// GenerateContentResponse is the response from [Client.GenerateContent], or
+// a chunk of [Client.StreamGenerateContent].
+type GenerateContentResponse struct {
+	Candidates     []Candidate     `json:"candidates,omitempty"`
+	PromptFeedback *PromptFeedback `json:"promptFeedback,omitempty"`
+	UsageMetadata  *UsageMetadata  `json:"usageMetadata,omitempty"`
+	ModelVersion   string          `json:"modelVersion,omitempty"`
+	ResponseID     string          `json:"responseId,omitempty"`
+}
+
+// EmbedContentRequest is a request of a [BatchEmbedContentsRequest].
+type EmbedContentRequest struct {
+	// Model is the model of the request, e.g. "models/text-embedding-004".
+	Model   string  `json:"model"`
+	Content Content `json:"content"`
+
+	// TaskType is e.g. "RETRIEVAL_QUERY" or "RETRIEVAL_DOCUMENT".
+	TaskType             string `json:"taskType,omitempty"`
+	Title                string `json:"title,omitempty"`
+	OutputDimensionality int    `json:"outputDimensionality,omitempty"`
+}
+
+// BatchEmbedContentsRequest is the request passed to [Client.BatchEmbedContents].
+type BatchEmbedContentsRequest struct {
+	Requests []EmbedContentRequest `json:"requests"`
+}
+
+// ContentEmbedding is the embedding of a content.
+type ContentEmbedding struct {
+	Values []float32 `json:"values"`
+}
+
+// BatchEmbedContentsResponse is the response from [Client.BatchEmbedContents].
+type BatchEmbedContentsResponse struct {
+	Embeddings []ContentEmbedding `json:"embeddings"`
+}
@@ -0,0 +1,258 @@
+package llamacpp
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/tech1024/goai/chat"
+	"github.com/tech1024/goai/internal/wire"
+	"github.com/tech1024/goai/prompt"
+)
+
+func NewChatModel(client *Client, model string) *ChatModel {
+	return &ChatModel{
+		client: client,
+		model:  model,
+	}
+}
+
+// ChatModel a goai.ChatModel using the /v1/chat/completions endpoint of
+// llama-server, which applies the chat template of the loaded model.
+type ChatModel struct {
+	client *Client
+	model  string
+}
+
+func (chatModel *ChatModel) Call(ctx context.Context, prompt prompt.Prompt) (*chat.Response, error) {
+	req, err := chatModel.buildChatRequest(prompt)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkContext(ctx, chatModel.client, prompt.Messages...); err != nil {
+		return nil, err
+	}
+
+	// llama-server generates a single choice, the candidates are sampled one by one.
+	response := &chat.Response{}
+	for range candidates(prompt) {
+		resp, err := chatModel.client.ChatCompletion(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+
+		response.ID = resp.ID
+		response.Model = wire.ModelOf(resp.Model, req.Model)
+		for _, choice := range resp.Choices {
+			response.Generations = append(response.Generations, chat.Generation{
+				Content:      choice.Message.Content,
+				Thinking:     choice.Message.ReasoningContent,
+				ToolCalls:    toToolCalls(choice.Message.ToolCalls),
+				FinishReason: chat.FinishReason(choice.FinishReason),
+				LogProbs:     toChatLogProbs(choice.Logprobs),
+			})
+		}
+		response.Usage = response.Usage.Add(toUsage(resp.Usage))
+		timing := resp.Timings.Timing()
+		response.Timing.PromptDuration += timing.PromptDuration
+		response.Timing.CompletionDuration += timing.CompletionDuration
+	}
+
+	return response, nil
+}
+
+func (chatModel *ChatModel) Stream(ctx context.Context, prompt prompt.Prompt, fn func(*chat.Response) error) error {
+	req, err := chatModel.buildChatRequest(prompt)
+	if err != nil {
+		return err
+	}
+	if err = checkContext(ctx, chatModel.client, prompt.Messages...); err != nil {
+		return err
+	}
+
+	// the candidates are streamed one after another, the chunks of a candidate
+	// carry its generation at its index, the usage of all candidates is only
+	// reported by the last chunk of the last one.
+	var usage chat.Usage
+	n := candidates(prompt)
+	for i := range n {
+		// tool calls are streamed as fragments, they are emitted once the
+		// choice finished.
+		assembler := &wire.ToolCallAssembler{Err: ErrInvalidToolCall}
+
+		err = chatModel.client.ChatCompletionStream(ctx, req, func(resp *ChatResponse) error {
+			chunk := &chat.Response{
+				ID:     resp.ID,
+				Model:  wire.ModelOf(resp.Model, req.Model),
+				Timing: resp.Timings.Timing(),
+			}
+			for _, choice := range resp.Choices {
+				generation := chat.Generation{
+					Content:      choice.Delta.Content,
+					Thinking:     choice.Delta.ReasoningContent,
+					FinishReason: chat.FinishReason(choice.FinishReason),
+					LogProbs:     toChatLogProbs(choice.Logprobs),
+				}
+				for _, toolCall := range choice.Delta.ToolCalls {
+					generation.ToolCallDeltas = append(generation.ToolCallDeltas, assembler.Add(toolCall.Index, toolCall.ID, toolCall.Function.Name, toolCall.Function.Arguments))
+				}
+				if choice.FinishReason != "" {
+					toolCalls, err := assembler.Complete()
+					if err != nil {
+						return err
+					}
+					generation.ToolCalls = toolCalls
+				}
+				chunk.Generations = append(make([]chat.Generation, i), generation)
+			}
+			if resp.Usage != nil {
+				usage = usage.Add(toUsage(resp.Usage))
+				if i == n-1 {
+					chunk.Usage = usage
+				}
+			}
+
+			return fn(chunk)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// candidates returns the number of generations requested by the prompt.
+func candidates(p prompt.Prompt) int {
+	return max(p.ChatOption.Candidates, 1)
+}
+
+func (chatModel *ChatModel) buildChatRequest(p prompt.Prompt) (*ChatRequest, error) {
+	extension, err := extensionOf(p.ChatOption.Extensions)
+	if err != nil {
+		return nil, err
+	}
+
+	request := ChatRequest{
+		Model:       chatModel.model,
+		Messages:    make([]ChatMessage, len(p.Messages)),
+		Logprobs:    p.ChatOption.LogProbs || p.ChatOption.TopLogProbs > 0,
+		TopLogprobs: p.ChatOption.TopLogProbs,
+		Sampling:    extension.sampling(),
+	}
+	if p.ChatOption.Model != "" {
+		request.Model = p.ChatOption.Model
+	}
+
+	for i, message := range p.Messages {
+		request.Messages[i] = ChatMessage{
+			Role:    message.Type().String(),
+			Content: message.Text(),
+		}
+
+		// images are sent as data urls to servers started with a multimodal projector.
+		if images := prompt.Images(message); len(images) > 0 {
+			var parts []ChatContentPart
+			for _, image := range images {
+				url := image.URL
+				if len(image.Data) > 0 {
+					url = "data:" + image.MimeType + ";base64," + base64.StdEncoding.EncodeToString(image.Data)
+				}
+				parts = append(parts, ChatContentPart{Type: "image_url", ImageURL: &ChatImageURL{URL: url}})
+			}
+			if message.Text() != "" {
+				parts = append(parts, ChatContentPart{Type: "text", Text: message.Text()})
+			}
+			request.Messages[i].Content = parts
+		}
+
+		switch message.Type() {
+		case prompt.MessageTypeAssistant:
+			for _, toolCall := range prompt.ToolCalls(message) {
+				if toolCall.Arguments != "" && !json.Valid([]byte(toolCall.Arguments)) {
+					return nil, fmt.Errorf("%w: %s: %s", ErrInvalidToolCall, toolCall.Name, toolCall.Arguments)
+				}
+				request.Messages[i].ToolCalls = append(request.Messages[i].ToolCalls, ChatToolCall{
+					ID:       toolCall.ID,
+					Type:     "function",
+					Function: ChatFunctionCall{Name: toolCall.Name, Arguments: toolCall.Arguments},
+				})
+			}
+		case prompt.MessageTypeTool:
+			request.Messages[i].ToolCallID = prompt.ToolCallID(message)
+		}
+	}
+
+	for _, tool := range p.ChatOption.Tools {
+		parameters := tool.Parameters
+		if len(parameters) == 0 {
+			parameters = json.RawMessage(`{"type":"object","properties":{}}`)
+		}
+		request.Tools = append(request.Tools, ChatTool{
+			Type: "function",
+			Function: ChatFunction{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  parameters,
+			},
+		})
+	}
+
+	return &request, nil
+}
+
+// checkContext fails early if the messages exceed the context size of a slot,
+// it is a no-op unless the client was created WithContextCheck.
+func checkContext(ctx context.Context, client *Client, messages ...prompt.Message) error {
+	if !client.checkContext {
+		return nil
+	}
+
+	contextSize, err := client.ContextSize(ctx)
+	if err != nil || contextSize == 0 {
+		return err
+	}
+
+	if tokens := prompt.NewFitter(contextSize, 0).Count(messages); tokens > contextSize {
+		return fmt.Errorf("%w: about %d tokens, the slots have %d", prompt.ErrContextExceeded, tokens, contextSize)
+	}
+
+	return nil
+}
+
+func toUsage(usage *ChatUsage) chat.Usage {
+	if usage == nil {
+		return chat.Usage{}
+	}
+
+	return chat.Usage{
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		TotalTokens:      usage.TotalTokens,
+	}
+}
+
+func toChatLogProbs(logprobs *ChatLogprobs) []chat.LogProb {
+	if logprobs == nil {
+		return nil
+	}
+
+	return toLogProbs(logprobs.Content)
+}
+
+func toLogProbs(probabilities []TokenProbability) []chat.LogProb {
+	if len(probabilities) == 0 {
+		return nil
+	}
+
+	result := make([]chat.LogProb, len(probabilities))
+	for i, probability := range probabilities {
+		result[i] = chat.LogProb{Token: probability.Token, LogProb: probability.Logprob}
+		for _, top := range probability.TopLogprobs {
+			result[i].TopLogProbs = append(result[i].TopLogProbs, chat.LogProb{Token: top.Token, LogProb: top.Logprob})
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,250 @@
+package llamacpp_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tech1024/goai"
+	"github.com/tech1024/goai/chat"
+	"github.com/tech1024/goai/goaitest"
+	"github.com/tech1024/goai/prompt"
+	"github.com/tech1024/goai/provider/llamacpp"
+	"github.com/tech1024/goai/provider/llamacpp/llamacpptest"
+	"github.com/tech1024/goai/usage"
+)
+
+func TestChatModel(t *testing.T) {
+	fake := goaitest.NewChatModel(
+		goaitest.Text("hello").WithUsage(chat.Usage{PromptTokens: 2, CompletionTokens: 1, TotalTokens: 3}),
+		goaitest.Chunks("a", "b", "c").WithUsage(chat.Usage{PromptTokens: 2, CompletionTokens: 3, TotalTokens: 5}),
+		goaitest.Error(&goaitest.StatusError{Code: http.StatusBadRequest, Message: "the request exceeds the available context size"}),
+	)
+	ts := llamacpptest.NewServer(fake, nil)
+	defer ts.Close()
+
+	model := llamacpp.NewChatModel(ts.LlamaCppClient(), "")
+	c := goai.NewChat(model)
+
+	resp, err := c.Call(context.Background(), prompt.NewPrompt(prompt.UserMessage("request 1")))
+	if err != nil || resp.Text() != "hello" || resp.Model != "goaitest.gguf" || resp.Usage.TotalTokens != 3 ||
+		resp.FinishReason() != chat.FinishReasonStop || resp.Timing.CompletionDuration == 0 {
+		t.Errorf("Call() got = %+v, error = %v", resp, err)
+	}
+
+	streamed := &chat.Response{}
+	var chunks []string
+	err = model.Stream(context.Background(), prompt.NewPrompt(prompt.UserMessage("request 2")), func(chunk *chat.Response) error {
+		if text := chunk.Text(); text != "" {
+			chunks = append(chunks, text)
+		}
+		streamed.Append(chunk)
+		return nil
+	})
+	if err != nil || strings.Join(chunks, "|") != "a|b|c" || streamed.Usage.TotalTokens != 5 || streamed.FinishReason() != chat.FinishReasonStop {
+		t.Errorf("Stream() got = %v, %+v, error = %v", chunks, streamed, err)
+	}
+
+	_, err = c.Chat(context.Background(), "request 3")
+	var statusErr *llamacpp.StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode() != http.StatusBadRequest || statusErr.Type != "invalid_request_error" {
+		t.Errorf("Chat() error = %v", err)
+	}
+
+	if requests := ts.Requests(); requests[0].Path != "/v1/chat/completions" {
+		t.Errorf("Call() path = %s", requests[0].Path)
+	}
+}
+
+func TestChatModel_Sampling(t *testing.T) {
+	fake := goaitest.NewChatModel(goaitest.Text(`{"answer":42}`))
+	ts := llamacpptest.NewServer(fake, nil)
+	defer ts.Close()
+
+	slot, cache := 1, false
+	p := prompt.NewPrompt(prompt.UserMessage("answer?"))
+	p.ChatOption.Extensions = []prompt.Extension{&llamacpp.Extension{
+		JSONSchema:  json.RawMessage(`{"type":"object"}`),
+		Slot:        &slot,
+		CachePrompt: &cache,
+		Options:     map[string]any{"seed": 7, "json_schema": "ignored"},
+	}}
+
+	if _, err := llamacpp.NewChatModel(ts.LlamaCppClient(), "").Call(context.Background(), p); err != nil {
+		t.Fatalf("Call() error = %v", err)
+	}
+
+	var req map[string]any
+	if err := json.Unmarshal(ts.Requests()[0].Body, &req); err != nil {
+		t.Fatal(err)
+	}
+	// the raw options do not override the typed ones.
+	if !reflect.DeepEqual(req["json_schema"], map[string]any{"type": "object"}) || req["id_slot"] != 1.0 || req["cache_prompt"] != false || req["seed"] != 7.0 {
+		t.Errorf("Call() request = %v", req)
+	}
+
+	p.ChatOption.Extensions = []prompt.Extension{&llamacpp.Extension{Grammar: `root ::= "yes"`, JSONSchema: json.RawMessage(`{}`)}}
+	if _, err := llamacpp.NewChatModel(ts.LlamaCppClient(), "").Call(context.Background(), p); err == nil {
+		t.Errorf("Call() with grammar and json schema error = nil")
+	}
+	if len(ts.Requests()) != 1 {
+		t.Errorf("Call() sent an invalid request")
+	}
+}
+
+func TestChatModel_Messages(t *testing.T) {
+	fake := goaitest.NewChatModel(goaitest.Text("ok"))
+	ts := llamacpptest.NewServer(fake, nil)
+	defer ts.Close()
+
+	weather := chat.ToolCall{ID: "call_1", Name: "weather", Arguments: `{"city":"Paris"}`}
+	p := prompt.NewPrompt(
+		prompt.SystemMessage("be brief"),
+		prompt.UserImageMessage("what is it?", prompt.Image{MimeType: "image/png", Data: []byte("png")}),
+		prompt.AssistantToolCallMessage("", weather),
+		prompt.ToolResultMessage(weather, "sunny"),
+	)
+	p.ChatOption.Tools = []prompt.Tool{{Name: "weather"}}
+
+	if _, err := llamacpp.NewChatModel(ts.LlamaCppClient(), "").Call(context.Background(), p); err != nil {
+		t.Fatalf("Call() error = %v", err)
+	}
+
+	messages := fake.LastPrompt().Messages
+	if len(messages) != 4 || messages[0].Text() != "be brief" {
+		t.Fatalf("Call() messages = %+v", messages)
+	}
+	if images := prompt.Images(messages[1]); len(images) != 1 || string(images[0].Data) != "png" || images[0].MimeType != "image/png" {
+		t.Errorf("Call() images = %+v", images)
+	}
+	if toolCalls := prompt.ToolCalls(messages[2]); !reflect.DeepEqual(toolCalls, []chat.ToolCall{weather}) {
+		t.Errorf("Call() tool calls = %+v", toolCalls)
+	}
+	if prompt.ToolCallID(messages[3]) != weather.ID || messages[3].Text() != "sunny" {
+		t.Errorf("Call() tool result = %+v", messages[3])
+	}
+
+	var req llamacpp.ChatRequest
+	if err := json.Unmarshal(ts.Requests()[0].Body, &req); err != nil {
+		t.Fatal(err)
+	}
+	if len(req.Tools) != 1 || string(req.Tools[0].Function.Parameters) != `{"type":"object","properties":{}}` {
+		t.Errorf("Call() tools = %+v", req.Tools)
+	}
+
+	p = prompt.NewPrompt(prompt.AssistantToolCallMessage("", chat.ToolCall{Name: "weather", Arguments: "{"}))
+	if _, err := llamacpp.NewChatModel(ts.LlamaCppClient(), "").Call(context.Background(), p); !errors.Is(err, llamacpp.ErrInvalidToolCall) {
+		t.Errorf("Call() error = %v", err)
+	}
+}
+
+func TestChatModel_Tools(t *testing.T) {
+	weather := chat.ToolCall{ID: "call_1", Name: "weather", Arguments: `{"city":"Paris"}`}
+	now := chat.ToolCall{ID: "call_2", Name: "now", Arguments: `{}`}
+	fake := goaitest.NewChatModel(goaitest.ToolCalls(weather, now), goaitest.ToolCalls(weather, now))
+	ts := llamacpptest.NewServer(fake, nil)
+	defer ts.Close()
+
+	p := prompt.NewPrompt(prompt.UserMessage("weather?"))
+	p.ChatOption.Tools = []prompt.Tool{{Name: "weather"}, {Name: "now"}}
+	model := llamacpp.NewChatModel(ts.LlamaCppClient(), "")
+
+	resp, err := model.Call(context.Background(), p)
+	if err != nil || !reflect.DeepEqual(resp.ToolCalls(), []chat.ToolCall{weather, now}) || resp.FinishReason() != chat.FinishReasonToolCalls {
+		t.Errorf("Call() got = %+v, error = %v", resp, err)
+	}
+
+	streamed := &chat.Response{}
+	var deltas int
+	err = model.Stream(context.Background(), p, func(chunk *chat.Response) error {
+		for _, generation := range chunk.Generations {
+			deltas += len(generation.ToolCallDeltas)
+		}
+		streamed.Append(chunk)
+		return nil
+	})
+	if err != nil || !reflect.DeepEqual(streamed.ToolCalls(), []chat.ToolCall{weather, now}) || streamed.FinishReason() != chat.FinishReasonToolCalls || deltas != 6 {
+		t.Errorf("Stream() got = %+v, %d deltas, error = %v", streamed, deltas, err)
+	}
+}
+
+func TestChatModel_Candidates(t *testing.T) {
+	logProbs := goaitest.Reply{Chunks: []*chat.Response{
+		{Generations: []chat.Generation{{Content: "42", LogProbs: []chat.LogProb{
+			{Token: "42", LogProb: -0.1, TopLogProbs: []chat.LogProb{{Token: "42", LogProb: -0.1}, {Token: "41", LogProb: -2}}},
+		}}}},
+	}}
+	usage := chat.Usage{PromptTokens: 2, CompletionTokens: 1, TotalTokens: 3}
+	fake := goaitest.NewChatModel(logProbs.WithUsage(usage), goaitest.Text("b").WithUsage(usage), goaitest.Text("a").WithUsage(usage), goaitest.Text("b").WithUsage(usage))
+	ts := llamacpptest.NewServer(fake, nil)
+	defer ts.Close()
+
+	p := prompt.NewPrompt(prompt.UserMessage("answer?"))
+	p.ChatOption.Candidates = 2
+	p.ChatOption.TopLogProbs = 2
+	model := llamacpp.NewChatModel(ts.LlamaCppClient(), "")
+
+	resp, err := model.Call(context.Background(), p)
+	if err != nil || len(resp.Generations) != 2 || resp.Text() != "42" || resp.Generations[1].Content != "b" || resp.Usage.TotalTokens != 6 {
+		t.Fatalf("Call() got = %+v, error = %v", resp, err)
+	}
+	want := []chat.LogProb{{Token: "42", LogProb: -0.1, TopLogProbs: []chat.LogProb{{Token: "42", LogProb: -0.1}, {Token: "41", LogProb: -2}}}}
+	if !reflect.DeepEqual(resp.Generations[0].LogProbs, want) {
+		t.Errorf("Call() log probs = %+v", resp.Generations[0].LogProbs)
+	}
+
+	var req llamacpp.ChatRequest
+	if err = json.Unmarshal(ts.Requests()[0].Body, &req); err != nil || !req.Logprobs || req.TopLogprobs != 2 {
+		t.Errorf("Call() request = %+v, error = %v", req, err)
+	}
+
+	streamed, _, err := goaitest.Stream(context.Background(), model, p)
+	if err != nil || len(streamed.Generations) != 2 || streamed.Generations[0].Content != "a" || streamed.Generations[1].Content != "b" || streamed.Usage.TotalTokens != 6 {
+		t.Errorf("Stream() got = %+v, error = %v", streamed, err)
+	}
+}
+
+func TestChatModel_ContextCheck(t *testing.T) {
+	fake := goaitest.NewChatModel(goaitest.Text("ok"))
+	ts := llamacpptest.NewServer(fake, nil)
+	defer ts.Close()
+	ts.ContextSize = 16
+
+	model := llamacpp.NewChatModel(ts.LlamaCppClient(llamacpp.WithContextCheck()), "")
+	_, err := model.Call(context.Background(), prompt.NewPrompt(prompt.UserMessage(strings.Repeat("word ", 100))))
+	if !errors.Is(err, prompt.ErrContextExceeded) {
+		t.Errorf("Call() error = %v", err)
+	}
+
+	resp, err := model.Call(context.Background(), prompt.NewPrompt(prompt.UserMessage("hi")))
+	if err != nil || resp.Text() != "ok" {
+		t.Errorf("Call() got = %+v, error = %v", resp, err)
+	}
+}
+
+func TestChatModel_CandidatesStreamUsage(t *testing.T) {
+	fake := goaitest.NewChatModel(
+		goaitest.Chunks("a", "b").WithUsage(chat.Usage{PromptTokens: 2, CompletionTokens: 2, TotalTokens: 4}),
+		goaitest.Chunks("c", "d").WithUsage(chat.Usage{PromptTokens: 2, CompletionTokens: 3, TotalTokens: 5}),
+	)
+	ts := llamacpptest.NewServer(fake, nil)
+	defer ts.Close()
+
+	// every candidate reports its own usage, the total is recorded once.
+	tracker := usage.NewTracker(nil, nil)
+	model := goai.WrapChatModel(llamacpp.NewChatModel(ts.LlamaCppClient(), "llama-test"), usage.Track(tracker))
+	p := prompt.NewPrompt(prompt.UserMessage("pick a letter"))
+	p.ChatOption.Model = "llama-test"
+	p.ChatOption.Candidates = 2
+	if err := model.Stream(context.Background(), p, func(*chat.Response) error { return nil }); err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+
+	if total := tracker.Total("llama-test"); total.Calls != 1 || total.TotalTokens != 9 {
+		t.Errorf("Stream() recorded = %+v", total)
+	}
+}
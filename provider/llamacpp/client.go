@@ -0,0 +1,323 @@
+// Package llamacpp implements goai models with the native endpoints of the
+// llama.cpp server, llama-server.
+package llamacpp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/tech1024/goai/internal/wire"
+)
+
+// NewClient returns a Client of the llama.cpp server at baseUrl, an empty
+// baseUrl is read from the LLAMACPP_HOST environment variable.
+func NewClient(baseUrl string, options ...ClientOption) *Client {
+	if baseUrl == "" {
+		baseUrl = os.Getenv("LLAMACPP_HOST")
+	}
+	if baseUrl == "" {
+		baseUrl = DefaultHost
+	}
+	if !strings.Contains(baseUrl, "://") {
+		baseUrl = "http://" + baseUrl
+	}
+
+	client := Client{
+		baseUrl:    strings.TrimSuffix(baseUrl, "/"),
+		httpClient: http.DefaultClient,
+		header:     make(http.Header),
+		props:      &propsCache{},
+	}
+
+	for _, option := range options {
+		option(&client)
+	}
+
+	return &client
+}
+
+type Client struct {
+	baseUrl      string
+	httpClient   *http.Client
+	header       http.Header // header The default headers of every request.
+	checkContext bool
+	props        *propsCache
+}
+
+// propsCache caches the props of the server, which only change when it restarts.
+type propsCache struct {
+	mu    sync.Mutex
+	props *Props
+}
+
+// Health reports whether the server is ready, it returns ErrLoading while the
+// model is loading.
+func (c *Client) Health(ctx context.Context) error {
+	httpResp, err := c.do(ctx, http.MethodGet, "/health", nil)
+	if err != nil {
+		return err
+	}
+	defer httpResp.Body.Close()
+
+	if httpResp.StatusCode == http.StatusServiceUnavailable {
+		return fmt.Errorf("%w: %w", ErrLoading, newStatusError(httpResp))
+	}
+	if httpResp.StatusCode >= http.StatusBadRequest {
+		return newStatusError(httpResp)
+	}
+
+	return nil
+}
+
+// Props returns the properties of the server.
+func (c *Client) Props(ctx context.Context) (*Props, error) {
+	var props Props
+	if err := c.call(ctx, http.MethodGet, "/props", nil, &props); err != nil {
+		return nil, err
+	}
+
+	c.props.mu.Lock()
+	c.props.props = &props
+	c.props.mu.Unlock()
+
+	return &props, nil
+}
+
+// ContextSize returns the context size of a slot, read from /props once.
+func (c *Client) ContextSize(ctx context.Context) (int, error) {
+	c.props.mu.Lock()
+	props := c.props.props
+	c.props.mu.Unlock()
+
+	if props == nil {
+		var err error
+		if props, err = c.Props(ctx); err != nil {
+			return 0, err
+		}
+	}
+
+	return props.DefaultGenerationSettings.NCtx, nil
+}
+
+// Completion completes the prompt.
+func (c *Client) Completion(ctx context.Context, req *CompletionRequest) (*CompletionResponse, error) {
+	req.Stream = false
+
+	var resp CompletionResponse
+	if err := c.call(ctx, http.MethodPost, "/completion", req, &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// CompletionStream completes the prompt streaming the response, fn receives every chunk.
+func (c *Client) CompletionStream(ctx context.Context, req *CompletionRequest, fn func(*CompletionResponse) error) error {
+	req.Stream = true
+
+	return c.stream(ctx, "/completion", req, func(data []byte) error {
+		var resp CompletionResponse
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return fmt.Errorf("unmarshal: %w", err)
+		}
+
+		return fn(&resp)
+	})
+}
+
+// Infill fills in the text between InputPrefix and InputSuffix.
+func (c *Client) Infill(ctx context.Context, req *CompletionRequest) (*CompletionResponse, error) {
+	req.Stream = false
+
+	var resp CompletionResponse
+	if err := c.call(ctx, http.MethodPost, "/infill", req, &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// InfillStream fills in the text streaming the response, fn receives every chunk.
+func (c *Client) InfillStream(ctx context.Context, req *CompletionRequest, fn func(*CompletionResponse) error) error {
+	req.Stream = true
+
+	return c.stream(ctx, "/infill", req, func(data []byte) error {
+		var resp CompletionResponse
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return fmt.Errorf("unmarshal: %w", err)
+		}
+
+		return fn(&resp)
+	})
+}
+
+// ChatCompletion sends the messages, the server applies the chat template of the model.
+func (c *Client) ChatCompletion(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	req.Stream = false
+
+	var resp ChatResponse
+	if err := c.call(ctx, http.MethodPost, "/v1/chat/completions", req, &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// ChatCompletionStream sends the messages streaming the response, fn receives every chunk.
+func (c *Client) ChatCompletionStream(ctx context.Context, req *ChatRequest, fn func(*ChatResponse) error) error {
+	req.Stream = true
+
+	return c.stream(ctx, "/v1/chat/completions", req, func(data []byte) error {
+		if string(data) == "[DONE]" {
+			return nil
+		}
+
+		var resp ChatResponse
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return fmt.Errorf("unmarshal: %w", err)
+		}
+
+		return fn(&resp)
+	})
+}
+
+// Embedding embeds the contents, the server must run with --embeddings.
+func (c *Client) Embedding(ctx context.Context, req *EmbeddingRequest) ([]EmbeddingResult, error) {
+	var results []EmbeddingResult
+	if err := c.call(ctx, http.MethodPost, "/embedding", req, &results); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// Rerank scores the relevance of the documents to the query, the server must
+// run a reranking model with --reranking.
+func (c *Client) Rerank(ctx context.Context, req *RerankRequest) (*RerankResponse, error) {
+	var resp RerankResponse
+	if err := c.call(ctx, http.MethodPost, "/reranking", req, &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// Slots returns the state of the slots, the server must run with --slots.
+func (c *Client) Slots(ctx context.Context) ([]Slot, error) {
+	var slots []Slot
+	if err := c.call(ctx, http.MethodGet, "/slots", nil, &slots); err != nil {
+		return nil, err
+	}
+
+	return slots, nil
+}
+
+// SaveSlot saves the prompt cache of the slot to the file, in the directory
+// set by --slot-save-path.
+func (c *Client) SaveSlot(ctx context.Context, id int, filename string) (*SlotResult, error) {
+	return c.slotAction(ctx, id, "save", map[string]string{"filename": filename})
+}
+
+// RestoreSlot restores the prompt cache of the slot from the file.
+func (c *Client) RestoreSlot(ctx context.Context, id int, filename string) (*SlotResult, error) {
+	return c.slotAction(ctx, id, "restore", map[string]string{"filename": filename})
+}
+
+// EraseSlot erases the prompt cache of the slot.
+func (c *Client) EraseSlot(ctx context.Context, id int) (*SlotResult, error) {
+	return c.slotAction(ctx, id, "erase", map[string]string{})
+}
+
+func (c *Client) slotAction(ctx context.Context, id int, action string, req any) (*SlotResult, error) {
+	var result SlotResult
+	if err := c.call(ctx, http.MethodPost, "/slots/"+strconv.Itoa(id)+"?action="+action, req, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (c *Client) call(ctx context.Context, method, path string, req, resp any) error {
+	httpResp, err := c.do(ctx, method, path, req)
+	if err != nil {
+		return err
+	}
+	defer httpResp.Body.Close()
+
+	if httpResp.StatusCode >= http.StatusBadRequest {
+		return newStatusError(httpResp)
+	}
+
+	if err = json.NewDecoder(httpResp.Body).Decode(resp); err != nil {
+		return fmt.Errorf("unmarshal: %w", err)
+	}
+
+	return nil
+}
+
+// stream sends the request, fn receives the data of each server-sent event.
+// An error event ends the stream with a *StatusError.
+func (c *Client) stream(ctx context.Context, path string, req any, fn func(data []byte) error) error {
+	httpResp, err := c.do(ctx, http.MethodPost, path, req)
+	if err != nil {
+		return err
+	}
+	defer httpResp.Body.Close()
+
+	if httpResp.StatusCode >= http.StatusBadRequest {
+		return newStatusError(httpResp)
+	}
+
+	return wire.ReadEvents(httpResp.Body, func(data []byte) error {
+		var e struct {
+			Error *struct {
+				Code    int    `json:"code"`
+				Type    string `json:"type"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(data, &e) == nil && e.Error != nil {
+			return &StatusError{
+				Code:    e.Error.Code,
+				Status:  strconv.Itoa(e.Error.Code) + " " + http.StatusText(e.Error.Code),
+				Type:    e.Error.Type,
+				Message: e.Error.Message,
+			}
+		}
+
+		return fn(data)
+	})
+}
+
+func (c *Client) do(ctx context.Context, method, path string, req any) (*http.Response, error) {
+	var body io.Reader
+	if req != nil {
+		bts, err := json.Marshal(req)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(bts)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, c.baseUrl+path, body)
+	if err != nil {
+		return nil, err
+	}
+
+	if req != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	for key, values := range c.header {
+		request.Header[key] = values
+	}
+
+	return c.httpClient.Do(request)
+}
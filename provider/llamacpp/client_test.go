@@ -0,0 +1,85 @@
+package llamacpp_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/tech1024/goai/provider/llamacpp"
+	"github.com/tech1024/goai/provider/llamacpp/llamacpptest"
+)
+
+func TestClient_Health(t *testing.T) {
+	ts := llamacpptest.NewServer(nil, nil)
+	defer ts.Close()
+
+	client := ts.LlamaCppClient(llamacpp.WithAPIKey("secret"))
+	if err := client.Health(context.Background()); err != nil {
+		t.Errorf("Health() error = %v", err)
+	}
+	if got := ts.Requests()[0].Header.Get("Authorization"); got != "Bearer secret" {
+		t.Errorf("Health() authorization = %q", got)
+	}
+
+	ts.Loading = true
+	err := client.Health(context.Background())
+	var statusErr *llamacpp.StatusError
+	if !errors.Is(err, llamacpp.ErrLoading) || !errors.As(err, &statusErr) || statusErr.StatusCode() != http.StatusServiceUnavailable || statusErr.Type != "unavailable_error" {
+		t.Errorf("Health() error = %v", err)
+	}
+}
+
+func TestClient_ContextSize(t *testing.T) {
+	ts := llamacpptest.NewServer(nil, nil)
+	defer ts.Close()
+	ts.ContextSize = 2048
+
+	client := ts.LlamaCppClient()
+	for range 2 {
+		size, err := client.ContextSize(context.Background())
+		if err != nil || size != 2048 {
+			t.Errorf("ContextSize() got = %d, error = %v", size, err)
+		}
+	}
+
+	// the props are fetched once.
+	if requests := ts.Requests(); len(requests) != 1 || requests[0].Path != "/props" {
+		t.Errorf("ContextSize() requests = %+v", requests)
+	}
+}
+
+func TestClient_Slots(t *testing.T) {
+	ts := llamacpptest.NewServer(nil, nil)
+	defer ts.Close()
+	ts.TotalSlots = 2
+
+	client := ts.LlamaCppClient()
+	slots, err := client.Slots(context.Background())
+	if err != nil || len(slots) != 2 || slots[1].ID != 1 || slots[1].NCtx != llamacpptest.DefaultContextSize {
+		t.Fatalf("Slots() got = %+v, error = %v", slots, err)
+	}
+
+	saved, err := client.SaveSlot(context.Background(), 1, "session.bin")
+	if err != nil || saved.IDSlot != 1 || saved.Filename != "session.bin" || saved.NSaved != 1 {
+		t.Errorf("SaveSlot() got = %+v, error = %v", saved, err)
+	}
+	restored, err := client.RestoreSlot(context.Background(), 0, "session.bin")
+	if err != nil || restored.NRestored != 1 {
+		t.Errorf("RestoreSlot() got = %+v, error = %v", restored, err)
+	}
+	erased, err := client.EraseSlot(context.Background(), 1)
+	if err != nil || erased.NErased != 1 {
+		t.Errorf("EraseSlot() got = %+v, error = %v", erased, err)
+	}
+
+	_, err = client.RestoreSlot(context.Background(), 0, "missing.bin")
+	var statusErr *llamacpp.StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode() != http.StatusBadRequest {
+		t.Errorf("RestoreSlot() error = %v", err)
+	}
+
+	if requests := ts.Requests(); requests[1].Method != http.MethodPost || requests[1].Path != "/slots/1" {
+		t.Errorf("SaveSlot() request = %s %s", requests[1].Method, requests[1].Path)
+	}
+}
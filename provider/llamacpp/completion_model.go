@@ -0,0 +1,113 @@
+package llamacpp
+
+import (
+	"context"
+
+	"github.com/tech1024/goai/chat"
+	"github.com/tech1024/goai/completion"
+	"github.com/tech1024/goai/internal/wire"
+	"github.com/tech1024/goai/prompt"
+)
+
+func NewCompletionModel(client *Client, model string) *CompletionModel {
+	return &CompletionModel{
+		client: client,
+		model:  model,
+	}
+}
+
+// CompletionModel a goai.CompletionModel using the /completion endpoint, or
+// the /infill endpoint for requests with a suffix.
+type CompletionModel struct {
+	client *Client
+
+	// model is reported by the responses, llama-server serves the model it
+	// has been started with.
+	model string
+}
+
+func (completionModel *CompletionModel) Call(ctx context.Context, request completion.Request) (*completion.Response, error) {
+	req, err := completionModel.buildCompletionRequest(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp *CompletionResponse
+	if request.Suffix != "" {
+		resp, err = completionModel.client.Infill(ctx, req)
+	} else {
+		resp, err = completionModel.client.Completion(ctx, req)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return completionModel.toCompletionResponse(resp), nil
+}
+
+func (completionModel *CompletionModel) Stream(ctx context.Context, request completion.Request, fn func(*completion.Response) error) error {
+	req, err := completionModel.buildCompletionRequest(ctx, request)
+	if err != nil {
+		return err
+	}
+
+	receive := func(resp *CompletionResponse) error {
+		return fn(completionModel.toCompletionResponse(resp))
+	}
+	if request.Suffix != "" {
+		return completionModel.client.InfillStream(ctx, req, receive)
+	}
+
+	return completionModel.client.CompletionStream(ctx, req, receive)
+}
+
+func (completionModel *CompletionModel) buildCompletionRequest(ctx context.Context, request completion.Request) (*CompletionRequest, error) {
+	extension, err := extensionOf(request.Option.Extensions)
+	if err != nil {
+		return nil, err
+	}
+
+	text := request.Prompt
+	if request.Option.System != "" {
+		text = request.Option.System + "\n\n" + text
+	}
+	if err = checkContext(ctx, completionModel.client, prompt.UserMessage(text+request.Suffix)); err != nil {
+		return nil, err
+	}
+
+	req := CompletionRequest{
+		Prompt:   text,
+		NPredict: request.Option.MaxTokens,
+		Stop:     request.Option.Stop,
+		Sampling: extension.sampling(),
+	}
+
+	// the infill prompt is the text after the fill-in-the-middle tokens,
+	// the code before the cursor is the prefix.
+	if request.Suffix != "" {
+		req.Prompt = ""
+		req.InputPrefix = text
+		req.InputSuffix = request.Suffix
+	}
+
+	return &req, nil
+}
+
+func (completionModel *CompletionModel) toCompletionResponse(resp *CompletionResponse) *completion.Response {
+	response := &completion.Response{
+		Model:    wire.ModelOf(resp.Model, completionModel.model),
+		Text:     resp.Content,
+		Timing:   resp.Timings.Timing(),
+		LogProbs: toLogProbs(resp.CompletionProbabilities),
+	}
+	// the usage is reported once stopped, by a whole response or the last chunk.
+	if resp.Stop {
+		response.Usage = chat.Usage{
+			PromptTokens:     resp.TokensEvaluated,
+			CompletionTokens: resp.TokensPredicted,
+			TotalTokens:      resp.TokensEvaluated + resp.TokensPredicted,
+		}
+	}
+
+	return response
+}
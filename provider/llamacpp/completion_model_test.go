@@ -0,0 +1,93 @@
+package llamacpp_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/tech1024/goai/chat"
+	"github.com/tech1024/goai/completion"
+	"github.com/tech1024/goai/goaitest"
+	"github.com/tech1024/goai/prompt"
+	"github.com/tech1024/goai/provider/llamacpp"
+	"github.com/tech1024/goai/provider/llamacpp/llamacpptest"
+)
+
+func TestCompletionModel(t *testing.T) {
+	logProbs := goaitest.Reply{Chunks: []*chat.Response{
+		{Generations: []chat.Generation{{Content: "yes", LogProbs: []chat.LogProb{
+			{Token: "yes", LogProb: -0.2, TopLogProbs: []chat.LogProb{{Token: "yes", LogProb: -0.2}, {Token: "no", LogProb: -1.7}}},
+		}}}},
+	}}
+	fake := goaitest.NewChatModel(logProbs, goaitest.Chunks("a", "b"))
+	ts := llamacpptest.NewServer(fake, nil)
+	defer ts.Close()
+
+	model := llamacpp.NewCompletionModel(ts.LlamaCppClient(), "")
+	resp, err := model.Call(context.Background(), completion.NewRequest("ok?", completion.Option{
+		System:     "answer yes or no",
+		MaxTokens:  1,
+		Extensions: []prompt.Extension{&llamacpp.Extension{Grammar: `root ::= "yes" | "no"`, NProbs: 2}},
+	}))
+	if err != nil || resp.Text != "yes" || resp.Model != "goaitest.gguf" || resp.Usage.CompletionTokens == 0 || resp.Usage.PromptTokens == 0 {
+		t.Fatalf("Call() got = %+v, error = %v", resp, err)
+	}
+	if len(resp.LogProbs) != 1 || len(resp.LogProbs[0].TopLogProbs) != 2 || resp.LogProbs[0].TopLogProbs[1].Token != "no" {
+		t.Errorf("Call() log probs = %+v", resp.LogProbs)
+	}
+
+	var req llamacpp.CompletionRequest
+	if err = json.Unmarshal(ts.Requests()[0].Body, &req); err != nil {
+		t.Fatal(err)
+	}
+	if req.Prompt != "answer yes or no\n\nok?" || req.NPredict != 1 || req.Grammar != `root ::= "yes" | "no"` || req.NProbs != 2 {
+		t.Errorf("Call() request = %+v", req)
+	}
+
+	var chunks []string
+	var usage chat.Usage
+	err = model.Stream(context.Background(), completion.NewRequest("go", completion.Option{}), func(chunk *completion.Response) error {
+		chunks = append(chunks, chunk.Text)
+		if !chunk.Usage.IsZero() {
+			usage = chunk.Usage
+		}
+		return nil
+	})
+	if err != nil || strings.Join(chunks, "") != "ab" || usage.TotalTokens == 0 {
+		t.Errorf("Stream() got = %v, %+v, error = %v", chunks, usage, err)
+	}
+}
+
+func TestCompletionModel_Infill(t *testing.T) {
+	fake := goaitest.NewChatModel(goaitest.Text("a + b"), goaitest.Chunks("a", " + b"))
+	ts := llamacpptest.NewServer(fake, nil)
+	defer ts.Close()
+
+	model := llamacpp.NewCompletionModel(ts.LlamaCppClient(), "")
+	request := completion.NewRequest("func add(a, b int) int {\n\treturn ", completion.Option{})
+	request.Suffix = "\n}"
+
+	resp, err := model.Call(context.Background(), request)
+	if err != nil || resp.Text != "a + b" {
+		t.Fatalf("Call() got = %+v, error = %v", resp, err)
+	}
+
+	var text string
+	err = model.Stream(context.Background(), request, func(chunk *completion.Response) error {
+		text += chunk.Text
+		return nil
+	})
+	if err != nil || text != "a + b" {
+		t.Errorf("Stream() got = %q, error = %v", text, err)
+	}
+
+	requests := ts.Requests()
+	var req llamacpp.CompletionRequest
+	if err = json.Unmarshal(requests[0].Body, &req); err != nil {
+		t.Fatal(err)
+	}
+	if requests[0].Path != "/infill" || requests[1].Path != "/infill" || req.Prompt != "" || req.InputPrefix != request.Prompt || req.InputSuffix != "\n}" {
+		t.Errorf("Call() request = %s %+v", requests[0].Path, req)
+	}
+}
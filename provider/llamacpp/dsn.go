@@ -0,0 +1,74 @@
+package llamacpp
+
+import (
+	"github.com/tech1024/goai"
+	"github.com/tech1024/goai/prompt"
+)
+
+// the llamacpp provider serves DSNs like "llamacpp://gpu1:8080?temperature=0.2",
+// "llamacpp:" uses LLAMACPP_HOST and the model is optional as the server
+// serves the model it has been started with. The chat parameters other than
+// context_check, slot and cache_prompt are passed as raw request parameters.
+func init() {
+	goai.RegisterProvider(ProviderName, goai.Provider{
+		ChatModel: func(dsn *goai.DSN) (goai.ChatModel, error) {
+			client, err := clientOf(dsn)
+			if err != nil {
+				return nil, err
+			}
+			extension, err := extensionOfDSN(dsn)
+			if err != nil {
+				return nil, err
+			}
+
+			return goai.DefaultChatOption(prompt.Option{
+				Extensions: []prompt.Extension{extension},
+			})(NewChatModel(client, dsn.Model)), nil
+		},
+		EmbeddingModel: func(dsn *goai.DSN) (goai.EmbeddingModel, error) {
+			client, err := clientOf(dsn)
+			if err != nil {
+				return nil, err
+			}
+
+			return NewEmbeddingModel(client, dsn.Model), nil
+		},
+	})
+}
+
+// clientOf returns the client of the DSN.
+func clientOf(dsn *goai.DSN) (*Client, error) {
+	apiKey, err := dsn.APIKey()
+	if err != nil {
+		return nil, err
+	}
+
+	var options []ClientOption
+	if apiKey != "" {
+		options = append(options, WithAPIKey(apiKey))
+	}
+	contextCheck, err := dsn.Bool("context_check")
+	if err != nil {
+		return nil, err
+	}
+	if contextCheck != nil && *contextCheck {
+		options = append(options, WithContextCheck())
+	}
+
+	return NewClient(dsn.BaseURL("http", ""), options...), nil
+}
+
+// extensionOfDSN returns the extension of the parameters of the DSN.
+func extensionOfDSN(dsn *goai.DSN) (*Extension, error) {
+	var extension Extension
+	var err error
+	if extension.Slot, err = dsn.Int("slot"); err != nil {
+		return nil, err
+	}
+	if extension.CachePrompt, err = dsn.Bool("cache_prompt"); err != nil {
+		return nil, err
+	}
+	extension.Options = dsn.Rest()
+
+	return &extension, extension.Validate()
+}
@@ -0,0 +1,58 @@
+package llamacpp_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/tech1024/goai"
+	"github.com/tech1024/goai/embedding"
+	"github.com/tech1024/goai/goaitest"
+	"github.com/tech1024/goai/prompt"
+	"github.com/tech1024/goai/provider/llamacpp/llamacpptest"
+)
+
+func TestOpenChatModel(t *testing.T) {
+	fake := goaitest.NewChatModel(goaitest.Text("hello"))
+	ts := llamacpptest.NewServer(fake, goaitest.NewEmbeddingModel(8))
+	defer ts.Close()
+	ts.ContextSize = 16
+
+	host := strings.TrimPrefix(ts.URL, "http://")
+	chatModel, err := goai.OpenChatModel("llamacpp://" + host + "?temperature=0.2&seed=7&slot=0&context_check=true")
+	if err != nil {
+		t.Fatalf("OpenChatModel() error = %v", err)
+	}
+
+	resp, err := chatModel.Call(context.Background(), prompt.NewPrompt(prompt.UserMessage("hi")))
+	if err != nil || resp.Text() != "hello" {
+		t.Fatalf("Call() got = %+v, error = %v", resp, err)
+	}
+
+	requests := ts.Requests()
+	var req map[string]any
+	_ = json.Unmarshal(requests[len(requests)-1].Body, &req)
+	if req["temperature"] != 0.2 || req["seed"] != 7.0 || req["id_slot"] != 0.0 {
+		t.Errorf("Call() request = %s", requests[len(requests)-1].Body)
+	}
+
+	_, err = chatModel.Call(context.Background(), prompt.NewPrompt(prompt.UserMessage(strings.Repeat("word ", 100))))
+	if !errors.Is(err, prompt.ErrContextExceeded) {
+		t.Errorf("Call() error = %v", err)
+	}
+
+	embeddingModel, err := goai.OpenEmbeddingModel("llamacpp://" + host + "?dimensions=4")
+	if err != nil {
+		t.Fatalf("OpenEmbeddingModel() error = %v", err)
+	}
+	embeddings, err := embeddingModel.Call(context.Background(), embedding.NewRequest([]string{"a"}, embedding.Option{}))
+	if err != nil || len(embeddings.Embeddings[0].Embedding) != 4 {
+		t.Errorf("Call() got = %+v, error = %v", embeddings, err)
+	}
+
+	if _, err = goai.OpenEmbeddingModel("llamacpp://" + host + "?temperature=0.2"); err == nil {
+		t.Errorf("OpenEmbeddingModel() with a chat parameter error = nil")
+	}
+}
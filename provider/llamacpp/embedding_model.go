@@ -0,0 +1,75 @@
+package llamacpp
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/tech1024/goai/embedding"
+)
+
+func NewEmbeddingModel(client *Client, model string) *EmbeddingModel {
+	return &EmbeddingModel{
+		client: client,
+		model:  model,
+	}
+}
+
+// EmbeddingModel a goai.EmbeddingModel using the /embedding endpoint of a
+// server started with --embeddings and a pooling type other than none.
+type EmbeddingModel struct {
+	client *Client
+	model  string
+}
+
+func (embeddingModel *EmbeddingModel) Call(ctx context.Context, request embedding.Request) (embedding.Response, error) {
+	embeddingResponse := embedding.Response{Model: embeddingModel.model}
+	if request.Option.Model != "" {
+		embeddingResponse.Model = request.Option.Model
+	}
+
+	results, err := embeddingModel.client.Embedding(ctx, &EmbeddingRequest{Content: request.Inputs})
+	if err != nil {
+		return embeddingResponse, err
+	}
+	if len(results) != len(request.Inputs) {
+		return embeddingResponse, fmt.Errorf("llamacpp: %d embeddings of %d inputs", len(results), len(request.Inputs))
+	}
+
+	embeddingResponse.Embeddings = make([]embedding.Embedding, len(results))
+	for i, result := range results {
+		// without pooling the server returns a vector per token.
+		if len(result.Embedding) != 1 {
+			return embeddingResponse, fmt.Errorf("llamacpp: %d vectors of input %d, start the server with a pooling type", len(result.Embedding), result.Index)
+		}
+
+		vector := result.Embedding[0]
+		// llama.cpp has no dimensions parameter, the vectors of matryoshka
+		// models are truncated and normalized again.
+		if dimensions := request.Option.Dimensions; dimensions > 0 && dimensions < len(vector) {
+			vector = normalize(vector[:dimensions])
+		}
+		embeddingResponse.Embeddings[i] = embedding.Embedding{Embedding: vector, Index: result.Index}
+	}
+
+	return embeddingResponse, nil
+}
+
+// normalize returns the vector scaled to unit length.
+func normalize(values []float32) []float32 {
+	var norm float64
+	for _, v := range values {
+		norm += float64(v) * float64(v)
+	}
+	norm = math.Sqrt(norm)
+
+	vector := make([]float32, len(values))
+	for i, v := range values {
+		if norm > 0 {
+			v = float32(float64(v) / norm)
+		}
+		vector[i] = v
+	}
+
+	return vector
+}
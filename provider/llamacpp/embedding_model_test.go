@@ -0,0 +1,71 @@
+package llamacpp_test
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"testing"
+
+	"github.com/tech1024/goai/embedding"
+	"github.com/tech1024/goai/goaitest"
+	"github.com/tech1024/goai/provider/llamacpp"
+	"github.com/tech1024/goai/provider/llamacpp/llamacpptest"
+)
+
+func TestEmbeddingModel(t *testing.T) {
+	ts := llamacpptest.NewServer(nil, goaitest.NewEmbeddingModel(8))
+	defer ts.Close()
+
+	model := llamacpp.NewEmbeddingModel(ts.LlamaCppClient(), "nomic-embed")
+	resp, err := model.Call(context.Background(), embedding.NewRequest([]string{"a", "b"}, embedding.Option{}))
+	if err != nil || len(resp.Embeddings) != 2 || resp.Model != "nomic-embed" {
+		t.Fatalf("Call() got = %+v, error = %v", resp, err)
+	}
+	for i, e := range resp.Embeddings {
+		if e.Index != i || len(e.Embedding) != 8 || e.Embedding[0] != goaitest.Vector([]string{"a", "b"}[i], 8)[0] {
+			t.Errorf("Call() embedding %d = %+v", i, e)
+		}
+	}
+
+	// the vectors are truncated and normalized again.
+	resp, err = model.Call(context.Background(), embedding.NewRequest([]string{"a"}, embedding.Option{Dimensions: 4}))
+	if err != nil || len(resp.Embeddings[0].Embedding) != 4 {
+		t.Fatalf("Call() got = %+v, error = %v", resp, err)
+	}
+	var norm float64
+	for _, v := range resp.Embeddings[0].Embedding {
+		norm += float64(v) * float64(v)
+	}
+	if math.Abs(norm-1) > 1e-5 {
+		t.Errorf("Call() norm = %v", norm)
+	}
+
+	if requests := ts.Requests(); requests[0].Path != "/embedding" {
+		t.Errorf("Call() path = %s", requests[0].Path)
+	}
+}
+
+func TestEmbeddingModel_NotSupported(t *testing.T) {
+	ts := llamacpptest.NewServer(nil, nil)
+	defer ts.Close()
+
+	_, err := llamacpp.NewEmbeddingModel(ts.LlamaCppClient(), "").Call(context.Background(), embedding.NewRequest([]string{"a"}, embedding.Option{}))
+	if goaitest.HTTPStatus(err) != http.StatusNotImplemented {
+		t.Errorf("Call() error = %v", err)
+	}
+}
+
+func TestRerankModel(t *testing.T) {
+	ts := llamacpptest.NewServer(nil, nil)
+	defer ts.Close()
+
+	model := llamacpp.NewRerankModel(ts.LlamaCppClient(), "bge-reranker")
+	results, err := model.Rerank(context.Background(), "paris", []string{"london", "paris", "rome"}, 2)
+	if err != nil || len(results) != 2 || results[0].Index != 1 || results[0].RelevanceScore != 1 || results[1].RelevanceScore > 1 {
+		t.Errorf("Rerank() got = %+v, error = %v", results, err)
+	}
+
+	if results, err = model.Rerank(context.Background(), "paris", nil, 0); err != nil || results != nil || len(ts.Requests()) != 1 {
+		t.Errorf("Rerank() without documents got = %+v, error = %v", results, err)
+	}
+}
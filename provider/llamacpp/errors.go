@@ -0,0 +1,75 @@
+package llamacpp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/tech1024/goai/internal/wire"
+)
+
+var (
+	// ErrLoading is returned by [Client.Health] while the server loads the model.
+	ErrLoading = errors.New("llamacpp: loading model")
+
+	// ErrInvalidToolCall is returned when the assembled arguments of a streamed
+	// tool call are no valid JSON.
+	ErrInvalidToolCall = errors.New("llamacpp: invalid tool call arguments")
+)
+
+// StatusError is returned when the server responds with an error status.
+type StatusError struct {
+	// Code the http status code.
+	Code int
+
+	// Status the http status, e.g. "503 Service Unavailable".
+	Status string
+
+	// Type and Message the error reported by the server, e.g. the type
+	// "unavailable_error", empty if the body is no server error.
+	Type    string
+	Message string
+
+	// Body the beginning of the response body.
+	Body string
+}
+
+func (e *StatusError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("http status code: %s, %s: %s", e.Status, e.Type, e.Message)
+	}
+	if e.Body != "" {
+		return fmt.Sprintf("http status code: %s, body: %s", e.Status, e.Body)
+	}
+
+	return fmt.Sprintf("http status code: %s", e.Status)
+}
+
+// StatusCode returns the http status code.
+func (e *StatusError) StatusCode() int {
+	return e.Code
+}
+
+func newStatusError(httpResp *http.Response) *StatusError {
+	bts, body := wire.ReadErrorBody(httpResp)
+
+	statusErr := StatusError{
+		Code:   httpResp.StatusCode,
+		Status: httpResp.Status,
+		Body:   body,
+	}
+
+	var e struct {
+		Error struct {
+			Type    string `json:"type"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(bts, &e) == nil {
+		statusErr.Type = e.Error.Type
+		statusErr.Message = e.Error.Message
+	}
+
+	return &statusErr
+}
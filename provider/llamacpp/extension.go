@@ -0,0 +1,90 @@
+package llamacpp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/tech1024/goai/prompt"
+)
+
+// ProviderName the name of the llama.cpp provider.
+const ProviderName = "llamacpp"
+
+// Extension the llama.cpp specific options of a prompt, attach it to
+// prompt.Option.Extensions or completion.Option.Extensions.
+type Extension struct {
+	// Grammar the GBNF grammar constraining the generation.
+	Grammar string
+
+	// JSONSchema the JSON schema constraining the generation, it cannot be
+	// combined with Grammar.
+	JSONSchema json.RawMessage
+
+	// NProbs the number of most likely tokens returned at each position of
+	// a completion, chat prompts use prompt.Option.TopLogProbs.
+	NProbs int
+
+	// Slot the slot processing the request, nil uses an idle slot.
+	Slot *int
+
+	// CachePrompt reuses the prompt cached by the slot, the server caches
+	// by default.
+	CachePrompt *bool
+
+	// Options the raw parameters of the request, e.g. "temperature" or "seed".
+	Options map[string]any
+}
+
+func (e *Extension) Provider() string {
+	return ProviderName
+}
+
+// Validate reports whether the options can be sent to llama.cpp.
+func (e *Extension) Validate() error {
+	if e.Grammar != "" && len(e.JSONSchema) > 0 {
+		return errors.New("llamacpp: grammar and json schema are exclusive")
+	}
+	if len(e.JSONSchema) > 0 {
+		var schema map[string]any
+		if err := json.Unmarshal(e.JSONSchema, &schema); err != nil || schema == nil {
+			return fmt.Errorf("llamacpp json schema: must be a JSON object: %s", e.JSONSchema)
+		}
+	}
+	if e.NProbs < 0 {
+		return fmt.Errorf("llamacpp n_probs: %d is negative", e.NProbs)
+	}
+
+	return nil
+}
+
+// sampling returns the sampling parameters of the request.
+func (e *Extension) sampling() Sampling {
+	return Sampling{
+		Grammar:     e.Grammar,
+		JSONSchema:  e.JSONSchema,
+		NProbs:      e.NProbs,
+		IDSlot:      e.Slot,
+		CachePrompt: e.CachePrompt,
+		Options:     e.Options,
+	}
+}
+
+// extensionOf returns the llama.cpp extension of the options, an empty one
+// if there is none.
+func extensionOf(extensions []prompt.Extension) (*Extension, error) {
+	for _, extension := range extensions {
+		if extension.Provider() != ProviderName {
+			continue
+		}
+
+		e, ok := extension.(*Extension)
+		if !ok || e == nil {
+			return nil, fmt.Errorf("llamacpp: unsupported extension %T", extension)
+		}
+
+		return e, e.Validate()
+	}
+
+	return &Extension{}, nil
+}
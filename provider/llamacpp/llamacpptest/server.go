@@ -0,0 +1,537 @@
+// Package llamacpptest provides an in-process fake llama.cpp server, whose
+// completion, chat and embedding endpoints are answered by goai models, e.g.
+// the scripted fakes of the goaitest package.
+package llamacpptest
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tech1024/goai"
+	"github.com/tech1024/goai/chat"
+	"github.com/tech1024/goai/embedding"
+	"github.com/tech1024/goai/goaitest"
+	"github.com/tech1024/goai/prompt"
+	"github.com/tech1024/goai/provider/llamacpp"
+)
+
+// DefaultContextSize the context size of a slot reported by /props.
+const DefaultContextSize = 4096
+
+// Server a fake llama.cpp server.
+type Server struct {
+	*goaitest.Server
+
+	// ChatModel answers /completion, /infill and /v1/chat/completions, a
+	// completion prompt is passed as a user message.
+	ChatModel goai.ChatModel
+
+	// EmbeddingModel answers /embedding.
+	EmbeddingModel goai.EmbeddingModel
+
+	// Model the model reported by the responses and /props.
+	Model string
+
+	// ContextSize the context size of a slot, zero uses DefaultContextSize.
+	ContextSize int
+
+	// TotalSlots the number of slots, zero uses one.
+	TotalSlots int
+
+	// Loading makes /health report the model as loading.
+	Loading bool
+
+	mu    sync.Mutex
+	saved map[string]bool
+}
+
+// NewServer starts a fake llama.cpp server answering with the models, either may be nil.
+func NewServer(chatModel goai.ChatModel, embeddingModel goai.EmbeddingModel) *Server {
+	s := &Server{
+		Server:         goaitest.NewServer(),
+		ChatModel:      chatModel,
+		EmbeddingModel: embeddingModel,
+		Model:          "goaitest.gguf",
+		saved:          make(map[string]bool),
+	}
+
+	s.HandleFunc("GET /health", s.handleHealth)
+	s.HandleFunc("GET /props", s.handleProps)
+	s.HandleFunc("GET /slots", s.handleSlots)
+	s.HandleFunc("POST /slots/{id}", s.handleSlotAction)
+	s.HandleFunc("POST /completion", s.handleCompletion)
+	s.HandleFunc("POST /infill", s.handleCompletion)
+	s.HandleFunc("POST /v1/chat/completions", s.handleChatCompletions)
+	s.HandleFunc("POST /embedding", s.handleEmbedding)
+	s.HandleFunc("POST /reranking", s.handleReranking)
+
+	return s
+}
+
+// LlamaCppClient returns a client talking to the server.
+func (s *Server) LlamaCppClient(options ...llamacpp.ClientOption) *llamacpp.Client {
+	options = append([]llamacpp.ClientOption{llamacpp.WithHTTPClient(s.Client())}, options...)
+
+	return llamacpp.NewClient(s.URL, options...)
+}
+
+func errorBody(err error) map[string]any {
+	code := goaitest.HTTPStatus(err)
+	return map[string]any{"error": map[string]any{
+		"code":    code,
+		"message": err.Error(),
+		"type":    errorType(code),
+	}}
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	goaitest.WriteJSON(w, goaitest.HTTPStatus(err), errorBody(err))
+}
+
+// errorType returns the type of the server error of a status code.
+func errorType(code int) string {
+	switch code {
+	case http.StatusBadRequest:
+		return "invalid_request_error"
+	case http.StatusNotFound:
+		return "not_found_error"
+	case http.StatusNotImplemented:
+		return "not_supported_error"
+	case http.StatusServiceUnavailable:
+		return "unavailable_error"
+	default:
+		return "server_error"
+	}
+}
+
+func (s *Server) contextSize() int {
+	if s.ContextSize > 0 {
+		return s.ContextSize
+	}
+
+	return DefaultContextSize
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, _ *http.Request) {
+	if s.Loading {
+		writeError(w, &goaitest.StatusError{Code: http.StatusServiceUnavailable, Message: "Loading model"})
+		return
+	}
+
+	goaitest.WriteJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (s *Server) handleProps(w http.ResponseWriter, _ *http.Request) {
+	goaitest.WriteJSON(w, http.StatusOK, llamacpp.Props{
+		DefaultGenerationSettings: llamacpp.GenerationSettings{NCtx: s.contextSize()},
+		TotalSlots:                max(s.TotalSlots, 1),
+		ModelPath:                 s.Model,
+	})
+}
+
+func (s *Server) handleSlots(w http.ResponseWriter, _ *http.Request) {
+	slots := make([]llamacpp.Slot, max(s.TotalSlots, 1))
+	for i := range slots {
+		slots[i] = llamacpp.Slot{ID: i, NCtx: s.contextSize()}
+	}
+
+	goaitest.WriteJSON(w, http.StatusOK, slots)
+}
+
+func (s *Server) handleSlotAction(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 0 || id >= max(s.TotalSlots, 1) {
+		writeError(w, &goaitest.StatusError{Code: http.StatusBadRequest, Message: "Invalid slot ID"})
+		return
+	}
+
+	var req struct {
+		Filename string `json:"filename"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&req)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := llamacpp.SlotResult{IDSlot: id, Filename: req.Filename}
+	switch action := r.URL.Query().Get("action"); action {
+	case "save":
+		s.saved[req.Filename] = true
+		result.NSaved = 1
+	case "restore":
+		if !s.saved[req.Filename] {
+			writeError(w, &goaitest.StatusError{Code: http.StatusBadRequest, Message: "Unable to restore slot, no available space in KV cache or invalid slot save file"})
+			return
+		}
+		result.NRestored = 1
+	case "erase":
+		result.NErased = 1
+	default:
+		writeError(w, &goaitest.StatusError{Code: http.StatusBadRequest, Message: "Invalid action: " + action})
+		return
+	}
+
+	goaitest.WriteJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) handleCompletion(w http.ResponseWriter, r *http.Request) {
+	var req llamacpp.CompletionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, &goaitest.StatusError{Code: http.StatusBadRequest, Message: err.Error()})
+		return
+	}
+	if s.ChatModel == nil {
+		writeError(w, &goaitest.StatusError{Code: http.StatusNotImplemented, Message: "This server does not support completions."})
+		return
+	}
+
+	p := prompt.NewPrompt(prompt.UserMessage(req.InputPrefix + req.Prompt + req.InputSuffix))
+	evaluated := prompt.EstimateTokens(req.InputPrefix + req.Prompt + req.InputSuffix)
+
+	if !req.Stream {
+		resp, err := s.ChatModel.Call(r.Context(), p)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		var generation chat.Generation
+		if len(resp.Generations) > 0 {
+			generation = resp.Generations[0]
+		}
+		completion := s.completionResponse(generation, evaluated)
+		completion.Content = generation.Content
+		if req.NProbs > 0 {
+			completion.CompletionProbabilities = toProbabilities(generation.LogProbs)
+		}
+
+		goaitest.WriteJSON(w, http.StatusOK, completion)
+		return
+	}
+
+	events := goaitest.NewEventStream(w)
+	streamed := &chat.Response{}
+	err := s.ChatModel.Stream(r.Context(), p, func(resp *chat.Response) error {
+		streamed.Append(resp)
+		if len(resp.Generations) == 0 {
+			return nil
+		}
+		generation := resp.Generations[0]
+		if generation.Content == "" {
+			return nil
+		}
+
+		chunk := llamacpp.CompletionResponse{Content: generation.Content, Model: s.Model}
+		if req.NProbs > 0 {
+			chunk.CompletionProbabilities = toProbabilities(generation.LogProbs)
+		}
+
+		return events.Send("", chunk)
+	})
+	if err != nil {
+		if !events.Started() {
+			writeError(w, err)
+			return
+		}
+		_ = events.Send("", errorBody(err))
+		return
+	}
+
+	last := chat.Generation{Content: streamed.Text(), FinishReason: streamed.FinishReason()}
+	_ = events.Send("", s.completionResponse(last, evaluated))
+}
+
+// completionResponse returns the final response of a completion.
+func (s *Server) completionResponse(generation chat.Generation, evaluated int) llamacpp.CompletionResponse {
+	predicted := prompt.EstimateTokens(generation.Content)
+	resp := llamacpp.CompletionResponse{
+		Model:           s.Model,
+		Stop:            true,
+		StopType:        llamacpp.StopEOS,
+		TokensPredicted: predicted,
+		TokensEvaluated: evaluated,
+		Timings:         &llamacpp.Timings{PromptN: evaluated, PromptMS: 1, PredictedN: predicted, PredictedMS: 2},
+	}
+	if generation.FinishReason == chat.FinishReasonLength {
+		resp.StopType = llamacpp.StopLimit
+	}
+
+	return resp
+}
+
+func (s *Server) handleChatCompletions(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		llamacpp.ChatRequest
+		Messages []struct {
+			Role       string                  `json:"role"`
+			Content    json.RawMessage         `json:"content"`
+			ToolCalls  []llamacpp.ChatToolCall `json:"tool_calls"`
+			ToolCallID string                  `json:"tool_call_id"`
+		} `json:"messages"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, &goaitest.StatusError{Code: http.StatusBadRequest, Message: err.Error()})
+		return
+	}
+	if s.ChatModel == nil {
+		writeError(w, &goaitest.StatusError{Code: http.StatusNotImplemented, Message: "This server does not support chat completions."})
+		return
+	}
+
+	p := prompt.Prompt{ChatOption: prompt.Option{Model: req.Model}}
+	for _, tool := range req.Tools {
+		p.ChatOption.Tools = append(p.ChatOption.Tools, prompt.Tool{
+			Name:        tool.Function.Name,
+			Description: tool.Function.Description,
+			Parameters:  tool.Function.Parameters,
+		})
+	}
+	for _, m := range req.Messages {
+		var text string
+		var images []prompt.Image
+		if json.Unmarshal(m.Content, &text) != nil {
+			var parts []llamacpp.ChatContentPart
+			_ = json.Unmarshal(m.Content, &parts)
+			for _, part := range parts {
+				if part.ImageURL != nil {
+					images = append(images, toImage(part.ImageURL.URL))
+				}
+				text += part.Text
+			}
+		}
+
+		switch {
+		case len(m.ToolCalls) > 0:
+			var toolCalls []chat.ToolCall
+			for _, tc := range m.ToolCalls {
+				toolCalls = append(toolCalls, chat.ToolCall{ID: tc.ID, Name: tc.Function.Name, Arguments: tc.Function.Arguments})
+			}
+			p.Messages = append(p.Messages, prompt.AssistantToolCallMessage(text, toolCalls...))
+		case m.Role == prompt.MessageTypeTool.String():
+			p.Messages = append(p.Messages, prompt.ToolResultMessage(chat.ToolCall{ID: m.ToolCallID}, text))
+		case len(images) > 0:
+			p.Messages = append(p.Messages, prompt.UserImageMessage(text, images...))
+		default:
+			p.Messages = append(p.Messages, prompt.NewMessage(prompt.MessageType(m.Role), text))
+		}
+	}
+
+	model := s.Model
+	if req.Model != "" {
+		model = req.Model
+	}
+
+	if !req.Stream {
+		resp, err := s.ChatModel.Call(r.Context(), p)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		completion := llamacpp.ChatResponse{ID: completionID(), Model: model, Usage: toUsage(resp.Usage), Timings: &llamacpp.Timings{PromptMS: 1, PredictedMS: 2}}
+		for _, generation := range resp.Generations {
+			choice := llamacpp.ChatChoice{
+				Index: len(completion.Choices),
+				Message: llamacpp.ChatResponseMessage{
+					Role:             "assistant",
+					Content:          generation.Content,
+					ReasoningContent: generation.Thinking,
+					ToolCalls:        toToolCalls(generation.ToolCalls),
+				},
+				FinishReason: finishReason(generation),
+			}
+			if req.Logprobs {
+				choice.Logprobs = &llamacpp.ChatLogprobs{Content: toProbabilities(generation.LogProbs)}
+			}
+			completion.Choices = append(completion.Choices, choice)
+		}
+
+		goaitest.WriteJSON(w, http.StatusOK, completion)
+		return
+	}
+
+	s.streamChatCompletions(r.Context(), w, model, req.Logprobs, p)
+}
+
+func (s *Server) streamChatCompletions(ctx context.Context, w http.ResponseWriter, model string, logprobs bool, p prompt.Prompt) {
+	events := goaitest.NewEventStream(w)
+	id := completionID()
+	streamed := &chat.Response{}
+	var toolCalls int
+
+	chunk := func(choice llamacpp.ChatChoice) llamacpp.ChatResponse {
+		return llamacpp.ChatResponse{ID: id, Model: model, Choices: []llamacpp.ChatChoice{choice}}
+	}
+
+	err := s.ChatModel.Stream(ctx, p, func(resp *chat.Response) error {
+		streamed.Append(resp)
+		if len(resp.Generations) == 0 {
+			return nil
+		}
+		generation := resp.Generations[0]
+
+		if generation.Content != "" || generation.Thinking != "" {
+			choice := llamacpp.ChatChoice{Delta: llamacpp.ChatResponseMessage{Content: generation.Content, ReasoningContent: generation.Thinking}}
+			if logprobs {
+				choice.Logprobs = &llamacpp.ChatLogprobs{Content: toProbabilities(generation.LogProbs)}
+			}
+			if err := events.Send("", chunk(choice)); err != nil {
+				return err
+			}
+		}
+
+		// tool calls are streamed in fragments.
+		for _, tc := range generation.ToolCalls {
+			index := toolCalls
+			toolCalls++
+			for j, fragment := range goaitest.Fragments(tc.Arguments) {
+				call := llamacpp.ChatToolCall{Index: &index, Function: llamacpp.ChatFunctionCall{Arguments: fragment}}
+				if j == 0 {
+					call.ID, call.Type, call.Function.Name = tc.ID, "function", tc.Name
+				}
+				if err := events.Send("", chunk(llamacpp.ChatChoice{Delta: llamacpp.ChatResponseMessage{ToolCalls: []llamacpp.ChatToolCall{call}}})); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		if !events.Started() {
+			writeError(w, err)
+			return
+		}
+		_ = events.Send("", errorBody(err))
+		return
+	}
+
+	final := chunk(llamacpp.ChatChoice{FinishReason: finishReason(chat.Generation{FinishReason: streamed.FinishReason(), ToolCalls: streamed.ToolCalls()})})
+	final.Usage = toUsage(streamed.Usage)
+	final.Timings = &llamacpp.Timings{PromptMS: 1, PredictedMS: 2}
+	_ = events.Send("", final)
+	_ = events.Done()
+}
+
+func (s *Server) handleEmbedding(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Content json.RawMessage `json:"content"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, &goaitest.StatusError{Code: http.StatusBadRequest, Message: err.Error()})
+		return
+	}
+	if s.EmbeddingModel == nil {
+		writeError(w, &goaitest.StatusError{Code: http.StatusNotImplemented, Message: "This server does not support embeddings. Start it with `--embeddings`"})
+		return
+	}
+
+	var inputs []string
+	if json.Unmarshal(req.Content, &inputs) != nil {
+		var input string
+		_ = json.Unmarshal(req.Content, &input)
+		inputs = []string{input}
+	}
+
+	resp, err := s.EmbeddingModel.Call(r.Context(), embedding.NewRequest(inputs, embedding.Option{}))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	results := make([]llamacpp.EmbeddingResult, len(resp.Embeddings))
+	for i, e := range resp.Embeddings {
+		results[i] = llamacpp.EmbeddingResult{Index: e.Index, Embedding: [][]float32{e.Embedding}}
+	}
+
+	goaitest.WriteJSON(w, http.StatusOK, results)
+}
+
+// handleReranking scores the documents by the cosine similarity of their
+// goaitest vectors to the vector of the query, equal texts score 1.
+func (s *Server) handleReranking(w http.ResponseWriter, r *http.Request) {
+	var req llamacpp.RerankRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, &goaitest.StatusError{Code: http.StatusBadRequest, Message: err.Error()})
+		return
+	}
+
+	query := goaitest.Vector(req.Query, 64)
+	resp := llamacpp.RerankResponse{Model: s.Model, Results: make([]llamacpp.RerankResult, len(req.Documents))}
+	for i, document := range req.Documents {
+		var score float64
+		for j, v := range goaitest.Vector(document, 64) {
+			score += float64(v) * float64(query[j])
+		}
+		resp.Results[i] = llamacpp.RerankResult{Index: i, RelevanceScore: math.Round(score*1e6) / 1e6}
+	}
+
+	goaitest.WriteJSON(w, http.StatusOK, resp)
+}
+
+// toImage returns the image of a data url, or of any other url.
+func toImage(url string) prompt.Image {
+	header, data, ok := strings.Cut(strings.TrimPrefix(url, "data:"), ";base64,")
+	if !ok || !strings.HasPrefix(url, "data:") {
+		return prompt.Image{URL: url}
+	}
+
+	bts, _ := base64.StdEncoding.DecodeString(data)
+	return prompt.Image{MimeType: header, Data: bts}
+}
+
+func toUsage(usage chat.Usage) *llamacpp.ChatUsage {
+	return &llamacpp.ChatUsage{
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		TotalTokens:      usage.TotalTokens,
+	}
+}
+
+func toToolCalls(toolCalls []chat.ToolCall) []llamacpp.ChatToolCall {
+	var calls []llamacpp.ChatToolCall
+	for _, tc := range toolCalls {
+		calls = append(calls, llamacpp.ChatToolCall{
+			ID:       tc.ID,
+			Type:     "function",
+			Function: llamacpp.ChatFunctionCall{Name: tc.Name, Arguments: tc.Arguments},
+		})
+	}
+
+	return calls
+}
+
+func toProbabilities(logProbs []chat.LogProb) []llamacpp.TokenProbability {
+	result := make([]llamacpp.TokenProbability, len(logProbs))
+	for i, logProb := range logProbs {
+		result[i] = llamacpp.TokenProbability{TokenProb: llamacpp.TokenProb{Token: logProb.Token, Logprob: logProb.LogProb}}
+		for _, top := range logProb.TopLogProbs {
+			result[i].TopLogprobs = append(result[i].TopLogprobs, llamacpp.TokenProb{Token: top.Token, Logprob: top.LogProb})
+		}
+	}
+
+	return result
+}
+
+func finishReason(generation chat.Generation) string {
+	if generation.FinishReason != "" {
+		return string(generation.FinishReason)
+	}
+	if len(generation.ToolCalls) > 0 {
+		return string(chat.FinishReasonToolCalls)
+	}
+
+	return string(chat.FinishReasonStop)
+}
+
+func completionID() string {
+	return fmt.Sprintf("chatcmpl-%d", time.Now().UnixNano())
+}
@@ -0,0 +1,41 @@
+package llamacpp
+
+import (
+	"net/http"
+)
+
+// DefaultHost the address llama-server listens on by default.
+const DefaultHost = "http://127.0.0.1:8080"
+
+// ClientOption configures a Client.
+type ClientOption func(*Client)
+
+// WithHTTPClient sets the http client sending the requests, defaults to http.DefaultClient.
+func WithHTTPClient(httpClient *http.Client) ClientOption {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithAPIKey sets the key of a server started with --api-key.
+func WithAPIKey(apiKey string) ClientOption {
+	return func(c *Client) {
+		c.header.Set("Authorization", "Bearer "+apiKey)
+	}
+}
+
+// WithHeader adds a header sent with every request.
+func WithHeader(key, value string) ClientOption {
+	return func(c *Client) {
+		c.header.Add(key, value)
+	}
+}
+
+// WithContextCheck makes the models fail early with prompt.ErrContextExceeded
+// when a prompt exceeds the context size of a slot, which is read from /props
+// once. Without it the server reports the error.
+func WithContextCheck() ClientOption {
+	return func(c *Client) {
+		c.checkContext = true
+	}
+}
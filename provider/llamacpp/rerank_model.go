@@ -0,0 +1,49 @@
+package llamacpp
+
+import (
+	"context"
+	"sort"
+)
+
+func NewRerankModel(client *Client, model string) *RerankModel {
+	return &RerankModel{
+		client: client,
+		model:  model,
+	}
+}
+
+// RerankModel scores documents by their relevance to a query using the
+// /reranking endpoint of a server started with --reranking.
+type RerankModel struct {
+	client *Client
+	model  string
+}
+
+// Rerank returns the results of the documents ordered by descending relevance,
+// a positive topN returns the topN most relevant ones only.
+func (rerankModel *RerankModel) Rerank(ctx context.Context, query string, documents []string, topN int) ([]RerankResult, error) {
+	if len(documents) == 0 {
+		return nil, nil
+	}
+
+	resp, err := rerankModel.client.Rerank(ctx, &RerankRequest{
+		Query:     query,
+		Documents: documents,
+		TopN:      topN,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// older servers return the results in the order of the documents and
+	// ignore top_n.
+	results := resp.Results
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].RelevanceScore > results[j].RelevanceScore
+	})
+	if topN > 0 && topN < len(results) {
+		results = results[:topN]
+	}
+
+	return results, nil
+}
@@ -0,0 +1,22 @@
+package llamacpp
+
+import (
+	"github.com/tech1024/goai/chat"
+)
+
+func toToolCalls(toolCalls []ChatToolCall) []chat.ToolCall {
+	var calls []chat.ToolCall
+	for _, toolCall := range toolCalls {
+		arguments := toolCall.Function.Arguments
+		if arguments == "" {
+			arguments = "{}"
+		}
+		calls = append(calls, chat.ToolCall{
+			ID:        toolCall.ID,
+			Name:      toolCall.Function.Name,
+			Arguments: arguments,
+		})
+	}
+
+	return calls
+}
@@ -0,0 +1,330 @@
+package llamacpp
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/tech1024/goai/chat"
+)
+
+// Stop types of a completion.
+const (
+	StopNone  = "none"
+	StopEOS   = "eos"
+	StopLimit = "limit"
+	StopWord  = "word"
+)
+
+// Sampling the llama.cpp specific parameters shared by the completion and the
+// chat requests.
+type Sampling struct {
+	// Grammar the GBNF grammar constraining the generation.
+	Grammar string `json:"grammar,omitempty"`
+
+	// JSONSchema the JSON schema constraining the generation, it is converted
+	// into a grammar by the server.
+	JSONSchema json.RawMessage `json:"json_schema,omitempty"`
+
+	// NProbs the number of most likely tokens returned at each position.
+	NProbs int `json:"n_probs,omitempty"`
+
+	// IDSlot the slot processing the request, -1 or nil uses an idle slot.
+	IDSlot *int `json:"id_slot,omitempty"`
+
+	// CachePrompt reuses the prompt cached by the slot, the server caches
+	// by default.
+	CachePrompt *bool `json:"cache_prompt,omitempty"`
+
+	// Options the raw parameters of the request, e.g. "temperature" or "seed".
+	Options map[string]any `json:"-"`
+}
+
+// CompletionRequest is the request passed to [Client.Completion] and [Client.Infill].
+type CompletionRequest struct {
+	Prompt string `json:"prompt"`
+
+	// InputPrefix and InputSuffix the text before and after the cursor of
+	// an infill request.
+	InputPrefix string `json:"input_prefix,omitempty"`
+	InputSuffix string `json:"input_suffix,omitempty"`
+
+	NPredict int      `json:"n_predict,omitempty"`
+	Stop     []string `json:"stop,omitempty"`
+	Stream   bool     `json:"stream,omitempty"`
+
+	Sampling
+}
+
+func (r *CompletionRequest) MarshalJSON() ([]byte, error) {
+	type request CompletionRequest
+	return marshalWithOptions((*request)(r), r.Options)
+}
+
+// TokenProb is a token and its log probability.
+type TokenProb struct {
+	ID      int     `json:"id"`
+	Token   string  `json:"token"`
+	Logprob float64 `json:"logprob"`
+	Bytes   []int   `json:"bytes,omitempty"`
+}
+
+// TokenProbability is a generated token, its log probability and the most
+// likely tokens at its position.
+type TokenProbability struct {
+	TokenProb
+	TopLogprobs []TokenProb `json:"top_logprobs,omitempty"`
+}
+
+// Timings is the durations of a request in milliseconds.
+type Timings struct {
+	PromptN     int     `json:"prompt_n"`
+	PromptMS    float64 `json:"prompt_ms"`
+	PredictedN  int     `json:"predicted_n"`
+	PredictedMS float64 `json:"predicted_ms"`
+}
+
+// Timing returns the durations of the request.
+func (t *Timings) Timing() chat.Timing {
+	if t == nil {
+		return chat.Timing{}
+	}
+
+	return chat.Timing{
+		PromptDuration:     time.Duration(t.PromptMS * float64(time.Millisecond)),
+		CompletionDuration: time.Duration(t.PredictedMS * float64(time.Millisecond)),
+	}
+}
+
+// CompletionResponse is the response from [Client.Completion], or a chunk of
+// [Client.CompletionStream].
+type CompletionResponse struct {
+	Content string `json:"content"`
+	Model   string `json:"model,omitempty"`
+	IDSlot  int    `json:"id_slot"`
+
+	// Stop reports the last chunk of a stream.
+	Stop bool `json:"stop"`
+
+	// StopType is StopEOS, StopLimit or StopWord once stopped.
+	StopType     string `json:"stop_type,omitempty"`
+	StoppingWord string `json:"stopping_word,omitempty"`
+	Truncated    bool   `json:"truncated,omitempty"`
+
+	TokensPredicted int      `json:"tokens_predicted,omitempty"`
+	TokensEvaluated int      `json:"tokens_evaluated,omitempty"`
+	Timings         *Timings `json:"timings,omitempty"`
+
+	CompletionProbabilities []TokenProbability `json:"completion_probabilities,omitempty"`
+}
+
+// ChatContentPart is a part of the content of a message, a text or an image.
+type ChatContentPart struct {
+	Type     string        `json:"type"`
+	Text     string        `json:"text,omitempty"`
+	ImageURL *ChatImageURL `json:"image_url,omitempty"`
+}
+
+// ChatImageURL is the url of an image, e.g. a data url.
+type ChatImageURL struct {
+	URL string `json:"url"`
+}
+
+// ChatFunctionCall is the function of a tool call.
+type ChatFunctionCall struct {
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments"`
+}
+
+// ChatToolCall is a tool call of the model, or a fragment of it when streaming.
+type ChatToolCall struct {
+	Index    *int             `json:"index,omitempty"`
+	ID       string           `json:"id,omitempty"`
+	Type     string           `json:"type,omitempty"`
+	Function ChatFunctionCall `json:"function"`
+}
+
+// ChatMessage is a message of the conversation.
+type ChatMessage struct {
+	Role string `json:"role"`
+
+	// Content is a string, or the []ChatContentPart of a message with images.
+	Content any `json:"content"`
+
+	ToolCalls  []ChatToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string         `json:"tool_call_id,omitempty"`
+}
+
+// ChatFunction is a function the model may call.
+type ChatFunction struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters"`
+}
+
+// ChatTool is a tool the model may use.
+type ChatTool struct {
+	Type     string       `json:"type"`
+	Function ChatFunction `json:"function"`
+}
+
+// ChatRequest is the request passed to [Client.ChatCompletion].
+type ChatRequest struct {
+	Model       string        `json:"model,omitempty"`
+	Messages    []ChatMessage `json:"messages"`
+	Tools       []ChatTool    `json:"tools,omitempty"`
+	MaxTokens   int           `json:"max_tokens,omitempty"`
+	Stop        []string      `json:"stop,omitempty"`
+	Logprobs    bool          `json:"logprobs,omitempty"`
+	TopLogprobs int           `json:"top_logprobs,omitempty"`
+	Stream      bool          `json:"stream,omitempty"`
+
+	Sampling
+}
+
+func (r *ChatRequest) MarshalJSON() ([]byte, error) {
+	type request ChatRequest
+	return marshalWithOptions((*request)(r), r.Options)
+}
+
+// ChatResponseMessage is the message of a choice, or its delta when streaming.
+type ChatResponseMessage struct {
+	Role             string         `json:"role,omitempty"`
+	Content          string         `json:"content,omitempty"`
+	ReasoningContent string         `json:"reasoning_content,omitempty"`
+	ToolCalls        []ChatToolCall `json:"tool_calls,omitempty"`
+}
+
+// ChatLogprobs is the log probabilities of a choice.
+type ChatLogprobs struct {
+	Content []TokenProbability `json:"content"`
+}
+
+// ChatChoice is a generated message.
+type ChatChoice struct {
+	Index        int                 `json:"index"`
+	Message      ChatResponseMessage `json:"message"`
+	Delta        ChatResponseMessage `json:"delta"`
+	FinishReason string              `json:"finish_reason,omitempty"`
+	Logprobs     *ChatLogprobs       `json:"logprobs,omitempty"`
+}
+
+// ChatUsage is the tokens used by a chat request.
+type ChatUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// ChatResponse is the response from [Client.ChatCompletion], or a chunk of
+// [Client.ChatCompletionStream].
+type ChatResponse struct {
+	ID      string       `json:"id"`
+	Model   string       `json:"model"`
+	Choices []ChatChoice `json:"choices"`
+	Usage   *ChatUsage   `json:"usage,omitempty"`
+	Timings *Timings     `json:"timings,omitempty"`
+}
+
+// EmbeddingRequest is the request passed to [Client.Embedding].
+type EmbeddingRequest struct {
+	Content []string `json:"content"`
+}
+
+// EmbeddingResult is the embedding of an input, a single vector unless the
+// server runs without pooling and returns one vector per token.
+type EmbeddingResult struct {
+	Index     int         `json:"index"`
+	Embedding [][]float32 `json:"embedding"`
+}
+
+// RerankRequest is the request passed to [Client.Rerank].
+type RerankRequest struct {
+	Query     string   `json:"query"`
+	Documents []string `json:"documents"`
+	TopN      int      `json:"top_n,omitempty"`
+}
+
+// RerankResult is the relevance of a document to the query.
+type RerankResult struct {
+	// Index the index of the document in the request.
+	Index          int     `json:"index"`
+	RelevanceScore float64 `json:"relevance_score"`
+}
+
+// RerankResponse is the response from [Client.Rerank].
+type RerankResponse struct {
+	Model   string         `json:"model,omitempty"`
+	Results []RerankResult `json:"results"`
+}
+
+// GenerationSettings is the default settings of a slot.
+type GenerationSettings struct {
+	// NCtx the context size of a slot.
+	NCtx int `json:"n_ctx"`
+}
+
+// Props is the properties of the server.
+type Props struct {
+	DefaultGenerationSettings GenerationSettings `json:"default_generation_settings"`
+	TotalSlots                int                `json:"total_slots"`
+	ModelPath                 string             `json:"model_path"`
+	ChatTemplate              string             `json:"chat_template,omitempty"`
+	BuildInfo                 string             `json:"build_info,omitempty"`
+}
+
+// Slot is the state of a slot of the server.
+type Slot struct {
+	ID           int  `json:"id"`
+	NCtx         int  `json:"n_ctx"`
+	IsProcessing bool `json:"is_processing"`
+}
+
+// SlotResult is the result of a slot action.
+type SlotResult struct {
+	IDSlot    int    `json:"id_slot"`
+	Filename  string `json:"filename,omitempty"`
+	NSaved    int    `json:"n_saved,omitempty"`
+	NRestored int    `json:"n_restored,omitempty"`
+	NErased   int    `json:"n_erased,omitempty"`
+}
+
+func (r *EmbeddingResult) UnmarshalJSON(data []byte) error {
+	var result struct {
+		Index     int             `json:"index"`
+		Embedding json.RawMessage `json:"embedding"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return err
+	}
+
+	// servers before the batched endpoint return a flat vector.
+	r.Index = result.Index
+	var vector []float32
+	if err := json.Unmarshal(result.Embedding, &vector); err == nil {
+		r.Embedding = [][]float32{vector}
+		return nil
+	}
+
+	return json.Unmarshal(result.Embedding, &r.Embedding)
+}
+
+// marshalWithOptions marshals the request and adds the raw options, the
+// fields of the request take precedence.
+func marshalWithOptions(v any, options map[string]any) ([]byte, error) {
+	bts, err := json.Marshal(v)
+	if err != nil || len(options) == 0 {
+		return bts, err
+	}
+
+	var fields map[string]any
+	if err = json.Unmarshal(bts, &fields); err != nil {
+		return nil, err
+	}
+	for key, value := range options {
+		if _, ok := fields[key]; !ok {
+			fields[key] = value
+		}
+	}
+
+	return json.Marshal(fields)
+}
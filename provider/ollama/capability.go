@@ -0,0 +1,232 @@
+package ollama
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Capability a capability of a model, as reported by /api/show.
+type Capability string
+
+const (
+	CapabilityCompletion Capability = "completion"
+	CapabilityTools      Capability = "tools"
+	CapabilityInsert     Capability = "insert"
+	CapabilityVision     Capability = "vision"
+	CapabilityEmbedding  Capability = "embedding"
+	CapabilityThinking   Capability = "thinking"
+)
+
+// Capabilities the capabilities of a model.
+type Capabilities struct {
+	Model        string
+	Capabilities []Capability
+
+	// ContextLength the context length the model was trained with, zero if unknown.
+	ContextLength int
+}
+
+// Has reports whether the model has the capability.
+func (c *Capabilities) Has(capability Capability) bool {
+	return slices.Contains(c.Capabilities, capability)
+}
+
+// capabilityCache caches the server version and the model capabilities.
+type capabilityCache struct {
+	mu      sync.Mutex
+	version string
+	models  map[string]*Capabilities
+}
+
+// ServerVersion returns the version of the ollama server, it is queried once
+// and cached by the client.
+func (c *Client) ServerVersion(ctx context.Context) (string, error) {
+	c.cache.mu.Lock()
+	version := c.cache.version
+	c.cache.mu.Unlock()
+	if version != "" {
+		return version, nil
+	}
+
+	version, err := c.Version(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	c.cache.mu.Lock()
+	c.cache.version = version
+	c.cache.mu.Unlock()
+
+	return version, nil
+}
+
+// Capabilities returns the capabilities of the model, they are queried once
+// and cached by the client until the model is pulled, created or deleted.
+func (c *Client) Capabilities(ctx context.Context, model string) (*Capabilities, error) {
+	c.cache.mu.Lock()
+	capabilities, ok := c.cache.models[model]
+	c.cache.mu.Unlock()
+	if ok {
+		return capabilities, nil
+	}
+
+	resp, err := c.Show(ctx, &ShowRequest{Model: model})
+	if err != nil {
+		return nil, err
+	}
+	capabilities = toCapabilities(model, resp)
+
+	c.cache.mu.Lock()
+	if c.cache.models == nil {
+		c.cache.models = make(map[string]*Capabilities)
+	}
+	c.cache.models[model] = capabilities
+	c.cache.mu.Unlock()
+
+	return capabilities, nil
+}
+
+// forget removes the cached capabilities of the model.
+func (c *Client) forget(model string) {
+	c.cache.mu.Lock()
+	delete(c.cache.models, model)
+	c.cache.mu.Unlock()
+}
+
+func toCapabilities(model string, resp *ShowResponse) *Capabilities {
+	capabilities := Capabilities{Model: model}
+	for _, capability := range resp.Capabilities {
+		capabilities.Capabilities = append(capabilities.Capabilities, Capability(capability))
+	}
+
+	architecture, _ := resp.ModelInfo["general.architecture"].(string)
+	if contextLength, ok := resp.ModelInfo[architecture+".context_length"].(float64); ok {
+		capabilities.ContextLength = int(contextLength)
+	}
+
+	// servers older than 0.6.4 report no capabilities, guess them like ollama did.
+	if len(resp.Capabilities) == 0 {
+		if _, ok := resp.ModelInfo[architecture+".pooling_type"]; ok {
+			capabilities.Capabilities = append(capabilities.Capabilities, CapabilityEmbedding)
+		} else {
+			capabilities.Capabilities = append(capabilities.Capabilities, CapabilityCompletion)
+		}
+		if strings.Contains(resp.Template, ".Tools") {
+			capabilities.Capabilities = append(capabilities.Capabilities, CapabilityTools)
+		}
+		if strings.Contains(resp.Template, ".Suffix") {
+			capabilities.Capabilities = append(capabilities.Capabilities, CapabilityInsert)
+		}
+		if len(resp.ProjectorInfo) > 0 {
+			capabilities.Capabilities = append(capabilities.Capabilities, CapabilityVision)
+		}
+	}
+
+	return &capabilities
+}
+
+// requirement a feature used by a request.
+type requirement struct {
+	// feature describes the feature in errors.
+	feature string
+
+	// capability the model capability the feature needs, empty if none.
+	capability Capability
+
+	// version the minimum server version of the feature, empty if any.
+	version string
+}
+
+var (
+	requireCompletion = requirement{feature: "chat and completion", capability: CapabilityCompletion}
+	requireTools      = requirement{feature: "tools", capability: CapabilityTools, version: "0.3.0"}
+	requireInsert     = requirement{feature: "suffix", capability: CapabilityInsert}
+	requireVision     = requirement{feature: "images", capability: CapabilityVision}
+	requireThinking   = requirement{feature: "think", capability: CapabilityThinking, version: "0.9.0"}
+	requireSchema     = requirement{feature: "JSON schema format", version: "0.5.0"}
+	requireEmbedding  = requirement{feature: "embedding", capability: CapabilityEmbedding}
+)
+
+// generationRequirements returns the requirements of a chat or generate request.
+func generationRequirements(format json.RawMessage, think *bool) []requirement {
+	requirements := []requirement{requireCompletion}
+	if think != nil && *think {
+		requirements = append(requirements, requireThinking)
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(format), []byte("{")) {
+		requirements = append(requirements, requireSchema)
+	}
+
+	return requirements
+}
+
+// require checks that the server and the model support the features, when
+// the client was created with WithCapabilityCheck.
+func (c *Client) require(ctx context.Context, model string, requirements ...requirement) (*Capabilities, error) {
+	if !c.checkCapabilities {
+		return nil, nil
+	}
+
+	version, err := c.ServerVersion(ctx)
+	if err != nil && !isMissingEndpoint(err) {
+		return nil, err
+	}
+
+	capabilities, err := c.Capabilities(ctx, model)
+	if err != nil {
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) && statusErr.Code == http.StatusNotFound && statusErr.Message != "" {
+			return nil, fmt.Errorf("%w: model %q not found", ErrUnsupported, model)
+		}
+		return nil, err
+	}
+
+	for _, r := range requirements {
+		if r.version != "" && !versionAtLeast(version, r.version) {
+			return nil, fmt.Errorf("%w: %s requires ollama %s or later, the server is %s", ErrUnsupported, r.feature, r.version, version)
+		}
+		if r.capability != "" && !capabilities.Has(r.capability) {
+			return nil, fmt.Errorf("%w: model %q does not support %s", ErrUnsupported, model, r.feature)
+		}
+	}
+
+	return capabilities, nil
+}
+
+// versionAtLeast reports whether the version is at least the minimum, unknown
+// and development versions, e.g. "0.0.0", are assumed to be recent.
+func versionAtLeast(version, minimum string) bool {
+	v, ok := parseVersion(version)
+	if !ok || v == [3]int{} {
+		return true
+	}
+	m, _ := parseVersion(minimum)
+
+	return slices.Compare(v[:], m[:]) >= 0
+}
+
+func parseVersion(version string) ([3]int, bool) {
+	var v [3]int
+	version, _, _ = strings.Cut(strings.TrimPrefix(version, "v"), "-")
+	parts := strings.Split(version, ".")
+	if len(parts) != 3 {
+		return v, false
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return v, false
+		}
+		v[i] = n
+	}
+
+	return v, true
+}
@@ -0,0 +1,125 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tech1024/goai/embedding"
+	"github.com/tech1024/goai/goaitest"
+	"github.com/tech1024/goai/prompt"
+	"github.com/tech1024/goai/provider/ollama/ollamatest"
+)
+
+func TestCapabilityCheck(t *testing.T) {
+	ts := ollamatest.NewServer(goaitest.NewChatModel(goaitest.Text("hi"), goaitest.Text("hi")), goaitest.NewEmbeddingModel(4))
+	defer ts.Close()
+	ts.Models = []ollamatest.Model{
+		{Name: "llama2", Capabilities: []string{"completion"}, ContextLength: 100},
+		{Name: "qwen3", Capabilities: []string{"completion", "tools", "thinking"}},
+		{Name: "nomic-embed-text", Capabilities: []string{"embedding"}},
+	}
+	ts.Version = "0.5.7"
+
+	client, _ := NewClient(ts.URL, WithCapabilityCheck())
+	think := true
+	tools := []prompt.Tool{{Name: "weather"}}
+
+	tests := []struct {
+		name    string
+		model   string
+		prompt  prompt.Prompt
+		wantErr error
+	}{
+		{name: "tools", model: "qwen3", prompt: prompt.Prompt{Messages: []prompt.Message{prompt.UserMessage("hi")}, ChatOption: prompt.Option{Tools: tools}}},
+		{name: "schema", model: "llama2", prompt: prompt.Prompt{Messages: []prompt.Message{prompt.UserMessage("hi")}, ChatOption: prompt.Option{Extensions: []prompt.Extension{&Extension{Format: json.RawMessage(`{"type":"object"}`)}}}}},
+		{name: "no tools", model: "llama2", prompt: prompt.Prompt{Messages: []prompt.Message{prompt.UserMessage("hi")}, ChatOption: prompt.Option{Tools: tools}}, wantErr: ErrUnsupported},
+		{name: "old server", model: "qwen3", prompt: prompt.Prompt{Messages: []prompt.Message{prompt.UserMessage("hi")}, ChatOption: prompt.Option{Extensions: []prompt.Extension{&Extension{Think: &think}}}}, wantErr: ErrUnsupported},
+		{name: "images", model: "llama2", prompt: prompt.NewPrompt(prompt.UserImageMessage("what is it?", prompt.Image{MimeType: "image/png", Data: []byte{0x89}})), wantErr: ErrUnsupported},
+		{name: "embedding model", model: "nomic-embed-text", prompt: prompt.NewPrompt(prompt.UserMessage("hi")), wantErr: ErrUnsupported},
+		{name: "missing model", model: "mistral", prompt: prompt.NewPrompt(prompt.UserMessage("hi")), wantErr: ErrUnsupported},
+		{name: "context", model: "llama2", prompt: prompt.NewPrompt(prompt.UserMessage(strings.Repeat("word ", 100))), wantErr: prompt.ErrContextExceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewNewChatModel(client, tt.model).Call(context.Background(), tt.prompt)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Call() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := NewEmbeddingModel(client, "llama2").Call(context.Background(), embedding.NewRequest([]string{"hi"}, embedding.Option{})); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Embedding Call() error = %v", err)
+	}
+	if _, err := NewEmbeddingModel(client, "nomic-embed-text").Call(context.Background(), embedding.NewRequest([]string{"hi"}, embedding.Option{})); err != nil {
+		t.Errorf("Embedding Call() error = %v", err)
+	}
+
+	counts := map[string]int{}
+	for _, r := range ts.Requests() {
+		counts[r.Path]++
+	}
+	want := map[string]int{"/api/version": 1, "/api/show": 4, "/api/chat": 2, "/api/embed": 1}
+	if !reflect.DeepEqual(counts, want) {
+		t.Errorf("requests = %v, want %v", counts, want)
+	}
+
+	ts.HandleFunc("DELETE /api/delete", func(w http.ResponseWriter, r *http.Request) {})
+	if err := client.Delete(context.Background(), &DeleteRequest{Model: "qwen3"}); err != nil {
+		t.Errorf("Delete() error = %v", err)
+	}
+	if _, err := client.Capabilities(context.Background(), "qwen3"); err != nil || counts["/api/show"]+1 != len(filter(ts.Requests(), "/api/show")) {
+		t.Errorf("Capabilities() not queried again after Delete(), error = %v", err)
+	}
+}
+
+func TestToCapabilities(t *testing.T) {
+	got := toCapabilities("codellama", &ShowResponse{
+		Template:      "{{ if .Suffix }}<PRE> {{ .Prompt }} <SUF>{{ .Suffix }} <MID>{{ end }}",
+		ModelInfo:     map[string]any{"general.architecture": "llama", "llama.context_length": 16384.0},
+		ProjectorInfo: map[string]any{"clip.has_vision_encoder": true},
+	})
+	want := &Capabilities{
+		Model:         "codellama",
+		Capabilities:  []Capability{CapabilityCompletion, CapabilityInsert, CapabilityVision},
+		ContextLength: 16384,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("toCapabilities() got = %v, want %v", got, want)
+	}
+}
+
+func TestVersionAtLeast(t *testing.T) {
+	tests := []struct {
+		version, minimum string
+		want             bool
+	}{
+		{"0.5.7", "0.5.0", true},
+		{"0.5.7", "0.9.0", false},
+		{"0.10.1", "0.9.0", true},
+		{"v0.9.0-rc1", "0.9.0", true},
+		{"0.0.0", "0.9.0", true},
+		{"", "0.9.0", true},
+	}
+	for _, tt := range tests {
+		if got := versionAtLeast(tt.version, tt.minimum); got != tt.want {
+			t.Errorf("versionAtLeast(%q, %q) = %v, want %v", tt.version, tt.minimum, got, tt.want)
+		}
+	}
+}
+
+func filter(requests []goaitest.HTTPRequest, path string) []goaitest.HTTPRequest {
+	var filtered []goaitest.HTTPRequest
+	for _, r := range requests {
+		if r.Path == path {
+			filtered = append(filtered, r)
+		}
+	}
+
+	return filtered
+}
@@ -2,7 +2,10 @@ package ollama
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 
+	"github.com/tech1024/goai/chat"
 	"github.com/tech1024/goai/prompt"
 )
 
@@ -18,48 +21,213 @@ type ChatModel struct {
 	model  string
 }
 
-func (chatModel *ChatModel) Call(ctx context.Context, prompt prompt.Prompt) (string, error) {
+func (chatModel *ChatModel) Call(ctx context.Context, prompt prompt.Prompt) (*chat.Response, error) {
 	req, err := chatModel.buildChatRequest(prompt)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	if err = chatModel.check(ctx, prompt, req); err != nil {
+		return nil, err
 	}
 
-	resp, err := chatModel.client.Chat(ctx, req)
-	if err != nil {
-		return "", err
+	// ollama has no candidate count, the candidates are sampled one by one.
+	response := &chat.Response{}
+	for range candidates(prompt) {
+		resp, err := chatModel.client.Chat(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+
+		candidate := chatModel.toChatResponse(resp)
+		response.Model = candidate.Model
+		response.Generations = append(response.Generations, candidate.Generations...)
+		response.Usage = response.Usage.Add(candidate.Usage)
+		response.Timing.PromptDuration += candidate.Timing.PromptDuration
+		response.Timing.CompletionDuration += candidate.Timing.CompletionDuration
 	}
 
-	return resp.Message.Content, nil
+	return response, nil
 }
 
-func (chatModel *ChatModel) Stream(ctx context.Context, prompt prompt.Prompt, fn func([]byte) error) error {
+func (chatModel *ChatModel) Stream(ctx context.Context, prompt prompt.Prompt, fn func(*chat.Response) error) error {
 	req, err := chatModel.buildChatRequest(prompt)
 	if err != nil {
 		return err
 	}
-
-	err = chatModel.client.ChatStream(ctx, req, fn)
-	if err != nil {
+	if err = chatModel.check(ctx, prompt, req); err != nil {
 		return err
 	}
 
+	// the candidates are streamed one after another, the chunks of a candidate
+	// carry its generation at its index, the usage of all candidates is only
+	// reported by the last chunk of the last one.
+	var usage chat.Usage
+	n := candidates(prompt)
+	for i := range n {
+		_, err = chatModel.client.ChatStreamResponse(ctx, req, func(resp *ChatResponse) error {
+			chunk := chatModel.toChatResponse(resp)
+			if i > 0 {
+				chunk.Generations = append(make([]chat.Generation, i), chunk.Generations...)
+			}
+			if !chunk.Usage.IsZero() {
+				usage = usage.Add(chunk.Usage)
+				chunk.Usage = chat.Usage{}
+				if i == n-1 {
+					chunk.Usage = usage
+				}
+			}
+
+			return fn(chunk)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
-func (chatModel *ChatModel) buildChatRequest(prompt prompt.Prompt) (*ChatRequest, error) {
+// candidates returns the number of generations requested by the prompt.
+func candidates(p prompt.Prompt) int {
+	return max(p.ChatOption.Candidates, 1)
+}
+
+func (chatModel *ChatModel) buildChatRequest(p prompt.Prompt) (*ChatRequest, error) {
 	request := ChatRequest{
 		Model:    chatModel.model,
-		Messages: make([]Message, len(prompt.Messages)),
+		Messages: make([]Message, len(p.Messages)),
 	}
-	for i, message := range prompt.Messages {
+	for i, message := range p.Messages {
 		request.Messages[i] = Message{
 			Role:    message.Type().String(),
 			Content: message.Text(),
 		}
+		for _, image := range prompt.Images(message) {
+			if len(image.Data) == 0 {
+				return nil, fmt.Errorf("%w: image urls, images must be attached as data", ErrUnsupported)
+			}
+			request.Messages[i].Images = append(request.Messages[i].Images, image.Data)
+		}
+
+		switch message.Type() {
+		case prompt.MessageTypeAssistant:
+			for _, toolCall := range prompt.ToolCalls(message) {
+				var arguments ToolCallFunctionArguments
+				if toolCall.Arguments != "" {
+					if err := json.Unmarshal([]byte(toolCall.Arguments), &arguments); err != nil {
+						return nil, fmt.Errorf("tool call %s arguments: %w", toolCall.Name, err)
+					}
+				}
+				request.Messages[i].ToolCalls = append(request.Messages[i].ToolCalls, ToolCall{
+					Function: ToolCallFunction{Name: toolCall.Name, Arguments: arguments},
+				})
+			}
+		case prompt.MessageTypeTool:
+			request.Messages[i].ToolName = prompt.ToolName(message)
+		}
+	}
+	if p.ChatOption.Model != "" {
+		request.Model = p.ChatOption.Model
+	}
+	request.Logprobs = p.ChatOption.LogProbs || p.ChatOption.TopLogProbs > 0
+	request.TopLogprobs = p.ChatOption.TopLogProbs
+
+	extension, err := extensionOf(p.ChatOption.Extensions)
+	if err != nil {
+		return nil, err
+	}
+	if extension != nil {
+		request.KeepAlive = extension.keepAlive()
+		request.Format = extension.Format
+		request.Think = extension.Think
+		request.Options = mergeOptions(request.Options, extension.Options)
 	}
-	if prompt.ChatOption.Model != "" {
-		request.Model = prompt.ChatOption.Model
+
+	for _, tool := range p.ChatOption.Tools {
+		parameters := tool.Parameters
+		if len(parameters) == 0 {
+			parameters = json.RawMessage(`{"type":"object","properties":{}}`)
+		}
+		request.Tools = append(request.Tools, Tool{
+			Type: "function",
+			Function: ToolFunction{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  parameters,
+			},
+		})
 	}
 
 	return &request, nil
 }
+
+// check fails early if the server or the model cannot serve the request.
+func (chatModel *ChatModel) check(ctx context.Context, p prompt.Prompt, req *ChatRequest) error {
+	requirements := generationRequirements(req.Format, req.Think)
+	if len(req.Tools) > 0 {
+		requirements = append(requirements, requireTools)
+	}
+	for _, message := range req.Messages {
+		if len(message.Images) > 0 {
+			requirements = append(requirements, requireVision)
+			break
+		}
+	}
+
+	capabilities, err := chatModel.client.require(ctx, req.Model, requirements...)
+	if err != nil || capabilities == nil || capabilities.ContextLength == 0 {
+		return err
+	}
+
+	if tokens := prompt.NewFitter(capabilities.ContextLength, 0).Count(p.Messages); tokens > capabilities.ContextLength {
+		return fmt.Errorf("%w: about %d tokens, model %q has %d", prompt.ErrContextExceeded, tokens, req.Model, capabilities.ContextLength)
+	}
+
+	return nil
+}
+
+func (chatModel *ChatModel) toChatResponse(resp *ChatResponse) *chat.Response {
+	generation := chat.Generation{
+		Content:      resp.Message.Content,
+		Thinking:     resp.Message.Thinking,
+		FinishReason: chat.FinishReason(resp.DoneReason),
+		LogProbs:     toLogProbs(resp.Logprobs),
+	}
+	for _, toolCall := range resp.Message.ToolCalls {
+		arguments := "{}"
+		if toolCall.Function.Arguments != nil {
+			arguments = toolCall.Function.Arguments.String()
+		}
+		generation.ToolCalls = append(generation.ToolCalls, chat.ToolCall{
+			Name:      toolCall.Function.Name,
+			Arguments: arguments,
+		})
+	}
+
+	return &chat.Response{
+		Model:       resp.Model,
+		Generations: []chat.Generation{generation},
+		Usage:       resp.Metrics.Usage(),
+		Timing: chat.Timing{
+			PromptDuration:     resp.Metrics.PromptEvalDuration,
+			CompletionDuration: resp.Metrics.EvalDuration,
+		},
+	}
+}
+
+func toLogProbs(logprobs []Logprob) []chat.LogProb {
+	if len(logprobs) == 0 {
+		return nil
+	}
+
+	result := make([]chat.LogProb, len(logprobs))
+	for i, logprob := range logprobs {
+		result[i] = chat.LogProb{
+			Token:       logprob.Token,
+			LogProb:     logprob.Logprob,
+			TopLogProbs: toLogProbs(logprob.TopLogprobs),
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,222 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tech1024/goai"
+	"github.com/tech1024/goai/chat"
+	"github.com/tech1024/goai/goaitest"
+	"github.com/tech1024/goai/prompt"
+	"github.com/tech1024/goai/provider/ollama/ollamatest"
+	"github.com/tech1024/goai/usage"
+)
+
+func TestChatModel(t *testing.T) {
+	fake := goaitest.NewChatModel(
+		goaitest.Text("hello").WithUsage(chat.Usage{PromptTokens: 2, CompletionTokens: 1, TotalTokens: 3}),
+		goaitest.Chunks("a", "b", "c").WithUsage(chat.Usage{PromptTokens: 2, CompletionTokens: 3, TotalTokens: 5}),
+		goaitest.Error(&goaitest.StatusError{Code: http.StatusNotFound, Message: "model not found"}),
+	)
+	ts := ollamatest.NewServer(fake, nil)
+	defer ts.Close()
+
+	client, err := NewClient(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := goai.NewChat(NewNewChatModel(client, "test-model"))
+
+	resp, err := c.Call(context.Background(), ollamaPrompt("request 1"))
+	if err != nil || resp.Text() != "hello" || resp.Model != "test-model" || resp.Usage.TotalTokens != 3 {
+		t.Errorf("Call() got = %v, error = %v", resp, err)
+	}
+
+	var chunks []string
+	err = c.ChatStream(context.Background(), "request 2", func(bts []byte) error {
+		chunks = append(chunks, string(bts))
+		return nil
+	})
+	if err != nil || strings.Join(chunks, "|") != "a|b|c" {
+		t.Errorf("ChatStream() got = %v, error = %v", chunks, err)
+	}
+
+	if _, err = c.Chat(context.Background(), "request 3"); err == nil || !strings.Contains(err.Error(), "model not found") {
+		t.Errorf("Chat() error = %v", err)
+	}
+
+	if got := fake.Prompts()[1].Messages[0].Text(); got != "request 2" {
+		t.Errorf("Prompts() got = %v", got)
+	}
+	if got := ts.Requests(); len(got) != 3 || got[0].Path != "/api/chat" {
+		t.Errorf("Requests() got = %v", got)
+	}
+}
+
+func TestChatModel_StreamError(t *testing.T) {
+	ts := ollamatest.NewServer(goaitest.NewChatModel(goaitest.Reply{
+		Chunks: goaitest.Chunks("partial").Chunks,
+		Err:    errors.New("model crashed"),
+	}), nil)
+	defer ts.Close()
+
+	client, _ := NewClient(ts.URL)
+	err := NewNewChatModel(client, "test-model").Stream(context.Background(), ollamaPrompt("q"), func(*chat.Response) error {
+		return nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "model crashed") {
+		t.Errorf("Stream() error = %v", err)
+	}
+}
+
+func ollamaPrompt(content string) prompt.Prompt {
+	return prompt.NewPrompt(prompt.UserMessage(content))
+}
+
+func TestChatModel_Tools(t *testing.T) {
+	weather := chat.ToolCall{Name: "get_weather", Arguments: `{"city":"Paris"}`}
+	fake := goaitest.NewChatModel(
+		goaitest.ToolCalls(weather),
+		goaitest.Text("It is sunny in Paris."),
+		goaitest.Reply{Chunks: []*chat.Response{
+			{Generations: []chat.Generation{{Content: "Let me check. "}}},
+			{Generations: []chat.Generation{{ToolCalls: []chat.ToolCall{weather}}}},
+		}},
+	)
+	ts := ollamatest.NewServer(fake, nil)
+	defer ts.Close()
+
+	client, _ := NewClient(ts.URL)
+	model := NewNewChatModel(client, "llama3.1")
+	p := ollamaPrompt("What's the weather in Paris?")
+	p.ChatOption.Tools = []prompt.Tool{{
+		Name:        "get_weather",
+		Description: "Get the current weather of a city",
+		Parameters:  json.RawMessage(`{"type":"object","properties":{"city":{"type":"string"}},"required":["city"]}`),
+	}}
+
+	resp, err := model.Call(context.Background(), p)
+	if err != nil || !reflect.DeepEqual(resp.ToolCalls(), []chat.ToolCall{weather}) {
+		t.Fatalf("Call() got = %v, error = %v", resp, err)
+	}
+
+	var req ChatRequest
+	_ = json.Unmarshal(ts.Requests()[0].Body, &req)
+	if len(req.Tools) != 1 || req.Tools[0].Type != "function" || req.Tools[0].Function.Name != "get_weather" ||
+		!strings.Contains(string(req.Tools[0].Function.Parameters), `"required":["city"]`) {
+		t.Errorf("Call() tools = %s", ts.Requests()[0].Body)
+	}
+
+	p.Messages = append(p.Messages,
+		prompt.AssistantToolCallMessage("", resp.ToolCalls()...),
+		prompt.ToolResultMessage(resp.ToolCalls()[0], `{"weather":"sunny"}`),
+	)
+	if resp, err = model.Call(context.Background(), p); err != nil || resp.Text() != "It is sunny in Paris." {
+		t.Fatalf("Call() got = %v, error = %v", resp, err)
+	}
+
+	_ = json.Unmarshal(ts.Requests()[1].Body, &req)
+	if len(req.Messages) != 3 || req.Messages[1].ToolCalls[0].Function.Arguments["city"] != "Paris" ||
+		req.Messages[2].Role != "tool" || req.Messages[2].ToolName != "get_weather" {
+		t.Errorf("Call() messages = %s", ts.Requests()[1].Body)
+	}
+
+	streamed := &chat.Response{}
+	err = model.Stream(context.Background(), p, func(chunk *chat.Response) error {
+		streamed.Append(chunk)
+		return nil
+	})
+	if err != nil || streamed.Text() != "Let me check. " || !reflect.DeepEqual(streamed.ToolCalls(), []chat.ToolCall{weather}) {
+		t.Errorf("Stream() got = %v, error = %v", streamed, err)
+	}
+}
+
+func TestChatModel_Candidates(t *testing.T) {
+	usage := chat.Usage{PromptTokens: 2, CompletionTokens: 1, TotalTokens: 3}
+	fake := goaitest.NewChatModel(
+		goaitest.Text("a").WithUsage(usage), goaitest.Text("b").WithUsage(usage), goaitest.Text("c").WithUsage(usage),
+		goaitest.Text("d").WithUsage(usage), goaitest.Chunks("e", "f").WithUsage(usage),
+	)
+	ts := ollamatest.NewServer(fake, nil)
+	defer ts.Close()
+
+	client, _ := NewClient(ts.URL)
+	model := NewNewChatModel(client, "llama3")
+	p := ollamaPrompt("pick a letter")
+	p.ChatOption.Candidates = 3
+
+	resp, err := model.Call(context.Background(), p)
+	if err != nil || len(resp.Generations) != 3 || resp.Generations[2].Content != "c" || resp.Usage != usage.Add(usage).Add(usage) {
+		t.Errorf("Call() got = %+v, error = %v", resp, err)
+	}
+
+	p.ChatOption.Candidates = 2
+	streamed := &chat.Response{}
+	err = model.Stream(context.Background(), p, func(chunk *chat.Response) error {
+		streamed.Append(chunk)
+		return nil
+	})
+	if err != nil || len(streamed.Generations) != 2 || streamed.Text() != "d" || streamed.Generations[1].Content != "ef" || streamed.Usage != usage.Add(usage) {
+		t.Errorf("Stream() got = %+v, error = %v", streamed, err)
+	}
+	if len(ts.Requests()) != 5 {
+		t.Errorf("requests = %d, want 5", len(ts.Requests()))
+	}
+}
+
+func TestChatModel_LogProbs(t *testing.T) {
+	var body []byte
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		_, _ = io.WriteString(w, `{"model":"llama3","message":{"role":"assistant","content":"yes"},"done":true,`+
+			`"logprobs":[{"token":"yes","logprob":-0.1,"top_logprobs":[{"token":"yes","logprob":-0.1},{"token":"no","logprob":-2.4}]}]}`)
+	}))
+	defer ts.Close()
+
+	client, _ := NewClient(ts.URL)
+	p := ollamaPrompt("is it?")
+	p.ChatOption.TopLogProbs = 2
+
+	resp, err := NewNewChatModel(client, "llama3").Call(context.Background(), p)
+	want := []chat.LogProb{{Token: "yes", LogProb: -0.1, TopLogProbs: []chat.LogProb{{Token: "yes", LogProb: -0.1}, {Token: "no", LogProb: -2.4}}}}
+	if err != nil || !reflect.DeepEqual(resp.Generations[0].LogProbs, want) {
+		t.Errorf("Call() got = %+v, error = %v", resp, err)
+	}
+
+	var req ChatRequest
+	_ = json.Unmarshal(body, &req)
+	if !req.Logprobs || req.TopLogprobs != 2 {
+		t.Errorf("Call() request = %s", body)
+	}
+}
+
+func TestChatModel_CandidatesStreamUsage(t *testing.T) {
+	fake := goaitest.NewChatModel(
+		goaitest.Chunks("a", "b").WithUsage(chat.Usage{PromptTokens: 2, CompletionTokens: 2, TotalTokens: 4}),
+		goaitest.Chunks("c", "d").WithUsage(chat.Usage{PromptTokens: 2, CompletionTokens: 3, TotalTokens: 5}),
+	)
+	ts := ollamatest.NewServer(fake, nil)
+	defer ts.Close()
+
+	// every candidate reports its own usage, the total is recorded once.
+	client, _ := NewClient(ts.URL)
+	tracker := usage.NewTracker(nil, nil)
+	model := goai.WrapChatModel(NewNewChatModel(client, "llama3"), usage.Track(tracker))
+	p := ollamaPrompt("pick a letter")
+	p.ChatOption.Model = "llama3"
+	p.ChatOption.Candidates = 2
+	if err := model.Stream(context.Background(), p, func(*chat.Response) error { return nil }); err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+
+	if total := tracker.Total("llama3"); total.Calls != 1 || total.TotalTokens != 9 {
+		t.Errorf("Stream() recorded = %+v", total)
+	}
+}
@@ -10,14 +10,25 @@ import (
 	"io"
 	"net/http"
 	"net/url"
-	"runtime"
 	"time"
+
+	"github.com/tech1024/goai/internal/wire"
 )
 
-func NewClient(baseUrl string) (*Client, error) {
+// NewClient returns a Client of the ollama server at baseUrl, an empty
+// baseUrl is read from the OLLAMA_HOST environment variable.
+func NewClient(baseUrl string, options ...ClientOption) (*Client, error) {
 	var err error
 
-	client := Client{}
+	client := Client{
+		header:    make(http.Header),
+		userAgent: defaultUserAgent,
+		cache:     &capabilityCache{},
+	}
+
+	if baseUrl == "" {
+		baseUrl = hostFromEnv()
+	}
 
 	client.baseUrl, err = url.Parse(baseUrl)
 	if err != nil {
@@ -26,24 +37,54 @@ func NewClient(baseUrl string) (*Client, error) {
 
 	client.httpClient = http.DefaultClient
 
+	for _, option := range options {
+		option(&client)
+	}
+
+	if client.transport != nil || client.timeout > 0 {
+		httpClient := *client.httpClient
+		if client.transport != nil {
+			httpClient.Transport = client.transport
+		}
+		if client.timeout > 0 {
+			httpClient.Timeout = client.timeout
+		}
+		client.httpClient = &httpClient
+	}
+
 	return &client, nil
 }
 
 type Client struct {
 	baseUrl    *url.URL // baseUrl The base url of the Client server.
 	httpClient *http.Client
+	transport  http.RoundTripper
+	timeout    time.Duration
+	header     http.Header // header The default headers of every request.
+	userAgent  string
+
+	streamIdleTimeout time.Duration
+
+	checkCapabilities bool
+	cache             *capabilityCache
 }
 
 // Chat part
 
 // Message is a single message in a chat sequence. The message contains the
-// role ("system", "user", or "assistant"), the content and an optional list
-// of images.
+// role ("system", "user", "assistant" or "tool"), the content and an optional
+// list of images.
 type Message struct {
 	Role      string      `json:"role"`
 	Content   string      `json:"content"`
 	Images    []ImageData `json:"images,omitempty"`
 	ToolCalls []ToolCall  `json:"tool_calls,omitempty"`
+
+	// Thinking is the thinking of a thinking model, if thinking is enabled.
+	Thinking string `json:"thinking,omitempty"`
+
+	// ToolName is the name of the tool a "tool" message answers.
+	ToolName string `json:"tool_name,omitempty"`
 }
 
 // ImageData represents the raw binary data of an image file.
@@ -70,16 +111,38 @@ type ChatRequest struct {
 	// Tools is an optional list of tools the model has access to.
 	Tools `json:"tools,omitempty"`
 
+	// Think enables thinking of thinking models, nil uses the model default.
+	Think *bool `json:"think,omitempty"`
+
+	// Logprobs returns the log probabilities of the generated tokens.
+	Logprobs bool `json:"logprobs,omitempty"`
+
+	// TopLogprobs is the number of most likely tokens returned at each position.
+	TopLogprobs int `json:"top_logprobs,omitempty"`
+
 	// Options lists model-specific options.
 	Options map[string]interface{} `json:"options"`
 }
 
+// Logprob is the log probability of a generated token.
+type Logprob struct {
+	Token   string  `json:"token"`
+	Logprob float64 `json:"logprob"`
+	Bytes   []int   `json:"bytes,omitempty"`
+
+	// TopLogprobs is the most likely tokens at the position, if requested.
+	TopLogprobs []Logprob `json:"top_logprobs,omitempty"`
+}
+
 type ChatResponse struct {
 	Model      string    `json:"model"`
 	CreatedAt  time.Time `json:"created_at"`
 	Message    Message   `json:"message"`
 	DoneReason string    `json:"done_reason,omitempty"`
 
+	// Logprobs is the log probabilities of the tokens of the message, if requested.
+	Logprobs []Logprob `json:"logprobs,omitempty"`
+
 	Done bool `json:"done"`
 
 	Metrics
@@ -105,14 +168,182 @@ func (c *Client) Chat(ctx context.Context, request *ChatRequest) (*ChatResponse,
 	return &response, err
 }
 
+// ChatStream sends a chat request, fn receives the JSON of every chunk. Use
+// ChatStreamResponse to receive the decoded chunks.
 func (c *Client) ChatStream(ctx context.Context, request *ChatRequest, fn func([]byte) error) error {
 	request.Stream = true
-	err := c.stream(ctx, http.MethodPost, "/api/chat", request, fn)
+	return c.stream(ctx, http.MethodPost, "/api/chat", request, fn)
+}
+
+// ChatStreamResponse sends a chat request, fn receives every decoded chunk.
+// It returns the final response, whose message accumulates the content, the
+// thinking, the tool calls and the Logprobs of all chunks. The Metrics and the
+// DoneReason are the ones of the final chunk. A stream ending without its final
+// chunk returns the partial response and ErrIncompleteStream.
+func (c *Client) ChatStreamResponse(ctx context.Context, request *ChatRequest, fn func(*ChatResponse) error) (*ChatResponse, error) {
+	var final ChatResponse
+	request.Stream = true
+	err := c.stream(ctx, http.MethodPost, "/api/chat", request, func(bts []byte) error {
+		var response ChatResponse
+		if err := json.Unmarshal(bts, &response); err != nil {
+			return fmt.Errorf("unmarshal: %w", err)
+		}
+
+		final.Model = response.Model
+		final.CreatedAt = response.CreatedAt
+		final.Message.Role = response.Message.Role
+		final.Message.Content += response.Message.Content
+		final.Message.Thinking += response.Message.Thinking
+		final.Message.ToolCalls = append(final.Message.ToolCalls, response.Message.ToolCalls...)
+		final.Logprobs = append(final.Logprobs, response.Logprobs...)
+		if response.Done {
+			final.Done = true
+			final.DoneReason = response.DoneReason
+			final.Metrics = response.Metrics
+		}
+
+		if fn == nil {
+			return nil
+		}
+
+		return fn(&response)
+	})
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return err
+	if !final.Done {
+		return &final, ErrIncompleteStream
+	}
+
+	return &final, nil
+}
+
+// Generate part
+
+// GenerateRequest describes a request sent by [Client.Generate].
+type GenerateRequest struct {
+	// Model is the model name.
+	Model string `json:"model"`
+
+	// Prompt is the textual prompt to send to the model.
+	Prompt string `json:"prompt"`
+
+	// Suffix is the text that comes after the inserted text, for fill-in-the-middle.
+	Suffix string `json:"suffix,omitempty"`
+
+	// System overrides the model's default system message/prompt.
+	System string `json:"system,omitempty"`
+
+	// Template overrides the model's default prompt template.
+	Template string `json:"template,omitempty"`
+
+	// Context is the context parameter returned from a previous call to
+	// [Client.Generate]. It can be used to keep a short conversational memory.
+	Context []int `json:"context,omitempty"`
+
+	// Stream enables streaming of returned responses, set by [Client.Generate]
+	// and [Client.GenerateStream].
+	Stream bool `json:"stream"`
+
+	// Raw set to true means that no formatting will be applied to the prompt.
+	Raw bool `json:"raw,omitempty"`
+
+	// Format specifies the format to return a response in.
+	Format json.RawMessage `json:"format,omitempty"`
+
+	// KeepAlive controls how long the model will stay loaded in memory following
+	// this request.
+	KeepAlive *Duration `json:"keep_alive,omitempty"`
+
+	// Images is an optional list of raw image bytes accompanying this
+	// request, for multimodal models.
+	Images []ImageData `json:"images,omitempty"`
+
+	// Think enables thinking of thinking models, nil uses the model default.
+	Think *bool `json:"think,omitempty"`
+
+	// Options lists model-specific options.
+	Options map[string]interface{} `json:"options"`
+}
+
+// GenerateResponse is the response passed into [Client.GenerateStream].
+type GenerateResponse struct {
+	// Model is the model name that generated the response.
+	Model string `json:"model"`
+
+	// CreatedAt is the timestamp of the response.
+	CreatedAt time.Time `json:"created_at"`
+
+	// Response is the textual response itself.
+	Response string `json:"response"`
+
+	// Thinking is the thinking of a thinking model, if thinking is enabled.
+	Thinking string `json:"thinking,omitempty"`
+
+	// Done specifies if the response is complete.
+	Done bool `json:"done"`
+
+	// DoneReason is the reason the model stopped generating text.
+	DoneReason string `json:"done_reason,omitempty"`
+
+	// Context is an encoding of the conversation used in this response; this
+	// can be sent in the next request to keep a conversational memory.
+	Context []int `json:"context,omitempty"`
+
+	Metrics
+}
+
+// Generate generates a response for the prompt.
+func (c *Client) Generate(ctx context.Context, request *GenerateRequest) (*GenerateResponse, error) {
+	var response GenerateResponse
+	request.Stream = false
+	if err := c.Post(ctx, "/api/generate", request, &response); err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
+
+// GenerateStream generates a response for the prompt, fn receives every
+// decoded chunk. It returns the final response accumulating the text of all
+// chunks, along with the final Context, Metrics and DoneReason. A stream
+// ending without its final chunk returns the partial response and ErrIncompleteStream.
+func (c *Client) GenerateStream(ctx context.Context, request *GenerateRequest, fn func(*GenerateResponse) error) (*GenerateResponse, error) {
+	var final GenerateResponse
+	request.Stream = true
+	err := c.stream(ctx, http.MethodPost, "/api/generate", request, func(bts []byte) error {
+		var response GenerateResponse
+		if err := json.Unmarshal(bts, &response); err != nil {
+			return fmt.Errorf("unmarshal: %w", err)
+		}
+
+		final.Model = response.Model
+		final.CreatedAt = response.CreatedAt
+		final.Response += response.Response
+		final.Thinking += response.Thinking
+		if response.Done {
+			final.Done = true
+			final.DoneReason = response.DoneReason
+			final.Context = response.Context
+			final.Metrics = response.Metrics
+		}
+
+		if fn == nil {
+			return nil
+		}
+
+		return fn(&response)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !final.Done {
+		return &final, ErrIncompleteStream
+	}
+
+	return &final, nil
 }
 
 // Embedding part
@@ -129,8 +360,12 @@ type EmbedRequest struct {
 	// this request.
 	KeepAlive *Duration `json:"keep_alive,omitempty"`
 
+	// Truncate truncates inputs exceeding the context length instead of failing.
 	Truncate *bool `json:"truncate,omitempty"`
 
+	// Dimensions is the number of dimensions of the embeddings, if the model supports it.
+	Dimensions int `json:"dimensions,omitempty"`
+
 	// Options lists model-specific options.
 	Options map[string]interface{} `json:"options"`
 }
@@ -190,7 +425,16 @@ func (c *Client) Embeddings(ctx context.Context, req *EmbeddingRequest) (*Embedd
 // Request part
 
 func (c *Client) Post(ctx context.Context, path string, data, response any) error {
-	httpResp, err := c.do(ctx, http.MethodPost, path, data)
+	return c.send(ctx, http.MethodPost, path, data, response)
+}
+
+func (c *Client) Get(ctx context.Context, path string, response any) error {
+	return c.send(ctx, http.MethodGet, path, nil, response)
+}
+
+// send sends a request and decodes its response, a nil response discards the body.
+func (c *Client) send(ctx context.Context, method, path string, data, response any) error {
+	httpResp, err := c.do(ctx, method, path, data)
 	if err != nil {
 		return err
 	}
@@ -218,52 +462,86 @@ func (c *Client) do(ctx context.Context, method, path string, data any) (*http.R
 	request, err := http.NewRequestWithContext(
 		ctx, method, c.baseUrl.JoinPath(path).String(), body,
 	)
+	if err != nil {
+		return nil, err
+	}
 
 	request.Header.Set("Content-Type", "application/json")
 	request.Header.Set("Accept", "application/json")
-	request.Header.Set("User-Agent", fmt.Sprintf("GoAI (%s %s) Go/%s", runtime.GOARCH, runtime.GOOS, runtime.Version()))
+	request.Header.Set("User-Agent", c.userAgent)
+	for key, values := range c.header {
+		request.Header[key] = values
+	}
 
 	return c.httpClient.Do(request)
 }
 
-const maxBufferSize = 512 * 1000
-
 func (c *Client) stream(ctx context.Context, method, path string, data any, fn func([]byte) error) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	// the idle timer cancels the request once no chunk arrived in time,
+	// including the wait for the first one
+	var idle *time.Timer
+	if c.streamIdleTimeout > 0 {
+		idle = time.AfterFunc(c.streamIdleTimeout, func() {
+			cancel(fmt.Errorf("%w: no chunk within %s", ErrStreamIdle, c.streamIdleTimeout))
+		})
+		defer idle.Stop()
+	}
+
 	httpResp, err := c.do(ctx, method, path, data)
 	if err != nil {
-		return err
+		return c.streamError(ctx, err)
 	}
 
 	defer httpResp.Body.Close()
 
-	scanner := bufio.NewScanner(httpResp.Body)
-	// increase the buffer size to avoid running out of space
-	scanBuf := make([]byte, 0, maxBufferSize)
-	scanner.Buffer(scanBuf, maxBufferSize)
-	for scanner.Scan() {
-		var errorResponse struct {
-			Error string `json:"error,omitempty"`
-		}
+	if httpResp.StatusCode >= http.StatusBadRequest {
+		return newStatusError(httpResp)
+	}
 
-		bts := scanner.Bytes()
-		if err := json.Unmarshal(bts, &errorResponse); err != nil {
-			return fmt.Errorf("unmarshal: %w", err)
+	reader := bufio.NewReader(httpResp.Body)
+	for {
+		bts, err := reader.ReadBytes('\n')
+		if idle != nil {
+			idle.Reset(c.streamIdleTimeout)
 		}
 
-		if errorResponse.Error != "" {
-			return errors.New(errorResponse.Error)
-		}
+		if bts = bytes.TrimSpace(bts); len(bts) > 0 {
+			var errorResponse struct {
+				Error string `json:"error,omitempty"`
+			}
+
+			if jsonErr := json.Unmarshal(bts, &errorResponse); jsonErr != nil {
+				return fmt.Errorf("unmarshal: %w, body: %s", jsonErr, wire.Snippet(bts))
+			}
+
+			if errorResponse.Error != "" {
+				return &StreamError{Message: errorResponse.Error}
+			}
 
-		if httpResp.StatusCode >= http.StatusBadRequest {
-			return fmt.Errorf("http status code: %s", httpResp.Status)
+			if fnErr := fn(bts); fnErr != nil {
+				return fnErr
+			}
 		}
 
-		if err := fn(bts); err != nil {
-			return err
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return c.streamError(ctx, err)
 		}
 	}
+}
 
-	return nil
+// streamError returns the cause of a canceled stream, e.g. the idle timeout.
+func (c *Client) streamError(ctx context.Context, err error) error {
+	if cause := context.Cause(ctx); errors.Is(cause, ErrStreamIdle) {
+		return cause
+	}
+
+	return err
 }
 
 type errorResponse struct {
@@ -273,22 +551,20 @@ type errorResponse struct {
 func (c *Client) processResponse(httpResp *http.Response, response any) error {
 	defer httpResp.Body.Close()
 
+	if httpResp.StatusCode >= http.StatusBadRequest {
+		return newStatusError(httpResp)
+	}
+
 	respBody, err := io.ReadAll(httpResp.Body)
 	if err != nil {
 		return err
 	}
 
-	if httpResp.StatusCode < http.StatusBadRequest {
-		return c.unMarshalJSON(respBody, response)
-	}
-
-	var e errorResponse
-	err = c.unMarshalJSON(respBody, &e)
-	if err != nil {
-		return fmt.Errorf("http status code: %s, %w", httpResp.Status, err)
+	if response == nil {
+		return nil
 	}
 
-	return fmt.Errorf("http status code: %s, %s", httpResp.Status, e.Error)
+	return c.unMarshalJSON(respBody, response)
 }
 
 func (c *Client) marshalJSON(data any) ([]byte, error) {
@@ -7,10 +7,13 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
-	"net/url"
 	"reflect"
 	"strings"
 	"testing"
+
+	"github.com/tech1024/goai/chat"
+	"github.com/tech1024/goai/goaitest"
+	"github.com/tech1024/goai/provider/ollama/ollamatest"
 )
 
 func _handlerFunc(t *testing.T, wantCode int, wantResp any) func(http.ResponseWriter, *http.Request) {
@@ -66,9 +69,9 @@ func TestClient_Chat(t *testing.T) {
 		handlerFunc(writer, request)
 	}))
 	defer ts.Close()
-	c := &Client{
-		baseUrl:    &url.URL{Scheme: "http", Host: ts.Listener.Addr().String()},
-		httpClient: http.DefaultClient,
+	c, err := NewClient(ts.URL)
+	if err != nil {
+		t.Fatal(err)
 	}
 
 	for _, tt := range tests {
@@ -127,9 +130,9 @@ func TestClient_Embed(t *testing.T) {
 		handlerFunc(writer, request)
 	}))
 	defer ts.Close()
-	c := &Client{
-		baseUrl:    &url.URL{Scheme: "http", Host: ts.Listener.Addr().String()},
-		httpClient: http.DefaultClient,
+	c, err := NewClient(ts.URL)
+	if err != nil {
+		t.Fatal(err)
 	}
 
 	for _, tt := range tests {
@@ -154,3 +157,42 @@ func TestClient_Embed(t *testing.T) {
 		})
 	}
 }
+
+func TestClient_ChatStreamResponse(t *testing.T) {
+	ts := ollamatest.NewServer(goaitest.NewChatModel(
+		goaitest.Chunks("Hello", " world").WithUsage(chat.Usage{PromptTokens: 5, CompletionTokens: 2}),
+		goaitest.Chunks("Hello", " world"),
+	), nil)
+	defer ts.Close()
+
+	c, err := NewClient(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var chunks []string
+	final, err := c.ChatStreamResponse(context.Background(), &ChatRequest{Model: "test-model"}, func(resp *ChatResponse) error {
+		chunks = append(chunks, resp.Message.Content)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ChatStreamResponse() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(chunks, []string{"Hello", " world", ""}) {
+		t.Errorf("ChatStreamResponse() chunks = %q", chunks)
+	}
+	if final.Message.Content != "Hello world" || final.Message.Role != "assistant" || !final.Done ||
+		final.DoneReason != "stop" || final.PromptEvalCount != 5 || final.EvalCount != 2 {
+		t.Errorf("ChatStreamResponse() got = %+v", final)
+	}
+
+	var raw []string
+	err = c.ChatStream(context.Background(), &ChatRequest{Model: "test-model"}, func(bts []byte) error {
+		raw = append(raw, string(bts))
+		return nil
+	})
+	if err != nil || len(raw) != 3 || !strings.Contains(raw[0], `"content":"Hello"`) {
+		t.Errorf("ChatStream() got = %q, error = %v", raw, err)
+	}
+}
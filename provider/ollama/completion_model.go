@@ -0,0 +1,118 @@
+package ollama
+
+import (
+	"context"
+
+	"github.com/tech1024/goai/chat"
+	"github.com/tech1024/goai/completion"
+)
+
+func NewCompletionModel(client *Client, model string) *CompletionModel {
+	return &CompletionModel{
+		client: client,
+		model:  model,
+	}
+}
+
+// CompletionModel a goai.CompletionModel using the /api/generate endpoint.
+type CompletionModel struct {
+	client *Client
+	model  string
+}
+
+func (completionModel *CompletionModel) Call(ctx context.Context, request completion.Request) (*completion.Response, error) {
+	req, err := completionModel.buildGenerateRequest(request)
+	if err != nil {
+		return nil, err
+	}
+	if err = completionModel.check(ctx, req); err != nil {
+		return nil, err
+	}
+
+	resp, err := completionModel.client.Generate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return completionModel.toCompletionResponse(resp), nil
+}
+
+func (completionModel *CompletionModel) Stream(ctx context.Context, request completion.Request, fn func(*completion.Response) error) error {
+	req, err := completionModel.buildGenerateRequest(request)
+	if err != nil {
+		return err
+	}
+	if err = completionModel.check(ctx, req); err != nil {
+		return err
+	}
+
+	_, err = completionModel.client.GenerateStream(ctx, req, func(resp *GenerateResponse) error {
+		return fn(completionModel.toCompletionResponse(resp))
+	})
+
+	return err
+}
+
+// check fails early if the server or the model cannot serve the request.
+func (completionModel *CompletionModel) check(ctx context.Context, req *GenerateRequest) error {
+	requirements := generationRequirements(req.Format, req.Think)
+	if req.Suffix != "" {
+		requirements = append(requirements, requireInsert)
+	}
+
+	_, err := completionModel.client.require(ctx, req.Model, requirements...)
+
+	return err
+}
+
+func (completionModel *CompletionModel) buildGenerateRequest(request completion.Request) (*GenerateRequest, error) {
+	req := GenerateRequest{
+		Model:    completionModel.model,
+		Prompt:   request.Prompt,
+		Suffix:   request.Suffix,
+		System:   request.Option.System,
+		Template: request.Option.Template,
+		Context:  request.Context,
+		Raw:      request.Option.Raw,
+	}
+	if request.Option.Model != "" {
+		req.Model = request.Option.Model
+	}
+
+	if request.Option.MaxTokens > 0 || len(request.Option.Stop) > 0 {
+		req.Options = make(map[string]interface{})
+		if request.Option.MaxTokens > 0 {
+			req.Options["num_predict"] = request.Option.MaxTokens
+		}
+		if len(request.Option.Stop) > 0 {
+			req.Options["stop"] = request.Option.Stop
+		}
+	}
+
+	extension, err := extensionOf(request.Option.Extensions)
+	if err != nil {
+		return nil, err
+	}
+	if extension != nil {
+		req.KeepAlive = extension.keepAlive()
+		req.Format = extension.Format
+		req.Think = extension.Think
+		req.Options = mergeOptions(req.Options, extension.Options)
+	}
+
+	return &req, nil
+}
+
+func (completionModel *CompletionModel) toCompletionResponse(resp *GenerateResponse) *completion.Response {
+	return &completion.Response{
+		Model:    resp.Model,
+		Text:     resp.Response,
+		Thinking: resp.Thinking,
+		Usage:    resp.Metrics.Usage(),
+		Context:  resp.Context,
+		Timing: chat.Timing{
+			PromptDuration:     resp.Metrics.PromptEvalDuration,
+			CompletionDuration: resp.Metrics.EvalDuration,
+		},
+	}
+}
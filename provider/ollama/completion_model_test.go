@@ -0,0 +1,81 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tech1024/goai"
+	"github.com/tech1024/goai/completion"
+	"github.com/tech1024/goai/provider/ollama/ollamatest"
+)
+
+func TestCompletionModel(t *testing.T) {
+	ts := ollamatest.NewServer(nil, nil)
+	defer ts.Close()
+	ts.HandleFunc("POST /api/generate", func(w http.ResponseWriter, r *http.Request) {
+		var req GenerateRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if !req.Stream {
+			_ = json.NewEncoder(w).Encode(GenerateResponse{
+				Model: req.Model, Response: "return a + b", Done: true, Context: []int{1, 2, 3},
+				Metrics: Metrics{PromptEvalCount: 7, EvalCount: 4},
+			})
+			return
+		}
+
+		_, _ = io.WriteString(w, `{"model":"codellama","response":"Hello","done":false}`+"\n"+
+			`{"model":"codellama","response":" world","done":false}`+"\n"+
+			`{"model":"codellama","response":"","done":true,"context":[4,5],"eval_count":2}`+"\n")
+	})
+
+	client, err := NewClient(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	model := NewCompletionModel(client, "codellama")
+
+	got, err := goai.NewCompletion(model).FillInTheMiddle(context.Background(), "def add(a, b):\n    ", "\n\nprint(add(1, 2))")
+	if err != nil || got != "return a + b" {
+		t.Errorf("FillInTheMiddle() got = %q, error = %v", got, err)
+	}
+
+	var req GenerateRequest
+	_ = json.Unmarshal(ts.Requests()[0].Body, &req)
+	if req.Model != "codellama" || req.Suffix != "\n\nprint(add(1, 2))" || req.Stream {
+		t.Errorf("FillInTheMiddle() request = %s", ts.Requests()[0].Body)
+	}
+
+	request := completion.NewRequest("Say hello", completion.Option{Raw: true, System: "Be brief", MaxTokens: 8})
+	resp, err := model.Call(context.Background(), request)
+	if err != nil || !reflect.DeepEqual(resp.Context, []int{1, 2, 3}) || resp.Usage.TotalTokens != 11 {
+		t.Errorf("Call() got = %v, error = %v", resp, err)
+	}
+	_ = json.Unmarshal(ts.Requests()[1].Body, &req)
+	if !req.Raw || req.System != "Be brief" || req.Options["num_predict"] != 8.0 {
+		t.Errorf("Call() request = %s", ts.Requests()[1].Body)
+	}
+
+	// the context of the previous response continues the conversation.
+	request = completion.NewRequest("Say it again", completion.Option{Template: "{{ .Prompt }}"})
+	request.Context = resp.Context
+	var text []string
+	var last *completion.Response
+	err = model.Stream(context.Background(), request, func(resp *completion.Response) error {
+		text = append(text, resp.Text)
+		last = resp
+		return nil
+	})
+	if err != nil || strings.Join(text, "") != "Hello world" || !reflect.DeepEqual(last.Context, []int{4, 5}) {
+		t.Errorf("Stream() got = %v, error = %v", text, err)
+	}
+	req = GenerateRequest{}
+	_ = json.Unmarshal(ts.Requests()[2].Body, &req)
+	if req.Template != "{{ .Prompt }}" || !reflect.DeepEqual(req.Context, []int{1, 2, 3}) || !req.Stream {
+		t.Errorf("Stream() request = %s", ts.Requests()[2].Body)
+	}
+}
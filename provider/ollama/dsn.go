@@ -0,0 +1,94 @@
+package ollama
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/tech1024/goai"
+	"github.com/tech1024/goai/embedding"
+	"github.com/tech1024/goai/prompt"
+)
+
+// the ollama provider serves DSNs like "ollama://gpu1:11434/llama3.1?num_ctx=8192",
+// "ollama:llama3.1" uses OLLAMA_HOST. The parameters other than keep_alive,
+// think and format of chat models and truncate of embedding models are passed
+// as raw model options.
+func init() {
+	goai.RegisterProvider(ProviderName, goai.Provider{
+		ChatModel: func(dsn *goai.DSN) (goai.ChatModel, error) {
+			client, err := clientOf(dsn)
+			if err != nil {
+				return nil, err
+			}
+
+			var extension Extension
+			if extension.Think, err = dsn.Bool("think"); err != nil {
+				return nil, err
+			}
+			if format := dsn.Param("format"); format != "" {
+				extension.Format, _ = json.Marshal(format)
+			}
+			if err = readExtension(dsn, &extension); err != nil {
+				return nil, err
+			}
+
+			return goai.DefaultChatOption(prompt.Option{
+				Extensions: []prompt.Extension{&extension},
+			})(NewNewChatModel(client, dsn.Model)), nil
+		},
+		EmbeddingModel: func(dsn *goai.DSN) (goai.EmbeddingModel, error) {
+			client, err := clientOf(dsn)
+			if err != nil {
+				return nil, err
+			}
+
+			var extension Extension
+			if extension.Truncate, err = dsn.Bool("truncate"); err != nil {
+				return nil, err
+			}
+			if err = readExtension(dsn, &extension); err != nil {
+				return nil, err
+			}
+
+			return goai.DefaultEmbeddingOption(embedding.Option{
+				Extensions: []prompt.Extension{&extension},
+			})(NewEmbeddingModel(client, dsn.Model)), nil
+		},
+	})
+}
+
+// clientOf returns the client of the DSN, whose host may omit the port.
+func clientOf(dsn *goai.DSN) (*Client, error) {
+	if dsn.Model == "" {
+		return nil, errors.New("ollama dsn: missing model")
+	}
+
+	apiKey, err := dsn.APIKey()
+	if err != nil {
+		return nil, err
+	}
+
+	baseUrl := dsn.BaseURL("http", "")
+	if baseUrl != "" {
+		baseUrl = parseHost(baseUrl)
+	}
+
+	var options []ClientOption
+	if apiKey != "" {
+		options = append(options, WithBearerToken(apiKey))
+	}
+
+	return NewClient(baseUrl, options...)
+}
+
+// readExtension reads the keep_alive and the remaining parameters of the DSN
+// into the extension.
+func readExtension(dsn *goai.DSN, extension *Extension) error {
+	var err error
+	if extension.KeepAlive, err = dsn.Duration("keep_alive"); err != nil {
+		return err
+	}
+	extension.Options = dsn.Rest()
+
+	return extension.Validate()
+}
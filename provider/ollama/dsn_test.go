@@ -0,0 +1,68 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/tech1024/goai"
+	"github.com/tech1024/goai/embedding"
+	"github.com/tech1024/goai/goaitest"
+	"github.com/tech1024/goai/prompt"
+	"github.com/tech1024/goai/provider/ollama/ollamatest"
+)
+
+func TestOpenChatModel(t *testing.T) {
+	fake := goaitest.NewChatModel(goaitest.Text("hello"))
+	ts := ollamatest.NewServer(fake, goaitest.NewEmbeddingModel(8))
+	defer ts.Close()
+
+	host := strings.TrimPrefix(ts.URL, "http://")
+	chatModel, err := goai.OpenChatModel("ollama://" + host + "/llama3.1:8b?num_ctx=8192&temperature=0.2&keep_alive=5m&think=false&format=json")
+	if err != nil {
+		t.Fatalf("OpenChatModel() error = %v", err)
+	}
+
+	resp, err := chatModel.Call(context.Background(), prompt.NewPrompt(prompt.UserMessage("hi")))
+	if err != nil || resp.Text() != "hello" {
+		t.Fatalf("Call() got = %+v, error = %v", resp, err)
+	}
+
+	var req map[string]any
+	_ = json.Unmarshal(ts.Requests()[0].Body, &req)
+	options, _ := req["options"].(map[string]any)
+	if req["model"] != "llama3.1:8b" || req["keep_alive"] != "5m0s" || req["think"] != false || req["format"] != "json" ||
+		options["num_ctx"] != 8192.0 || options["temperature"] != 0.2 {
+		t.Errorf("Call() request = %s", ts.Requests()[0].Body)
+	}
+
+	embeddingModel, err := goai.OpenEmbeddingModel("ollama://" + host + "/nomic-embed-text?dimensions=4&truncate=false")
+	if err != nil {
+		t.Fatalf("OpenEmbeddingModel() error = %v", err)
+	}
+	embeddings, err := embeddingModel.Call(context.Background(), embedding.NewRequest([]string{"a"}, embedding.Option{}))
+	if err != nil || len(embeddings.Embeddings[0].Embedding) != 4 {
+		t.Errorf("Call() got = %+v, error = %v", embeddings, err)
+	}
+
+	for _, dsn := range []string{"ollama://" + host, "ollama:llama3.1?format=xml", "ollama:llama3.1?keep_alive=forever"} {
+		if _, err = goai.OpenChatModel(dsn); err == nil {
+			t.Errorf("OpenChatModel(%q) error = nil", dsn)
+		}
+	}
+}
+
+func TestParseHost(t *testing.T) {
+	tests := map[string]string{
+		"":                  DefaultHost,
+		"gpu1":              "http://gpu1:11434",
+		"http://gpu1:8080":  "http://gpu1:8080",
+		"https://ollama.io": "https://ollama.io:443",
+	}
+	for host, want := range tests {
+		if got := parseHost(host); got != want {
+			t.Errorf("parseHost(%q) = %q, want %q", host, got, want)
+		}
+	}
+}
@@ -2,7 +2,12 @@ package ollama
 
 import (
 	"context"
+	"errors"
+	"math"
+	"net/http"
+	"sync/atomic"
 
+	"github.com/tech1024/goai/chat"
 	"github.com/tech1024/goai/embedding"
 )
 
@@ -16,23 +21,45 @@ func NewEmbeddingModel(client *Client, model string) *EmbeddingModel {
 type EmbeddingModel struct {
 	client *Client
 	model  string
+
+	// legacy is set once the server turned out to predate /api/embed.
+	legacy atomic.Bool
 }
 
 func (embeddingModel *EmbeddingModel) Call(ctx context.Context, request embedding.Request) (embedding.Response, error) {
-	er := EmbedRequest{
-		Model: embeddingModel.model,
-		Input: request.Inputs,
+	var embeddingResponse embedding.Response
+
+	er, err := embeddingModel.buildEmbedRequest(request)
+	if err != nil {
+		return embeddingResponse, err
+	}
+	if _, err = embeddingModel.client.require(ctx, er.Model, requireEmbedding); err != nil {
+		return embeddingResponse, err
 	}
 
-	var embeddingResponse embedding.Response
-	resp, err := embeddingModel.client.Embed(ctx, &er)
+	embeddingResponse.Model = er.Model
+
+	var embeddings [][]float32
+	if embeddingModel.legacy.Load() {
+		embeddings, err = embeddingModel.legacyEmbed(ctx, er, request.Inputs)
+	} else {
+		var resp *EmbedResponse
+		resp, err = embeddingModel.client.Embed(ctx, er)
+		if isMissingEndpoint(err) {
+			embeddingModel.legacy.Store(true)
+			embeddings, err = embeddingModel.legacyEmbed(ctx, er, request.Inputs)
+		} else if err == nil {
+			embeddings = resp.Embeddings
+			embeddingResponse.Usage = chat.Usage{PromptTokens: resp.PromptEvalCount, TotalTokens: resp.PromptEvalCount}
+		}
+	}
 
 	if err != nil {
 		return embeddingResponse, err
 	}
 
-	embeddingResponse.Embeddings = make([]embedding.Embedding, len(resp.Embeddings))
-	for i, es := range resp.Embeddings {
+	embeddingResponse.Embeddings = make([]embedding.Embedding, len(embeddings))
+	for i, es := range embeddings {
 		embeddingResponse.Embeddings[i] = embedding.Embedding{
 			Embedding: es,
 			Index:     i,
@@ -41,3 +68,77 @@ func (embeddingModel *EmbeddingModel) Call(ctx context.Context, request embeddin
 
 	return embeddingResponse, nil
 }
+
+func (embeddingModel *EmbeddingModel) buildEmbedRequest(request embedding.Request) (*EmbedRequest, error) {
+	er := EmbedRequest{
+		Model:      embeddingModel.model,
+		Input:      request.Inputs,
+		Dimensions: request.Option.Dimensions,
+	}
+	if request.Option.Model != "" {
+		er.Model = request.Option.Model
+	}
+
+	extension, err := extensionOf(request.Option.Extensions)
+	if err != nil {
+		return nil, err
+	}
+	if extension != nil {
+		er.KeepAlive = extension.keepAlive()
+		er.Truncate = extension.Truncate
+		er.Options = mergeOptions(er.Options, extension.Options)
+	}
+
+	return &er, nil
+}
+
+// legacyEmbed embeds the inputs one by one with /api/embeddings, the vectors
+// are normalized and cut to the dimensions like /api/embed does.
+func (embeddingModel *EmbeddingModel) legacyEmbed(ctx context.Context, er *EmbedRequest, inputs []string) ([][]float32, error) {
+	embeddings := make([][]float32, len(inputs))
+	for i, input := range inputs {
+		resp, err := embeddingModel.client.Embeddings(ctx, &EmbeddingRequest{
+			Model:     er.Model,
+			Prompt:    input,
+			KeepAlive: er.KeepAlive,
+			Options:   er.Options,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		values := resp.Embedding
+		if er.Dimensions > 0 && er.Dimensions < len(values) {
+			values = values[:er.Dimensions]
+		}
+		embeddings[i] = normalize(values)
+	}
+
+	return embeddings, nil
+}
+
+// isMissingEndpoint reports whether the server answered with a plain 404,
+// an unknown model is reported as an ollama error instead.
+func isMissingEndpoint(err error) bool {
+	var statusErr *StatusError
+
+	return errors.As(err, &statusErr) && statusErr.Code == http.StatusNotFound && statusErr.Message == ""
+}
+
+func normalize(values []float64) []float32 {
+	var norm float64
+	for _, v := range values {
+		norm += v * v
+	}
+	norm = math.Sqrt(norm)
+
+	vector := make([]float32, len(values))
+	for i, v := range values {
+		if norm > 0 {
+			v /= norm
+		}
+		vector[i] = float32(v)
+	}
+
+	return vector
+}
@@ -0,0 +1,79 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/tech1024/goai/embedding"
+	"github.com/tech1024/goai/goaitest"
+	"github.com/tech1024/goai/prompt"
+	"github.com/tech1024/goai/provider/ollama/ollamatest"
+)
+
+func TestEmbeddingModel(t *testing.T) {
+	ts := ollamatest.NewServer(nil, goaitest.NewEmbeddingModel(8))
+	defer ts.Close()
+
+	client, _ := NewClient(ts.URL)
+	model := NewEmbeddingModel(client, "nomic-embed-text")
+	keepAlive, truncate := time.Minute, false
+	request := embedding.NewRequest([]string{"hello", "world"}, embedding.Option{
+		Model:      "mxbai-embed-large",
+		Dimensions: 4,
+		Extensions: []prompt.Extension{&Extension{KeepAlive: &keepAlive, Truncate: &truncate}},
+	})
+
+	resp, err := model.Call(context.Background(), request)
+	if err != nil || len(resp.Embeddings) != 2 || !reflect.DeepEqual(resp.Embeddings[1].Embedding, goaitest.Vector("world", 4)) {
+		t.Fatalf("Call() got = %v, error = %v", resp, err)
+	}
+
+	var req map[string]any
+	_ = json.Unmarshal(ts.Requests()[0].Body, &req)
+	if req["model"] != "mxbai-embed-large" || req["dimensions"] != 4.0 || req["truncate"] != false || req["keep_alive"] != "1m0s" {
+		t.Errorf("Call() request = %s", ts.Requests()[0].Body)
+	}
+}
+
+func TestEmbeddingModel_Legacy(t *testing.T) {
+	ts := ollamatest.NewServer(nil, goaitest.NewEmbeddingModel(8))
+	defer ts.Close()
+	ts.Legacy = true
+
+	client, _ := NewClient(ts.URL)
+	model := NewEmbeddingModel(client, "nomic-embed-text")
+
+	for range 2 {
+		resp, err := model.Call(context.Background(), embedding.NewRequest([]string{"hello", "world"}, embedding.Option{Dimensions: 4}))
+		if err != nil || len(resp.Embeddings) != 2 {
+			t.Fatalf("Call() got = %v, error = %v", resp, err)
+		}
+
+		var norm float64
+		for _, v := range resp.Embeddings[0].Embedding {
+			norm += float64(v * v)
+		}
+		if len(resp.Embeddings[0].Embedding) != 4 || math.Abs(norm-1) > 1e-5 {
+			t.Errorf("Call() embedding = %v", resp.Embeddings[0].Embedding)
+		}
+	}
+
+	// the first call probes /api/embed once, then embeds each input.
+	var paths []string
+	for _, r := range ts.Requests() {
+		paths = append(paths, r.Path)
+	}
+	want := []string{"/api/embed", "/api/embeddings", "/api/embeddings", "/api/embeddings", "/api/embeddings"}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("Call() paths = %v, want %v", paths, want)
+	}
+
+	ts.EmbeddingModel = nil
+	if _, err := NewEmbeddingModel(client, "missing").Call(context.Background(), embedding.NewRequest([]string{"hello"}, embedding.Option{})); err == nil {
+		t.Errorf("Call() error = nil")
+	}
+}
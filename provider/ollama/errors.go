@@ -0,0 +1,83 @@
+package ollama
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/tech1024/goai/internal/wire"
+)
+
+var (
+	// ErrIncompleteStream is returned when a stream ended without its final chunk.
+	ErrIncompleteStream = errors.New("ollama: stream ended before done")
+
+	// ErrStreamIdle is returned when a stream received no chunk within the
+	// idle timeout of the client.
+	ErrStreamIdle = errors.New("ollama: stream idle timeout")
+
+	// ErrUnsupported is returned when a request uses a feature the server or
+	// the model does not support.
+	ErrUnsupported = errors.New("ollama: unsupported")
+)
+
+// StatusError is returned when the server responds with an error status, e.g.
+// a proxy answering with an html or plain text 502.
+type StatusError struct {
+	// Code the http status code.
+	Code int
+
+	// Status the http status, e.g. "404 Not Found".
+	Status string
+
+	// Message the error reported by ollama, empty if the body is no ollama error.
+	Message string
+
+	// Body the beginning of the response body.
+	Body string
+}
+
+func (e *StatusError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("http status code: %s, %s", e.Status, e.Message)
+	}
+	if e.Body != "" {
+		return fmt.Sprintf("http status code: %s, body: %s", e.Status, e.Body)
+	}
+
+	return fmt.Sprintf("http status code: %s", e.Status)
+}
+
+// StatusCode returns the http status code.
+func (e *StatusError) StatusCode() int {
+	return e.Code
+}
+
+// StreamError is returned when the server reports an error within a stream,
+// after it responded with a success status, e.g. the model runner crashed.
+type StreamError struct {
+	// Message the error reported by ollama.
+	Message string
+}
+
+func (e *StreamError) Error() string {
+	return e.Message
+}
+
+func newStatusError(httpResp *http.Response) *StatusError {
+	bts, body := wire.ReadErrorBody(httpResp)
+
+	statusErr := StatusError{
+		Code:   httpResp.StatusCode,
+		Status: httpResp.Status,
+		Body:   body,
+	}
+
+	var e errorResponse
+	if json.Unmarshal(bts, &e) == nil {
+		statusErr.Message = e.Error
+	}
+
+	return &statusErr
+}
@@ -0,0 +1,126 @@
+package ollama
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClient_StreamErrors(t *testing.T) {
+	long := strings.Repeat("a", 1024*1024)
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		want    string
+		wantErr error
+		check   func(t *testing.T, err error)
+	}{
+		{
+			name: "proxy html error",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadGateway)
+				_, _ = io.WriteString(w, "<html><body>502 Bad Gateway</body></html>")
+			},
+			check: func(t *testing.T, err error) {
+				var statusErr *StatusError
+				if !errors.As(err, &statusErr) || statusErr.StatusCode() != http.StatusBadGateway ||
+					!strings.Contains(statusErr.Body, "502 Bad Gateway") {
+					t.Errorf("ChatStreamResponse() error = %v, want StatusError", err)
+				}
+			},
+		},
+		{
+			name: "error within the stream",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = io.WriteString(w, `{"message":{"role":"assistant","content":"Hel"},"done":false}`+"\n"+`{"error":"model runner has unexpectedly stopped"}`+"\n")
+			},
+			check: func(t *testing.T, err error) {
+				var streamErr *StreamError
+				if !errors.As(err, &streamErr) || streamErr.Message != "model runner has unexpectedly stopped" {
+					t.Errorf("ChatStreamResponse() error = %v, want StreamError", err)
+				}
+			},
+		},
+		{
+			name: "ollama error status",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = io.WriteString(w, `{"error": "model not found"}`)
+			},
+			check: func(t *testing.T, err error) {
+				var statusErr *StatusError
+				if !errors.As(err, &statusErr) || statusErr.Message != "model not found" {
+					t.Errorf("ChatStreamResponse() error = %v, want StatusError", err)
+				}
+			},
+		},
+		{
+			name: "long lines",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = io.WriteString(w, `{"message":{"content":"`+long+`"}}`+"\n"+`{"done":true}`)
+			},
+			want: long,
+		},
+		{
+			name: "stream without done",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = io.WriteString(w, `{"message":{"content":"partial"}}`+"\n")
+			},
+			want:    "partial",
+			wantErr: ErrIncompleteStream,
+		},
+		{
+			name: "plain text chunk",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = io.WriteString(w, "upstream connect error\n")
+			},
+			check: func(t *testing.T, err error) {
+				if err == nil || !strings.Contains(err.Error(), "upstream connect error") {
+					t.Errorf("ChatStreamResponse() error = %v, want body snippet", err)
+				}
+			},
+		},
+		{
+			name: "idle stream",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = io.WriteString(w, `{"message":{"content":"first"}}`+"\n")
+				w.(http.Flusher).Flush()
+				select {
+				case <-r.Context().Done():
+				case <-time.After(time.Second):
+				}
+			},
+			wantErr: ErrStreamIdle,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(tt.handler)
+			defer ts.Close()
+
+			c, err := NewClient(ts.URL, WithStreamIdleTimeout(100*time.Millisecond))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			final, err := c.ChatStreamResponse(context.Background(), &ChatRequest{}, nil)
+			if tt.check != nil {
+				tt.check(t, err)
+				return
+			}
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ChatStreamResponse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if final != nil && final.Message.Content != tt.want {
+				t.Errorf("ChatStreamResponse() got = %d bytes, want %d bytes", len(final.Message.Content), len(tt.want))
+			}
+		})
+	}
+}
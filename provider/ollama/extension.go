@@ -0,0 +1,115 @@
+package ollama
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/tech1024/goai/prompt"
+)
+
+// ProviderName the name of the ollama provider.
+const ProviderName = "ollama"
+
+// KeepAliveForever keeps the model loaded until the server stops.
+const KeepAliveForever time.Duration = -1
+
+// FormatJSON requests the response as any valid JSON.
+var FormatJSON = json.RawMessage(`"json"`)
+
+// Extension the ollama specific options of a prompt, attach it to
+// prompt.Option.Extensions, completion.Option.Extensions or
+// embedding.Option.Extensions.
+type Extension struct {
+	// KeepAlive how long the model stays loaded after the request, a negative
+	// duration keeps it loaded forever and zero unloads it immediately.
+	KeepAlive *time.Duration
+
+	// Format FormatJSON or the JSON schema the response must conform to.
+	Format json.RawMessage
+
+	// Think enables or disables the thinking of thinking models.
+	Think *bool
+
+	// Truncate truncates embedding inputs exceeding the context length instead
+	// of failing, the server truncates by default.
+	Truncate *bool
+
+	// Options the raw model options, e.g. "temperature" or "num_ctx".
+	Options map[string]interface{}
+}
+
+func (e *Extension) Provider() string {
+	return ProviderName
+}
+
+// Validate reports whether the options can be sent to ollama.
+func (e *Extension) Validate() error {
+	if len(e.Format) > 0 {
+		var format any
+		if err := json.Unmarshal(e.Format, &format); err != nil {
+			return fmt.Errorf("ollama format: %w", err)
+		}
+
+		switch f := format.(type) {
+		case string:
+			if f != "json" {
+				return fmt.Errorf("ollama format: unknown format %q", f)
+			}
+		case map[string]any:
+		default:
+			return errors.New("ollama format: must be \"json\" or a JSON schema object")
+		}
+	}
+
+	for key := range e.Options {
+		if key == "" {
+			return errors.New("ollama options: empty option name")
+		}
+	}
+
+	return nil
+}
+
+// keepAlive returns the keep-alive duration of the request.
+func (e *Extension) keepAlive() *Duration {
+	if e.KeepAlive == nil {
+		return nil
+	}
+
+	return &Duration{Duration: *e.KeepAlive}
+}
+
+// extensionOf returns the ollama extension of the options, nil if there is none.
+func extensionOf(extensions []prompt.Extension) (*Extension, error) {
+	for _, extension := range extensions {
+		if extension.Provider() != ProviderName {
+			continue
+		}
+
+		e, ok := extension.(*Extension)
+		if !ok {
+			return nil, fmt.Errorf("ollama: unsupported extension %T", extension)
+		}
+
+		return e, e.Validate()
+	}
+
+	return nil, nil
+}
+
+// mergeOptions returns the model options with the extra ones, which take precedence.
+func mergeOptions(options, extra map[string]interface{}) map[string]interface{} {
+	if len(extra) == 0 {
+		return options
+	}
+	if options == nil {
+		options = make(map[string]interface{}, len(extra))
+	}
+	for key, value := range extra {
+		options[key] = value
+	}
+
+	return options
+}
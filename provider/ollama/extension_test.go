@@ -0,0 +1,94 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/tech1024/goai/chat"
+	"github.com/tech1024/goai/prompt"
+)
+
+func TestDuration_JSON(t *testing.T) {
+	tests := []struct {
+		duration time.Duration
+		json     string
+	}{
+		{duration: 5 * time.Minute, json: `"5m0s"`},
+		{duration: 0, json: `"0s"`},
+		{duration: KeepAliveForever, json: `-1`},
+		{duration: -time.Hour, json: `-1`},
+	}
+
+	for _, tt := range tests {
+		got, err := json.Marshal(Duration{Duration: tt.duration})
+		if err != nil || string(got) != tt.json {
+			t.Errorf("MarshalJSON(%v) got = %s, want %s", tt.duration, got, tt.json)
+		}
+	}
+
+	for input, want := range map[string]time.Duration{`"10m"`: 10 * time.Minute, `"-1s"`: -1, `-1`: -1, `30`: 30 * time.Second} {
+		var d Duration
+		if err := json.Unmarshal([]byte(input), &d); err != nil || d.Duration != want {
+			t.Errorf("UnmarshalJSON(%s) got = %v, want %v, error = %v", input, d.Duration, want, err)
+		}
+	}
+
+	var d Duration
+	if err := json.Unmarshal([]byte(`"forever"`), &d); err == nil {
+		t.Errorf("UnmarshalJSON() error = nil")
+	}
+}
+
+func TestChatModel_Extension(t *testing.T) {
+	var body []byte
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		_, _ = io.WriteString(w, `{"model":"qwen3","message":{"role":"assistant","content":"{\"answer\":4}","thinking":"2+2=4"},"done":true}`)
+	}))
+	defer ts.Close()
+
+	client, _ := NewClient(ts.URL)
+	model := NewNewChatModel(client, "qwen3")
+	keepAlive, think := KeepAliveForever, true
+	p := prompt.NewPrompt(prompt.UserMessage("2+2?"))
+	p.ChatOption.Extensions = []prompt.Extension{&Extension{
+		KeepAlive: &keepAlive,
+		Format:    json.RawMessage(`{"type":"object","properties":{"answer":{"type":"integer"}}}`),
+		Think:     &think,
+		Options:   map[string]interface{}{"temperature": 0, "num_ctx": 8192},
+	}}
+
+	resp, err := model.Call(context.Background(), p)
+	if err != nil || resp.Text() != `{"answer":4}` || resp.Generations[0].Thinking != "2+2=4" {
+		t.Fatalf("Call() got = %v, error = %v", resp, err)
+	}
+
+	var req map[string]any
+	_ = json.Unmarshal(body, &req)
+	options, _ := req["options"].(map[string]any)
+	format, _ := req["format"].(map[string]any)
+	if req["keep_alive"] != -1.0 || req["think"] != true || format["type"] != "object" || options["num_ctx"] != 8192.0 {
+		t.Errorf("Call() request = %s", body)
+	}
+
+	for _, format := range []string{`"yaml"`, `[1]`, `{`} {
+		p.ChatOption.Extensions = []prompt.Extension{&Extension{Format: json.RawMessage(format)}}
+		if _, err = model.Call(context.Background(), p); err == nil {
+			t.Errorf("Call() with format %s error = nil", format)
+		}
+	}
+
+	streamed := &chat.Response{}
+	p.ChatOption.Extensions = []prompt.Extension{&Extension{Format: FormatJSON}}
+	if err = model.Stream(context.Background(), p, func(chunk *chat.Response) error {
+		streamed.Append(chunk)
+		return nil
+	}); err != nil || streamed.Generations[0].Thinking != "2+2=4" {
+		t.Errorf("Stream() got = %v, error = %v", streamed, err)
+	}
+}
@@ -0,0 +1,265 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Model management part
+
+// ModelDetails provides details about a model.
+type ModelDetails struct {
+	ParentModel       string   `json:"parent_model"`
+	Format            string   `json:"format"`
+	Family            string   `json:"family"`
+	Families          []string `json:"families"`
+	ParameterSize     string   `json:"parameter_size"`
+	QuantizationLevel string   `json:"quantization_level"`
+}
+
+// ListModelResponse is a single model description in [ListResponse].
+type ListModelResponse struct {
+	Name       string       `json:"name"`
+	Model      string       `json:"model"`
+	ModifiedAt time.Time    `json:"modified_at"`
+	Size       int64        `json:"size"`
+	Digest     string       `json:"digest"`
+	Details    ModelDetails `json:"details,omitempty"`
+}
+
+// ListResponse is the response from [Client.List].
+type ListResponse struct {
+	Models []ListModelResponse `json:"models"`
+}
+
+// List lists the models available locally.
+func (c *Client) List(ctx context.Context) (*ListResponse, error) {
+	var resp ListResponse
+	if err := c.Get(ctx, "/api/tags", &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// ProcessModelResponse is a single model description in [ProcessResponse].
+type ProcessModelResponse struct {
+	Name      string       `json:"name"`
+	Model     string       `json:"model"`
+	Size      int64        `json:"size"`
+	Digest    string       `json:"digest"`
+	Details   ModelDetails `json:"details,omitempty"`
+	ExpiresAt time.Time    `json:"expires_at"`
+	SizeVRAM  int64        `json:"size_vram"`
+}
+
+// ProcessResponse is the response from [Client.ListRunning].
+type ProcessResponse struct {
+	Models []ProcessModelResponse `json:"models"`
+}
+
+// ListRunning lists the models currently loaded into memory.
+func (c *Client) ListRunning(ctx context.Context) (*ProcessResponse, error) {
+	var resp ProcessResponse
+	if err := c.Get(ctx, "/api/ps", &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// ShowRequest is the request passed to [Client.Show].
+type ShowRequest struct {
+	Model  string `json:"model"`
+	System string `json:"system,omitempty"`
+
+	// Verbose returns the full data of the tokenizer in ModelInfo.
+	Verbose bool `json:"verbose,omitempty"`
+
+	Options map[string]interface{} `json:"options,omitempty"`
+}
+
+// ShowResponse is the response from [Client.Show].
+type ShowResponse struct {
+	License       string         `json:"license,omitempty"`
+	Modelfile     string         `json:"modelfile,omitempty"`
+	Parameters    string         `json:"parameters,omitempty"`
+	Template      string         `json:"template,omitempty"`
+	System        string         `json:"system,omitempty"`
+	Details       ModelDetails   `json:"details,omitempty"`
+	Messages      []Message      `json:"messages,omitempty"`
+	ModelInfo     map[string]any `json:"model_info,omitempty"`
+	ProjectorInfo map[string]any `json:"projector_info,omitempty"`
+	Capabilities  []string       `json:"capabilities,omitempty"`
+	ModifiedAt    time.Time      `json:"modified_at"`
+}
+
+// Show returns the details of a model.
+func (c *Client) Show(ctx context.Context, req *ShowRequest) (*ShowResponse, error) {
+	var resp ShowResponse
+	if err := c.Post(ctx, "/api/show", req, &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// ProgressResponse is the progress of [Client.Pull], [Client.Push] and [Client.Create].
+type ProgressResponse struct {
+	Status    string `json:"status"`
+	Digest    string `json:"digest,omitempty"`
+	Total     int64  `json:"total,omitempty"`
+	Completed int64  `json:"completed,omitempty"`
+}
+
+// ProgressFunc receives the progress of a long-running operation.
+type ProgressFunc func(ProgressResponse) error
+
+// PullRequest is the request passed to [Client.Pull].
+type PullRequest struct {
+	Model string `json:"model"`
+
+	// Insecure allows insecure connections to the registry.
+	Insecure bool `json:"insecure,omitempty"`
+
+	// Stream reports the progress, always enabled by [Client.Pull].
+	Stream *bool `json:"stream,omitempty"`
+}
+
+// Pull downloads a model from the registry, fn receives its progress.
+func (c *Client) Pull(ctx context.Context, req *PullRequest, fn ProgressFunc) error {
+	req.Stream = ptr(true)
+	c.forget(req.Model)
+	return c.streamProgress(ctx, "/api/pull", req, fn)
+}
+
+// PushRequest is the request passed to [Client.Push].
+type PushRequest struct {
+	Model string `json:"model"`
+
+	// Insecure allows insecure connections to the registry.
+	Insecure bool `json:"insecure,omitempty"`
+
+	// Stream reports the progress, always enabled by [Client.Push].
+	Stream *bool `json:"stream,omitempty"`
+}
+
+// Push uploads a model to the registry, fn receives its progress.
+func (c *Client) Push(ctx context.Context, req *PushRequest, fn ProgressFunc) error {
+	req.Stream = ptr(true)
+	return c.streamProgress(ctx, "/api/push", req, fn)
+}
+
+// CreateRequest is the request passed to [Client.Create].
+type CreateRequest struct {
+	// Model is the name of the model to create.
+	Model string `json:"model"`
+
+	// From is the name of an existing model to create the model from.
+	From string `json:"from,omitempty"`
+
+	// Files maps file names to the digests of blobs uploaded by [Client.CreateBlob].
+	Files map[string]string `json:"files,omitempty"`
+
+	// Adapters maps file names to the digests of LoRA adapter blobs.
+	Adapters map[string]string `json:"adapters,omitempty"`
+
+	Template   string                 `json:"template,omitempty"`
+	License    any                    `json:"license,omitempty"`
+	System     string                 `json:"system,omitempty"`
+	Parameters map[string]interface{} `json:"parameters,omitempty"`
+	Messages   []Message              `json:"messages,omitempty"`
+
+	// Quantize quantizes a non-quantized model, e.g. "q4_K_M".
+	Quantize string `json:"quantize,omitempty"`
+
+	// Modelfile is the content of a Modelfile, for servers older than 0.5.5.
+	Modelfile string `json:"modelfile,omitempty"`
+
+	// Stream reports the progress, always enabled by [Client.Create].
+	Stream *bool `json:"stream,omitempty"`
+}
+
+// Create creates a model, fn receives its progress.
+func (c *Client) Create(ctx context.Context, req *CreateRequest, fn ProgressFunc) error {
+	req.Stream = ptr(true)
+	c.forget(req.Model)
+	return c.streamProgress(ctx, "/api/create", req, fn)
+}
+
+// CopyRequest is the request passed to [Client.Copy].
+type CopyRequest struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+}
+
+// Copy creates a model with another name from an existing model.
+func (c *Client) Copy(ctx context.Context, req *CopyRequest) error {
+	c.forget(req.Destination)
+	return c.Post(ctx, "/api/copy", req, nil)
+}
+
+// DeleteRequest is the request passed to [Client.Delete].
+type DeleteRequest struct {
+	Model string `json:"model"`
+}
+
+// Delete deletes a model and its data.
+func (c *Client) Delete(ctx context.Context, req *DeleteRequest) error {
+	c.forget(req.Model)
+	return c.send(ctx, http.MethodDelete, "/api/delete", req, nil)
+}
+
+// HasBlob reports whether the blob of the digest, e.g. "sha256:...", exists on the server.
+func (c *Client) HasBlob(ctx context.Context, digest string) (bool, error) {
+	httpResp, err := c.do(ctx, http.MethodHead, "/api/blobs/"+digest, nil)
+	if err != nil {
+		return false, err
+	}
+	defer httpResp.Body.Close()
+
+	switch httpResp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	}
+
+	return false, newStatusError(httpResp)
+}
+
+// CreateBlob uploads the content of r as the blob of the digest.
+func (c *Client) CreateBlob(ctx context.Context, digest string, r io.Reader) error {
+	return c.send(ctx, http.MethodPost, "/api/blobs/"+digest, r, nil)
+}
+
+// Version returns the version of the ollama server.
+func (c *Client) Version(ctx context.Context) (string, error) {
+	var resp struct {
+		Version string `json:"version"`
+	}
+	if err := c.Get(ctx, "/api/version", &resp); err != nil {
+		return "", err
+	}
+
+	return resp.Version, nil
+}
+
+func (c *Client) streamProgress(ctx context.Context, path string, req any, fn ProgressFunc) error {
+	return c.stream(ctx, http.MethodPost, path, req, func(bts []byte) error {
+		var resp ProgressResponse
+		if err := json.Unmarshal(bts, &resp); err != nil {
+			return fmt.Errorf("unmarshal: %w", err)
+		}
+
+		if fn == nil {
+			return nil
+		}
+
+		return fn(resp)
+	})
+}
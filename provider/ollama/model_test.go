@@ -0,0 +1,111 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tech1024/goai/provider/ollama/ollamatest"
+)
+
+func TestClient_ModelManagement(t *testing.T) {
+	ts := ollamatest.NewServer(nil, nil)
+	defer ts.Close()
+	ts.Models = []ollamatest.Model{{Name: "llama3:latest", Model: "llama3:latest", Size: 42, Capabilities: []string{"completion", "tools"}, ContextLength: 8192}}
+	ts.Running = []ollamatest.Model{{Name: "llama3:latest", Model: "llama3:latest", SizeVRAM: 21}}
+	ts.HandleFunc("POST /api/pull", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "{\"status\":\"pulling manifest\"}\n{\"status\":\"downloading\",\"digest\":\"sha256:1\",\"total\":10,\"completed\":5}\n{\"status\":\"success\"}\n")
+	})
+	ts.HandleFunc("POST /api/push", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = io.WriteString(w, `{"error": "unauthorized"}`)
+	})
+	ts.HandleFunc("POST /api/copy", func(w http.ResponseWriter, r *http.Request) {})
+	ts.HandleFunc("DELETE /api/delete", func(w http.ResponseWriter, r *http.Request) {})
+	ts.HandleFunc("HEAD /api/blobs/{digest}", func(w http.ResponseWriter, r *http.Request) {
+		switch r.PathValue("digest") {
+		case "sha256:exists":
+		case "sha256:invalid":
+			w.WriteHeader(http.StatusBadRequest)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	ts.HandleFunc("POST /api/blobs/{digest}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	c, err := NewClient(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	if list, err := c.List(ctx); err != nil || len(list.Models) != 1 || list.Models[0].Size != 42 {
+		t.Errorf("List() got = %v, error = %v", list, err)
+	}
+
+	if ps, err := c.ListRunning(ctx); err != nil || len(ps.Models) != 1 || ps.Models[0].SizeVRAM != 21 {
+		t.Errorf("ListRunning() got = %v, error = %v", ps, err)
+	}
+
+	show, err := c.Show(ctx, &ShowRequest{Model: "llama3:latest"})
+	if err != nil || !reflect.DeepEqual(show.Capabilities, []string{"completion", "tools"}) || show.ModelInfo["goaitest.context_length"] != 8192.0 {
+		t.Errorf("Show() got = %v, error = %v", show, err)
+	}
+
+	var statuses []string
+	err = c.Pull(ctx, &PullRequest{Model: "llama3"}, func(progress ProgressResponse) error {
+		statuses = append(statuses, progress.Status)
+		return nil
+	})
+	if err != nil || strings.Join(statuses, "|") != "pulling manifest|downloading|success" {
+		t.Errorf("Pull() got = %v, error = %v", statuses, err)
+	}
+
+	if err = c.Push(ctx, &PushRequest{Model: "llama3"}, nil); err == nil || !strings.Contains(err.Error(), "unauthorized") {
+		t.Errorf("Push() error = %v", err)
+	}
+
+	if err = c.Copy(ctx, &CopyRequest{Source: "llama3", Destination: "llama3-copy"}); err != nil {
+		t.Errorf("Copy() error = %v", err)
+	}
+
+	if err = c.Delete(ctx, &DeleteRequest{Model: "llama3-copy"}); err != nil {
+		t.Errorf("Delete() error = %v", err)
+	}
+
+	if ok, err := c.HasBlob(ctx, "sha256:exists"); err != nil || !ok {
+		t.Errorf("HasBlob() got = %v, error = %v", ok, err)
+	}
+	if ok, err := c.HasBlob(ctx, "sha256:missing"); err != nil || ok {
+		t.Errorf("HasBlob() got = %v, error = %v", ok, err)
+	}
+	var statusErr *StatusError
+	if _, err := c.HasBlob(ctx, "sha256:invalid"); !errors.As(err, &statusErr) || statusErr.StatusCode() != http.StatusBadRequest {
+		t.Errorf("HasBlob() error = %v, want StatusError", err)
+	}
+
+	if err = c.CreateBlob(ctx, "sha256:new", strings.NewReader("data")); err != nil {
+		t.Errorf("CreateBlob() error = %v", err)
+	}
+
+	if version, err := c.Version(ctx); err != nil || version != "0.6.0" {
+		t.Errorf("Version() got = %v, error = %v", version, err)
+	}
+
+	var pull map[string]any
+	requests := ts.Requests()
+	_ = json.Unmarshal(requests[3].Body, &pull)
+	if requests[3].Path != "/api/pull" || pull["stream"] != true {
+		t.Errorf("Pull() request = %s %s", requests[3].Path, requests[3].Body)
+	}
+	if requests[6].Method != http.MethodDelete {
+		t.Errorf("Delete() method = %s", requests[6].Method)
+	}
+}
@@ -0,0 +1,378 @@
+// Package ollamatest provides an in-process fake Ollama server, whose chat
+// and embedding endpoints are answered by goai models, e.g. the scripted
+// fakes of the goaitest package.
+package ollamatest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/tech1024/goai"
+	"github.com/tech1024/goai/chat"
+	"github.com/tech1024/goai/embedding"
+	"github.com/tech1024/goai/goaitest"
+	"github.com/tech1024/goai/prompt"
+)
+
+// Model a model listed by /api/tags, or by /api/ps if it has an expiry.
+// /api/show describes the models of /api/tags.
+type Model struct {
+	Name       string    `json:"name"`
+	Model      string    `json:"model"`
+	ModifiedAt time.Time `json:"modified_at"`
+	Size       int64     `json:"size"`
+	Digest     string    `json:"digest"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	SizeVRAM   int64     `json:"size_vram,omitempty"`
+
+	// Capabilities the capabilities reported by /api/show, e.g. "completion" or "tools".
+	Capabilities []string `json:"-"`
+
+	// ContextLength the context length reported by /api/show.
+	ContextLength int `json:"-"`
+}
+
+type message struct {
+	Role      string     `json:"role"`
+	Content   string     `json:"content"`
+	Images    [][]byte   `json:"images,omitempty"`
+	ToolCalls []toolCall `json:"tool_calls,omitempty"`
+	ToolName  string     `json:"tool_name,omitempty"`
+}
+
+type toolCall struct {
+	Function struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	} `json:"function"`
+}
+
+type chatRequest struct {
+	Model    string    `json:"model"`
+	Messages []message `json:"messages"`
+	Stream   *bool     `json:"stream"`
+	Tools    []struct {
+		Function struct {
+			Name        string          `json:"name"`
+			Description string          `json:"description"`
+			Parameters  json.RawMessage `json:"parameters"`
+		} `json:"function"`
+	} `json:"tools"`
+}
+
+type chatResponse struct {
+	Model              string    `json:"model"`
+	CreatedAt          time.Time `json:"created_at"`
+	Message            message   `json:"message"`
+	Done               bool      `json:"done"`
+	DoneReason         string    `json:"done_reason,omitempty"`
+	TotalDuration      int64     `json:"total_duration,omitempty"`
+	PromptEvalCount    int       `json:"prompt_eval_count,omitempty"`
+	PromptEvalDuration int64     `json:"prompt_eval_duration,omitempty"`
+	EvalCount          int       `json:"eval_count,omitempty"`
+	EvalDuration       int64     `json:"eval_duration,omitempty"`
+}
+
+type embedRequest struct {
+	Model      string `json:"model"`
+	Input      any    `json:"input"`
+	Prompt     string `json:"prompt"`
+	Dimensions int    `json:"dimensions"`
+}
+
+// Server a fake Ollama server.
+type Server struct {
+	*goaitest.Server
+
+	// ChatModel answers /api/chat.
+	ChatModel goai.ChatModel
+
+	// EmbeddingModel answers /api/embed.
+	EmbeddingModel goai.EmbeddingModel
+
+	// Models the models listed by /api/tags.
+	Models []Model
+
+	// Running the models listed by /api/ps.
+	Running []Model
+
+	// Version the version reported by /api/version.
+	Version string
+
+	// Legacy answers /api/embed with a plain 404 like servers older than
+	// 0.3.0, which only provide /api/embeddings.
+	Legacy bool
+}
+
+// NewServer starts a fake Ollama server answering with the models, either may be nil.
+func NewServer(chatModel goai.ChatModel, embeddingModel goai.EmbeddingModel) *Server {
+	s := &Server{
+		Server:         goaitest.NewServer(),
+		ChatModel:      chatModel,
+		EmbeddingModel: embeddingModel,
+		Version:        "0.6.0",
+	}
+
+	s.HandleFunc("POST /api/chat", s.handleChat)
+	s.HandleFunc("POST /api/embed", s.handleEmbed)
+	s.HandleFunc("POST /api/embeddings", s.handleEmbeddings)
+	s.HandleFunc("GET /api/tags", s.handleTags)
+	s.HandleFunc("GET /api/ps", s.handlePs)
+	s.HandleFunc("POST /api/show", s.handleShow)
+	s.HandleFunc("GET /api/version", s.handleVersion)
+
+	return s
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	goaitest.WriteJSON(w, goaitest.HTTPStatus(err), map[string]string{"error": err.Error()})
+}
+
+func (s *Server) handleChat(w http.ResponseWriter, r *http.Request) {
+	var req chatRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, &goaitest.StatusError{Code: http.StatusBadRequest, Message: err.Error()})
+		return
+	}
+	if s.ChatModel == nil {
+		writeError(w, &goaitest.StatusError{Code: http.StatusNotFound, Message: "model '" + req.Model + "' not found"})
+		return
+	}
+
+	p := toPrompt(req)
+	if req.Stream != nil && !*req.Stream {
+		resp, err := s.ChatModel.Call(r.Context(), p)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		final := toChatResponse(req.Model, resp)
+		final.Done = true
+		final.DoneReason = "stop"
+		goaitest.WriteJSON(w, http.StatusOK, final)
+		return
+	}
+
+	s.streamChat(r.Context(), w, req, p)
+}
+
+func (s *Server) streamChat(ctx context.Context, w http.ResponseWriter, req chatRequest, p prompt.Prompt) {
+	encoder := json.NewEncoder(w)
+	flusher, _ := w.(http.Flusher)
+	var started bool
+	var usage chat.Usage
+
+	err := s.ChatModel.Stream(ctx, p, func(chunk *chat.Response) error {
+		if !chunk.Usage.IsZero() {
+			usage = chunk.Usage
+		}
+		if chunk.Text() == "" && len(chunk.ToolCalls()) == 0 {
+			return nil
+		}
+
+		if !started {
+			started = true
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			w.WriteHeader(http.StatusOK)
+		}
+		if err := encoder.Encode(toChatResponse(req.Model, chunk)); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		if !started {
+			writeError(w, err)
+			return
+		}
+		_ = encoder.Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	final := toChatResponse(req.Model, &chat.Response{Usage: usage})
+	final.Message.Role = "assistant"
+	final.Done = true
+	final.DoneReason = "stop"
+	if !started {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	}
+	_ = encoder.Encode(final)
+}
+
+func (s *Server) handleEmbed(w http.ResponseWriter, r *http.Request) {
+	if s.Legacy {
+		http.NotFound(w, r)
+		return
+	}
+
+	var req embedRequest
+	if !s.decodeEmbed(w, r, &req) {
+		return
+	}
+
+	var inputs []string
+	switch input := req.Input.(type) {
+	case string:
+		inputs = []string{input}
+	case []any:
+		for _, v := range input {
+			text, _ := v.(string)
+			inputs = append(inputs, text)
+		}
+	}
+
+	resp, err := s.EmbeddingModel.Call(r.Context(), embedding.NewRequest(inputs, embedding.Option{Model: req.Model, Dimensions: req.Dimensions}))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	goaitest.WriteJSON(w, http.StatusOK, map[string]any{
+		"model":      req.Model,
+		"embeddings": resp.List(),
+	})
+}
+
+// handleEmbeddings answers the legacy endpoint, which embeds a single prompt
+// into an unnormalized float64 vector.
+func (s *Server) handleEmbeddings(w http.ResponseWriter, r *http.Request) {
+	var req embedRequest
+	if !s.decodeEmbed(w, r, &req) {
+		return
+	}
+
+	resp, err := s.EmbeddingModel.Call(r.Context(), embedding.NewRequest([]string{req.Prompt}, embedding.Option{Model: req.Model}))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	values := make([]float64, len(resp.Embeddings[0].Embedding))
+	for i, v := range resp.Embeddings[0].Embedding {
+		values[i] = float64(v) * 2
+	}
+
+	goaitest.WriteJSON(w, http.StatusOK, map[string]any{"embedding": values})
+}
+
+func (s *Server) decodeEmbed(w http.ResponseWriter, r *http.Request, req *embedRequest) bool {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		writeError(w, &goaitest.StatusError{Code: http.StatusBadRequest, Message: err.Error()})
+		return false
+	}
+	if s.EmbeddingModel == nil {
+		writeError(w, &goaitest.StatusError{Code: http.StatusNotFound, Message: "model '" + req.Model + "' not found"})
+		return false
+	}
+
+	return true
+}
+
+func (s *Server) handleTags(w http.ResponseWriter, _ *http.Request) {
+	models := s.Models
+	if models == nil {
+		models = []Model{}
+	}
+
+	goaitest.WriteJSON(w, http.StatusOK, map[string]any{"models": models})
+}
+
+func (s *Server) handlePs(w http.ResponseWriter, _ *http.Request) {
+	models := s.Running
+	if models == nil {
+		models = []Model{}
+	}
+
+	goaitest.WriteJSON(w, http.StatusOK, map[string]any{"models": models})
+}
+
+func (s *Server) handleShow(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Model string `json:"model"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, &goaitest.StatusError{Code: http.StatusBadRequest, Message: err.Error()})
+		return
+	}
+
+	for _, m := range s.Models {
+		if m.Name != req.Model {
+			continue
+		}
+
+		goaitest.WriteJSON(w, http.StatusOK, map[string]any{
+			"capabilities": m.Capabilities,
+			"model_info": map[string]any{
+				"general.architecture":    "goaitest",
+				"goaitest.context_length": m.ContextLength,
+			},
+			"modified_at": m.ModifiedAt,
+		})
+		return
+	}
+
+	writeError(w, &goaitest.StatusError{Code: http.StatusNotFound, Message: "model '" + req.Model + "' not found"})
+}
+
+func (s *Server) handleVersion(w http.ResponseWriter, _ *http.Request) {
+	goaitest.WriteJSON(w, http.StatusOK, map[string]string{"version": s.Version})
+}
+
+func toPrompt(req chatRequest) prompt.Prompt {
+	p := prompt.Prompt{ChatOption: prompt.Option{Model: req.Model}}
+	for _, tool := range req.Tools {
+		p.ChatOption.Tools = append(p.ChatOption.Tools, prompt.Tool{
+			Name:        tool.Function.Name,
+			Description: tool.Function.Description,
+			Parameters:  tool.Function.Parameters,
+		})
+	}
+
+	for _, m := range req.Messages {
+		switch {
+		case len(m.ToolCalls) > 0:
+			var toolCalls []chat.ToolCall
+			for _, tc := range m.ToolCalls {
+				toolCalls = append(toolCalls, chat.ToolCall{Name: tc.Function.Name, Arguments: string(tc.Function.Arguments)})
+			}
+			p.Messages = append(p.Messages, prompt.AssistantToolCallMessage(m.Content, toolCalls...))
+		case m.Role == prompt.MessageTypeTool.String():
+			p.Messages = append(p.Messages, prompt.ToolResultMessage(chat.ToolCall{Name: m.ToolName}, m.Content))
+		default:
+			p.Messages = append(p.Messages, prompt.NewMessage(prompt.MessageType(m.Role), m.Content))
+		}
+	}
+
+	return p
+}
+
+func toChatResponse(model string, resp *chat.Response) chatResponse {
+	cr := chatResponse{
+		Model:              model,
+		CreatedAt:          time.Now().UTC(),
+		Message:            message{Role: "assistant", Content: resp.Text()},
+		PromptEvalCount:    resp.Usage.PromptTokens,
+		PromptEvalDuration: int64(resp.Timing.PromptDuration),
+		EvalCount:          resp.Usage.CompletionTokens,
+		EvalDuration:       int64(resp.Timing.CompletionDuration),
+	}
+	for _, tc := range resp.ToolCalls() {
+		var call toolCall
+		call.Function.Name = tc.Name
+		call.Function.Arguments = json.RawMessage(tc.Arguments)
+		if tc.Arguments == "" {
+			call.Function.Arguments = json.RawMessage("{}")
+		}
+		cr.Message.ToolCalls = append(cr.Message.ToolCalls, call)
+	}
+
+	return cr
+}
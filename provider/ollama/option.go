@@ -0,0 +1,123 @@
+package ollama
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultHost the address of a local ollama server.
+	DefaultHost = "http://127.0.0.1:11434"
+
+	defaultPort = "11434"
+)
+
+var defaultUserAgent = fmt.Sprintf("GoAI (%s %s) Go/%s", runtime.GOARCH, runtime.GOOS, runtime.Version())
+
+// ClientOption configures a Client.
+type ClientOption func(*Client)
+
+// WithHTTPClient sets the http client sending the requests, defaults to http.DefaultClient.
+func WithHTTPClient(httpClient *http.Client) ClientOption {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithTransport sets the transport of the http client, e.g. a recording one in tests.
+func WithTransport(transport http.RoundTripper) ClientOption {
+	return func(c *Client) {
+		c.transport = transport
+	}
+}
+
+// WithTimeout sets the time limit of a request including reading its
+// response, which also covers streamed responses.
+func WithTimeout(timeout time.Duration) ClientOption {
+	return func(c *Client) {
+		c.timeout = timeout
+	}
+}
+
+// WithHeader adds a header sent with every request.
+func WithHeader(key, value string) ClientOption {
+	return func(c *Client) {
+		c.header.Add(key, value)
+	}
+}
+
+// WithBearerToken authenticates the requests with the token, e.g. for an
+// ollama server behind an auth proxy.
+func WithBearerToken(token string) ClientOption {
+	return func(c *Client) {
+		c.header.Set("Authorization", "Bearer "+token)
+	}
+}
+
+// WithUserAgent sets the User-Agent header of the requests.
+func WithUserAgent(userAgent string) ClientOption {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// hostFromEnv returns the base url configured by OLLAMA_HOST.
+func hostFromEnv() string {
+	return parseHost(os.Getenv("OLLAMA_HOST"))
+}
+
+// parseHost returns the base url of a host, which may omit the scheme or the
+// port like the ollama cli allows.
+func parseHost(host string) string {
+	host = strings.TrimSpace(host)
+	if host == "" {
+		return DefaultHost
+	}
+
+	scheme := "http"
+	if s, rest, ok := strings.Cut(host, "://"); ok {
+		scheme, host = s, rest
+	}
+
+	path := ""
+	if i := strings.Index(host, "/"); i >= 0 {
+		host, path = host[:i], host[i:]
+	}
+
+	hostname, port, err := net.SplitHostPort(host)
+	if err != nil {
+		hostname, port = host, defaultPort
+		if scheme == "https" {
+			port = "443"
+		}
+	}
+	if hostname == "" {
+		hostname = "127.0.0.1"
+	}
+
+	return scheme + "://" + net.JoinHostPort(hostname, port) + path
+}
+
+// WithStreamIdleTimeout fails a stream with ErrStreamIdle once no chunk arrived
+// within the timeout, which includes the wait for the first chunk while the
+// model is loaded.
+func WithStreamIdleTimeout(timeout time.Duration) ClientOption {
+	return func(c *Client) {
+		c.streamIdleTimeout = timeout
+	}
+}
+
+// WithCapabilityCheck checks the server version and the model capabilities
+// before each request of the goai models, so that e.g. a prompt with tools
+// fails with ErrUnsupported for a model without tool support. Both are
+// queried once and cached by the client.
+func WithCapabilityCheck() ClientOption {
+	return func(c *Client) {
+		c.checkCapabilities = true
+	}
+}
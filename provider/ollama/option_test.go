@@ -0,0 +1,69 @@
+package ollama
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/tech1024/goai/goaitest"
+	"github.com/tech1024/goai/provider/ollama/ollamatest"
+)
+
+func TestNewClient_Options(t *testing.T) {
+	ts := ollamatest.NewServer(goaitest.NewChatModel(goaitest.Text("ok").WithLatency(100*time.Millisecond)), nil)
+	defer ts.Close()
+
+	c, err := NewClient(ts.URL,
+		WithHTTPClient(ts.Client()),
+		WithBearerToken("secret"),
+		WithHeader("X-Tenant", "a"),
+		WithUserAgent("test-agent"),
+		WithTimeout(time.Millisecond),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = c.Chat(context.Background(), &ChatRequest{Model: "test-model"}); err == nil {
+		t.Errorf("Chat() error = nil, want timeout")
+	}
+
+	header := ts.Requests()[0].Header
+	for key, want := range map[string]string{"Authorization": "Bearer secret", "X-Tenant": "a", "User-Agent": "test-agent"} {
+		if got := header.Get(key); got != want {
+			t.Errorf("header %s got = %q, want %q", key, got, want)
+		}
+	}
+	if ts.Client().Timeout != 0 || http.DefaultClient.Timeout != 0 {
+		t.Errorf("WithTimeout() modified the given http client")
+	}
+}
+
+func TestNewClient_Env(t *testing.T) {
+	tests := []struct {
+		env  string
+		want string
+	}{
+		{env: "", want: "http://127.0.0.1:11434"},
+		{env: "0.0.0.0", want: "http://0.0.0.0:11434"},
+		{env: ":8080", want: "http://127.0.0.1:8080"},
+		{env: "gpu1:11435", want: "http://gpu1:11435"},
+		{env: "https://ollama.example.com", want: "https://ollama.example.com:443"},
+		{env: "http://proxy:8080/ollama", want: "http://proxy:8080/ollama"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.env, func(t *testing.T) {
+			t.Setenv("OLLAMA_HOST", tt.env)
+			c, err := NewClient("")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := c.baseUrl.String(); got != tt.want {
+				t.Errorf("NewClient() baseUrl = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
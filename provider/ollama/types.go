@@ -2,7 +2,10 @@ package ollama
 
 import (
 	"encoding/json"
+	"fmt"
 	"time"
+
+	"github.com/tech1024/goai/chat"
 )
 
 type ToolCall struct {
@@ -42,15 +45,9 @@ type Tool struct {
 type ToolFunction struct {
 	Name        string `json:"name"`
 	Description string `json:"description"`
-	Parameters  struct {
-		Type       string   `json:"type"`
-		Required   []string `json:"required"`
-		Properties map[string]struct {
-			Type        string   `json:"type"`
-			Description string   `json:"description"`
-			Enum        []string `json:"enum,omitempty"`
-		} `json:"properties"`
-	} `json:"parameters"`
+
+	// Parameters is the JSON schema of the arguments.
+	Parameters json.RawMessage `json:"parameters"`
 }
 
 func (t *ToolFunction) String() string {
@@ -58,6 +55,8 @@ func (t *ToolFunction) String() string {
 	return string(bts)
 }
 
+// Duration is the keep-alive duration of a model, a negative duration keeps
+// the model loaded forever and zero unloads it right after the request.
 type Duration struct {
 	time.Duration
 }
@@ -68,3 +67,47 @@ func (d Duration) MarshalJSON() ([]byte, error) {
 	}
 	return []byte("\"" + d.Duration.String() + "\""), nil
 }
+
+// UnmarshalJSON accepts a duration string like "5m", or a number of seconds
+// where a negative number means forever.
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var v any
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+
+	switch t := v.(type) {
+	case float64:
+		if t < 0 {
+			d.Duration = -1
+			return nil
+		}
+		d.Duration = time.Duration(t * float64(time.Second))
+	case string:
+		duration, err := time.ParseDuration(t)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q: %w", t, err)
+		}
+		if duration < 0 {
+			duration = -1
+		}
+		d.Duration = duration
+	default:
+		return fmt.Errorf("invalid duration %s", b)
+	}
+
+	return nil
+}
+
+// Usage returns the tokens consumed, ollama reports them with the final response only.
+func (m Metrics) Usage() chat.Usage {
+	return chat.Usage{
+		PromptTokens:     m.PromptEvalCount,
+		CompletionTokens: m.EvalCount,
+		TotalTokens:      m.PromptEvalCount + m.EvalCount,
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -2,10 +2,16 @@ package openai
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"io"
+	"math"
+	"net/http"
+	"strings"
 
 	"github.com/sashabaranov/go-openai"
+	"github.com/tech1024/goai/chat"
+	"github.com/tech1024/goai/internal/wire"
 	"github.com/tech1024/goai/prompt"
 )
 
@@ -21,34 +27,62 @@ type ChatModel struct {
 	model  string
 }
 
-func (chatModel *ChatModel) Call(ctx context.Context, prompt prompt.Prompt) (string, error) {
+func (chatModel *ChatModel) Call(ctx context.Context, prompt prompt.Prompt) (*chat.Response, error) {
 	req, err := chatModel.buildChatRequest(prompt)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	resp, err := chatModel.client.CreateChatCompletion(ctx, req)
-
 	if err != nil {
-		return "", err
+		return nil, toError(err)
 	}
 
-	return resp.Choices[0].Message.Content, nil
+	response := &chat.Response{
+		ID:          resp.ID,
+		Model:       wire.ModelOf(resp.Model, req.Model),
+		Generations: make([]chat.Generation, len(resp.Choices)),
+		Usage:       toUsage(resp.Usage),
+	}
+	for i, choice := range resp.Choices {
+		response.Generations[i] = chat.Generation{
+			Content:      choice.Message.Content,
+			ToolCalls:    toToolCalls(choice.Message.ToolCalls),
+			FinishReason: chat.FinishReason(choice.FinishReason),
+		}
+		if choice.LogProbs != nil {
+			response.Generations[i].LogProbs = toLogProbs(choice.LogProbs.Content)
+		}
+	}
+
+	return response, nil
 }
 
-func (chatModel *ChatModel) Stream(ctx context.Context, prompt prompt.Prompt, fn func([]byte) error) error {
+func (chatModel *ChatModel) Stream(ctx context.Context, prompt prompt.Prompt, fn func(*chat.Response) error) error {
 	req, err := chatModel.buildChatRequest(prompt)
 	if err != nil {
 		return err
 	}
+	streamUsage := extensionOf(prompt.ChatOption).StreamUsage
+	if streamUsage == nil || *streamUsage {
+		req.StreamOptions = &openai.StreamOptions{IncludeUsage: true}
+	}
 
 	stream, err := chatModel.client.CreateChatCompletionStream(ctx, req)
+	if streamUsage == nil && rejectsStreamOptions(err) {
+		req.StreamOptions = nil
+		stream, err = chatModel.client.CreateChatCompletionStream(ctx, req)
+	}
 	if err != nil {
-		return err
+		return toError(err)
 	}
 
 	defer stream.Close()
 
+	// tool calls are streamed as fragments, they are assembled per choice and
+	// emitted once the choice finished.
+	assemblers := make(map[int]*wire.ToolCallAssembler)
+
 	var resp openai.ChatCompletionStreamResponse
 	for {
 		resp, err = stream.Recv()
@@ -56,32 +90,192 @@ func (chatModel *ChatModel) Stream(ctx context.Context, prompt prompt.Prompt, fn
 			break
 		}
 		if err != nil {
+			return toError(err)
+		}
+
+		// the usage is reported by a final chunk without choices.
+		chunk := &chat.Response{
+			ID:    resp.ID,
+			Model: wire.ModelOf(resp.Model, req.Model),
+		}
+		for _, choice := range resp.Choices {
+			if choice.Index < 0 {
+				continue
+			}
+			for len(chunk.Generations) <= choice.Index {
+				chunk.Generations = append(chunk.Generations, chat.Generation{})
+			}
+			chunk.Generations[choice.Index] = chat.Generation{
+				Content:      choice.Delta.Content,
+				FinishReason: chat.FinishReason(choice.FinishReason),
+			}
+			if choice.Logprobs != nil {
+				chunk.Generations[choice.Index].LogProbs = toStreamLogProbs(choice.Logprobs.Content)
+			}
+
+			assembler := assemblers[choice.Index]
+			if assembler == nil {
+				assembler = &wire.ToolCallAssembler{Err: ErrInvalidToolCall}
+				assemblers[choice.Index] = assembler
+			}
+			for _, toolCall := range choice.Delta.ToolCalls {
+				chunk.Generations[choice.Index].ToolCallDeltas = append(chunk.Generations[choice.Index].ToolCallDeltas, assembler.Add(toolCall.Index, toolCall.ID, toolCall.Function.Name, toolCall.Function.Arguments))
+			}
+			if choice.FinishReason != "" {
+				if chunk.Generations[choice.Index].ToolCalls, err = assembler.Complete(); err != nil {
+					return err
+				}
+			}
+		}
+		if resp.Usage != nil {
+			chunk.Usage = toUsage(*resp.Usage)
+		}
+
+		if err = fn(chunk); err != nil {
 			return err
 		}
+	}
 
-		err = fn([]byte(resp.Choices[0].Delta.Content))
-		if err != nil {
+	// compatible servers may end the stream without a finish reason.
+	chunk := &chat.Response{Model: req.Model}
+	for index, assembler := range assemblers {
+		if !assembler.Pending() {
+			continue
+		}
+		for len(chunk.Generations) <= index {
+			chunk.Generations = append(chunk.Generations, chat.Generation{})
+		}
+		if chunk.Generations[index].ToolCalls, err = assembler.Complete(); err != nil {
 			return err
 		}
 	}
+	if len(chunk.Generations) > 0 {
+		return fn(chunk)
+	}
 
 	return nil
 }
 
-func (chatModel *ChatModel) buildChatRequest(prompt prompt.Prompt) (openai.ChatCompletionRequest, error) {
+func (chatModel *ChatModel) buildChatRequest(p prompt.Prompt) (openai.ChatCompletionRequest, error) {
 	request := openai.ChatCompletionRequest{
 		Model:    chatModel.model,
-		Messages: make([]openai.ChatCompletionMessage, len(prompt.Messages)),
+		Messages: make([]openai.ChatCompletionMessage, len(p.Messages)),
 	}
-	for i, message := range prompt.Messages {
+	for i, message := range p.Messages {
 		request.Messages[i] = openai.ChatCompletionMessage{
 			Role:    message.Type().String(),
 			Content: message.Text(),
 		}
+
+		switch message.Type() {
+		case prompt.MessageTypeAssistant:
+			for _, toolCall := range prompt.ToolCalls(message) {
+				request.Messages[i].ToolCalls = append(request.Messages[i].ToolCalls, openai.ToolCall{
+					ID:       toolCall.ID,
+					Type:     openai.ToolTypeFunction,
+					Function: openai.FunctionCall{Name: toolCall.Name, Arguments: toolCall.Arguments},
+				})
+			}
+		case prompt.MessageTypeTool:
+			request.Messages[i].ToolCallID = prompt.ToolCallID(message)
+		}
+	}
+	for _, tool := range p.ChatOption.Tools {
+		parameters := tool.Parameters
+		if len(parameters) == 0 {
+			parameters = json.RawMessage(`{"type":"object","properties":{}}`)
+		}
+		request.Tools = append(request.Tools, openai.Tool{
+			Type: openai.ToolTypeFunction,
+			Function: &openai.FunctionDefinition{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  parameters,
+			},
+		})
 	}
-	if prompt.ChatOption.Model != "" {
-		request.Model = prompt.ChatOption.Model
+	if p.ChatOption.Model != "" {
+		request.Model = p.ChatOption.Model
 	}
+	if p.ChatOption.Candidates > 1 {
+		request.N = p.ChatOption.Candidates
+	}
+	request.LogProbs = p.ChatOption.LogProbs || p.ChatOption.TopLogProbs > 0
+	request.TopLogProbs = p.ChatOption.TopLogProbs
+
+	extension := extensionOf(p.ChatOption)
+	request.MaxCompletionTokens = extension.MaxTokens
+	if extension.Temperature != nil {
+		request.Temperature = nonZero(*extension.Temperature)
+	}
+	if extension.TopP != nil {
+		request.TopP = nonZero(*extension.TopP)
+	}
+	request.Seed = extension.Seed
+	request.Stop = extension.Stop
 
 	return request, nil
 }
+
+// nonZero returns the value as float32, go-openai omits zero values so an
+// explicit zero is sent as the smallest non-zero float32.
+func nonZero(value float64) float32 {
+	if value == 0 {
+		return math.SmallestNonzeroFloat32
+	}
+
+	return float32(value)
+}
+
+// rejectsStreamOptions reports whether the server rejected the request with a
+// 400 naming the stream options, other errors are not retried.
+func rejectsStreamOptions(err error) bool {
+	var text string
+	var apiErr *openai.APIError
+	var reqErr *openai.RequestError
+	switch {
+	case errors.As(err, &apiErr) && apiErr.HTTPStatusCode == http.StatusBadRequest:
+		text = apiErr.Message
+		if apiErr.Param != nil {
+			text += " " + *apiErr.Param
+		}
+	case errors.As(err, &reqErr) && reqErr.HTTPStatusCode == http.StatusBadRequest:
+		text = string(reqErr.Body)
+	default:
+		return false
+	}
+
+	return strings.Contains(text, "stream_options") || strings.Contains(text, "include_usage")
+}
+
+func toUsage(usage openai.Usage) chat.Usage {
+	return chat.Usage{
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		TotalTokens:      usage.TotalTokens,
+	}
+}
+
+func toLogProbs(logProbs []openai.LogProb) []chat.LogProb {
+	result := make([]chat.LogProb, len(logProbs))
+	for i, logProb := range logProbs {
+		result[i] = chat.LogProb{Token: logProb.Token, LogProb: logProb.LogProb}
+		for _, top := range logProb.TopLogProbs {
+			result[i].TopLogProbs = append(result[i].TopLogProbs, chat.LogProb{Token: top.Token, LogProb: top.LogProb})
+		}
+	}
+
+	return result
+}
+
+func toStreamLogProbs(logProbs []openai.ChatCompletionTokenLogprob) []chat.LogProb {
+	result := make([]chat.LogProb, len(logProbs))
+	for i, logProb := range logProbs {
+		result[i] = chat.LogProb{Token: logProb.Token, LogProb: logProb.Logprob}
+		for _, top := range logProb.TopLogprobs {
+			result[i].TopLogProbs = append(result[i].TopLogProbs, chat.LogProb{Token: top.Token, LogProb: top.Logprob})
+		}
+	}
+
+	return result
+}
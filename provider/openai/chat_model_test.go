@@ -0,0 +1,302 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/tech1024/goai"
+	"github.com/tech1024/goai/chat"
+	"github.com/tech1024/goai/goaitest"
+	"github.com/tech1024/goai/metrics"
+	"github.com/tech1024/goai/prompt"
+	"github.com/tech1024/goai/provider/openai/openaitest"
+)
+
+func TestChatModel(t *testing.T) {
+	fake := goaitest.NewChatModel(
+		goaitest.Text("hello").WithUsage(chat.Usage{PromptTokens: 2, CompletionTokens: 1, TotalTokens: 3}),
+		goaitest.Chunks("a", "b", "c"),
+		goaitest.Error(&goaitest.StatusError{Code: http.StatusTooManyRequests, Message: "rate limited"}),
+	)
+	ts := openaitest.NewServer(fake, nil)
+	defer ts.Close()
+
+	c := goai.NewChat(NewChatModel(ts.OpenAIClient(), "gpt-test"))
+
+	resp, err := c.Call(context.Background(), testPrompt("request 1"))
+	if err != nil || resp.Text() != "hello" || resp.Model != "gpt-test" || resp.Usage.TotalTokens != 3 {
+		t.Errorf("Call() got = %v, error = %v", resp, err)
+	}
+
+	var chunks []string
+	err = c.ChatStream(context.Background(), "request 2", func(bts []byte) error {
+		chunks = append(chunks, string(bts))
+		return nil
+	})
+	if err != nil || strings.Join(chunks, "|") != "a|b|c" {
+		t.Errorf("ChatStream() got = %v, error = %v", chunks, err)
+	}
+
+	_, err = c.Chat(context.Background(), "request 3")
+	var statusErr *StatusError
+	var apiErr *openai.APIError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode() != http.StatusTooManyRequests || !errors.As(err, &apiErr) || !strings.Contains(err.Error(), "rate limited") {
+		t.Errorf("Chat() error = %v", err)
+	}
+	if category := metrics.Classify(err); category != metrics.CategoryRateLimit {
+		t.Errorf("Classify() = %v, want %v", category, metrics.CategoryRateLimit)
+	}
+}
+
+func testPrompt(content string) prompt.Prompt {
+	return prompt.NewPrompt(prompt.UserMessage(content))
+}
+
+func TestChatModel_FinishReason(t *testing.T) {
+	usage := chat.Usage{PromptTokens: 3, CompletionTokens: 2, TotalTokens: 5}
+	fake := goaitest.NewChatModel(
+		goaitest.Text("partial").WithFinishReason(chat.FinishReasonLength),
+		goaitest.Chunks("a", "b").WithFinishReason(chat.FinishReasonContentFilter).WithUsage(usage),
+	)
+	ts := openaitest.NewServer(fake, nil)
+	defer ts.Close()
+
+	model := NewChatModel(ts.OpenAIClient(), "gpt-test")
+	resp, err := model.Call(context.Background(), testPrompt("hi"))
+	if err != nil || resp.Text() != "partial" || resp.FinishReason() != chat.FinishReasonLength {
+		t.Errorf("Call() got = %v, error = %v", resp, err)
+	}
+
+	streamed := &chat.Response{}
+	err = model.Stream(context.Background(), testPrompt("hi"), func(chunk *chat.Response) error {
+		streamed.Append(chunk)
+		return nil
+	})
+	if err != nil || streamed.Text() != "ab" || streamed.FinishReason() != chat.FinishReasonContentFilter || streamed.Usage != usage {
+		t.Errorf("Stream() got = %+v, error = %v", streamed, err)
+	}
+
+	var req openai.ChatCompletionRequest
+	_ = json.Unmarshal(ts.Requests()[1].Body, &req)
+	if req.StreamOptions == nil || !req.StreamOptions.IncludeUsage {
+		t.Errorf("Stream() request = %s", ts.Requests()[1].Body)
+	}
+}
+
+func TestChatModel_EmptyChoices(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+			_, _ = io.WriteString(w, "data: {\"id\":\"1\",\"choices\":[]}\n\ndata: {\"id\":\"1\",\"choices\":[{\"index\":0,\"delta\":{\"content\":\"hi\"}}]}\n\ndata: [DONE]\n\n")
+			return
+		}
+		_, _ = io.WriteString(w, `{"id":"1","choices":[],"usage":{"prompt_tokens":4,"total_tokens":4}}`)
+	}))
+	defer ts.Close()
+
+	model := NewChatModel(NewClient("", WithBaseURL(ts.URL)), "gpt-test")
+	resp, err := model.Call(context.Background(), testPrompt("hi"))
+	if err != nil || resp.Text() != "" || len(resp.Generations) != 0 || resp.Usage.PromptTokens != 4 {
+		t.Errorf("Call() got = %v, error = %v", resp, err)
+	}
+
+	var chunks []string
+	if err = goai.NewChat(model).ChatStream(context.Background(), "hi", func(bts []byte) error {
+		chunks = append(chunks, string(bts))
+		return nil
+	}); err != nil || strings.Join(chunks, "|") != "hi" {
+		t.Errorf("ChatStream() got = %v, error = %v", chunks, err)
+	}
+}
+
+func TestChatModel_StreamUsage(t *testing.T) {
+	// the server rejects stream_options like some OpenAI-compatible servers.
+	var bodies []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bts, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(bts))
+		if strings.Contains(string(bts), "bad schema") {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = io.WriteString(w, `{"error":{"message":"invalid schema for function weather"}}`)
+			return
+		}
+		if strings.Contains(string(bts), "stream_options") {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = io.WriteString(w, `{"error":{"message":"unknown field stream_options"}}`)
+			return
+		}
+		_, _ = io.WriteString(w, "data: {\"id\":\"1\",\"choices\":[{\"index\":0,\"delta\":{\"content\":\"hi\"}}]}\n\ndata: [DONE]\n\n")
+	}))
+	defer ts.Close()
+
+	model := NewChatModel(NewClient("", WithBaseURL(ts.URL)), "gpt-test")
+	stream := func(streamUsage *bool) (string, error) {
+		p := testPrompt("hi")
+		p.ChatOption.Extensions = []prompt.Extension{&Extension{StreamUsage: streamUsage}}
+		streamed := &chat.Response{}
+		err := model.Stream(context.Background(), p, func(chunk *chat.Response) error {
+			streamed.Append(chunk)
+			return nil
+		})
+
+		return streamed.Text(), err
+	}
+
+	if got, err := stream(nil); err != nil || got != "hi" || len(bodies) != 2 || strings.Contains(bodies[1], "stream_options") {
+		t.Errorf("Stream() got = %q, error = %v, requests = %q", got, err, bodies)
+	}
+
+	bodies = nil
+	if got, err := stream(new(bool)); err != nil || got != "hi" || len(bodies) != 1 {
+		t.Errorf("Stream() without usage got = %q, error = %v, requests = %q", got, err, bodies)
+	}
+
+	bodies = nil
+	streamUsage := true
+	if _, err := stream(&streamUsage); err == nil || len(bodies) != 1 {
+		t.Errorf("Stream() with usage error = %v, requests = %q", err, bodies)
+	}
+
+	// other bad requests are not sent again.
+	bodies = nil
+	err := model.Stream(context.Background(), testPrompt("bad schema"), func(*chat.Response) error { return nil })
+	if err == nil || !strings.Contains(err.Error(), "invalid schema for function weather") || len(bodies) != 1 {
+		t.Errorf("Stream() of a bad request error = %v, requests = %q", err, bodies)
+	}
+}
+
+func TestChatModel_Candidates(t *testing.T) {
+	logProbs := []chat.LogProb{{Token: "yes", LogProb: -0.1, TopLogProbs: []chat.LogProb{{Token: "yes", LogProb: -0.1}, {Token: "no", LogProb: -2.4}}}}
+	fake := goaitest.NewChatModel(
+		goaitest.Reply{Chunks: []*chat.Response{{Generations: []chat.Generation{{Content: "yes", LogProbs: logProbs}}}}},
+		goaitest.Text("no"),
+	)
+	ts := openaitest.NewServer(fake, nil)
+	defer ts.Close()
+
+	p := testPrompt("is it?")
+	p.ChatOption.Candidates = 2
+	p.ChatOption.TopLogProbs = 2
+	resp, err := NewChatModel(ts.OpenAIClient(), "gpt-test").Call(context.Background(), p)
+	if err != nil || len(resp.Generations) != 2 || resp.Generations[1].Content != "no" || !reflect.DeepEqual(resp.Generations[0].LogProbs, logProbs) {
+		t.Fatalf("Call() got = %+v, error = %v", resp, err)
+	}
+
+	var req openai.ChatCompletionRequest
+	_ = json.Unmarshal(ts.Requests()[0].Body, &req)
+	if req.N != 2 || !req.LogProbs || req.TopLogProbs != 2 {
+		t.Errorf("Call() request = %s", ts.Requests()[0].Body)
+	}
+}
+
+func TestChatModel_StreamLogProbs(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `data: {"choices":[{"index":1,"delta":{"content":"b"},"logprobs":{"content":[{"token":"b","logprob":-0.5,"top_logprobs":[]}]}}]}`+"\n\n"+
+			`data: {"choices":[{"index":0,"delta":{"content":"a"},"logprobs":{"content":[{"token":"a","logprob":-0.2,"top_logprobs":[{"token":"a","logprob":-0.2}]}]}}]}`+"\n\n"+
+			"data: [DONE]\n\n")
+	}))
+	defer ts.Close()
+
+	streamed := &chat.Response{}
+	err := NewChatModel(NewClient("", WithBaseURL(ts.URL)), "gpt-test").Stream(context.Background(), testPrompt("hi"), func(chunk *chat.Response) error {
+		streamed.Append(chunk)
+		return nil
+	})
+	want := []chat.Generation{
+		{Content: "a", LogProbs: []chat.LogProb{{Token: "a", LogProb: -0.2, TopLogProbs: []chat.LogProb{{Token: "a", LogProb: -0.2}}}}},
+		{Content: "b", LogProbs: []chat.LogProb{{Token: "b", LogProb: -0.5}}},
+	}
+	if err != nil || !reflect.DeepEqual(streamed.Generations, want) {
+		t.Errorf("Stream() got = %+v, error = %v", streamed.Generations, err)
+	}
+}
+
+func TestChatModel_Tools(t *testing.T) {
+	weather := chat.ToolCall{ID: "call_1", Name: "weather", Arguments: `{"city":"Paris"}`}
+	fake := goaitest.NewChatModel(goaitest.ToolCalls(weather), goaitest.ToolCalls(weather), goaitest.Text("sunny"))
+	ts := openaitest.NewServer(fake, nil)
+	defer ts.Close()
+
+	model := NewChatModel(ts.OpenAIClient(), "gpt-test")
+	p := testPrompt("weather in Paris?")
+	p.ChatOption.Tools = []prompt.Tool{{Name: "weather", Description: "current weather", Parameters: json.RawMessage(`{"type":"object","properties":{"city":{"type":"string"}}}`)}}
+
+	resp, err := model.Call(context.Background(), p)
+	if err != nil || !reflect.DeepEqual(resp.ToolCalls(), []chat.ToolCall{weather}) || resp.FinishReason() != chat.FinishReasonToolCalls {
+		t.Fatalf("Call() got = %+v, error = %v", resp, err)
+	}
+	if tools := fake.LastPrompt().ChatOption.Tools; len(tools) != 1 || tools[0].Name != "weather" {
+		t.Errorf("Call() tools = %v", tools)
+	}
+
+	var deltas []chat.ToolCallDelta
+	streamed := &chat.Response{}
+	err = model.Stream(context.Background(), p, func(chunk *chat.Response) error {
+		for _, generation := range chunk.Generations {
+			deltas = append(deltas, generation.ToolCallDeltas...)
+		}
+		streamed.Append(chunk)
+		return nil
+	})
+	if err != nil || !reflect.DeepEqual(streamed.ToolCalls(), []chat.ToolCall{weather}) || len(deltas) != 3 || deltas[0].Name != "weather" {
+		t.Fatalf("Stream() got = %+v, deltas = %v, error = %v", streamed, deltas, err)
+	}
+
+	p.Messages = append(p.Messages, prompt.AssistantToolCallMessage("", weather), prompt.ToolResultMessage(weather, "sunny"))
+	if _, err = model.Call(context.Background(), p); err != nil {
+		t.Fatal(err)
+	}
+	messages := fake.LastPrompt().Messages
+	if !reflect.DeepEqual(prompt.ToolCalls(messages[1]), []chat.ToolCall{weather}) || prompt.ToolCallID(messages[2]) != "call_1" {
+		t.Errorf("Call() messages = %v", messages)
+	}
+}
+
+func TestChatModel_StreamToolCallFragments(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    []chat.ToolCall
+		wantErr error
+	}{
+		{
+			name: "without index and finish reason",
+			body: `data: {"choices":[{"delta":{"tool_calls":[{"id":"a","type":"function","function":{"name":"f","arguments":"{\"x\""}}]}}]}` + "\n\n" +
+				`data: {"choices":[{"delta":{"tool_calls":[{"function":{"arguments":":1}"}}]}}]}` + "\n\n" +
+				`data: {"choices":[{"delta":{"tool_calls":[{"id":"b","type":"function","function":{"name":"g"}}]}}]}` + "\n\n" +
+				"data: [DONE]\n\n",
+			want: []chat.ToolCall{{ID: "a", Name: "f", Arguments: `{"x":1}`}, {ID: "b", Name: "g", Arguments: "{}"}},
+		},
+		{
+			name: "invalid arguments",
+			body: `data: {"choices":[{"delta":{"tool_calls":[{"index":0,"id":"a","type":"function","function":{"name":"f","arguments":"{\"x\":"}}]}}]}` + "\n\n" +
+				`data: {"choices":[{"delta":{},"finish_reason":"tool_calls"}]}` + "\n\n" +
+				"data: [DONE]\n\n",
+			wantErr: ErrInvalidToolCall,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = io.WriteString(w, tt.body)
+			}))
+			defer ts.Close()
+
+			streamed := &chat.Response{}
+			err := NewChatModel(NewClient("", WithBaseURL(ts.URL)), "gpt-test").Stream(context.Background(), testPrompt("hi"), func(chunk *chat.Response) error {
+				streamed.Append(chunk)
+				return nil
+			})
+			if !errors.Is(err, tt.wantErr) || !reflect.DeepEqual(streamed.ToolCalls(), tt.want) {
+				t.Errorf("Stream() got = %+v, error = %v", streamed.ToolCalls(), err)
+			}
+		})
+	}
+}
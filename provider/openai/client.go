@@ -0,0 +1,93 @@
+package openai
+
+import (
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// ClientOption configures the client created by NewClient or NewAzureClient.
+type ClientOption func(*openai.ClientConfig)
+
+// NewClient returns a client of the OpenAI API or of an OpenAI-compatible
+// server, e.g. vLLM, LM Studio or llama.cpp. An empty apiKey is read from the
+// OPENAI_API_KEY environment variable, the base url and the organization from
+// OPENAI_BASE_URL and OPENAI_ORG_ID unless set by an option.
+func NewClient(apiKey string, options ...ClientOption) *openai.Client {
+	if apiKey == "" {
+		apiKey = os.Getenv("OPENAI_API_KEY")
+	}
+
+	config := openai.DefaultConfig(apiKey)
+	if baseURL := os.Getenv("OPENAI_BASE_URL"); baseURL != "" {
+		config.BaseURL = strings.TrimSuffix(baseURL, "/")
+	}
+	config.OrgID = os.Getenv("OPENAI_ORG_ID")
+
+	for _, option := range options {
+		option(&config)
+	}
+
+	return openai.NewClientWithConfig(config)
+}
+
+// NewAzureClient returns a client of the Azure OpenAI resource at endpoint,
+// e.g. "https://my-resource.openai.azure.com". Models are sent as deployment
+// names, see WithAzureDeployments.
+func NewAzureClient(apiKey, endpoint string, options ...ClientOption) *openai.Client {
+	config := openai.DefaultAzureConfig(apiKey, strings.TrimSuffix(endpoint, "/"))
+	for _, option := range options {
+		option(&config)
+	}
+
+	return openai.NewClientWithConfig(config)
+}
+
+// WithBaseURL sets the base url of the API, e.g. "http://localhost:8000/v1"
+// for vLLM.
+func WithBaseURL(baseURL string) ClientOption {
+	return func(c *openai.ClientConfig) {
+		c.BaseURL = strings.TrimSuffix(baseURL, "/")
+	}
+}
+
+// WithOrganization sets the OpenAI-Organization header of the requests.
+func WithOrganization(organization string) ClientOption {
+	return func(c *openai.ClientConfig) {
+		c.OrgID = organization
+	}
+}
+
+// WithHTTPClient sets the http client sending the requests.
+func WithHTTPClient(httpClient *http.Client) ClientOption {
+	return func(c *openai.ClientConfig) {
+		c.HTTPClient = httpClient
+	}
+}
+
+// WithAPIVersion sets the api-version of the Azure requests.
+func WithAPIVersion(version string) ClientOption {
+	return func(c *openai.ClientConfig) {
+		c.APIVersion = version
+	}
+}
+
+// WithAzureDeployments maps model names to the Azure deployment names, models
+// without deployment are mapped like Azure names deployments by default.
+func WithAzureDeployments(deployments map[string]string) ClientOption {
+	return func(c *openai.ClientConfig) {
+		fallback := c.AzureModelMapperFunc
+		c.AzureModelMapperFunc = func(model string) string {
+			if deployment, ok := deployments[model]; ok {
+				return deployment
+			}
+			if fallback != nil {
+				return fallback(model)
+			}
+
+			return model
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package openai
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/tech1024/goai/embedding"
+	"github.com/tech1024/goai/goaitest"
+	"github.com/tech1024/goai/provider/openai/openaitest"
+)
+
+func TestNewClient(t *testing.T) {
+	ts := openaitest.NewServer(goaitest.NewChatModel(goaitest.Text("a"), goaitest.Text("b")), nil)
+	defer ts.Close()
+
+	client := NewClient("sk-test", WithBaseURL(ts.URL+"/v1/"), WithOrganization("org-1"), WithHTTPClient(ts.Client()))
+	if resp, err := NewChatModel(client, "gpt-test").Call(context.Background(), testPrompt("hi")); err != nil || resp.Text() != "a" {
+		t.Errorf("Call() got = %v, error = %v", resp, err)
+	}
+
+	header := ts.Requests()[0].Header
+	if header.Get("Authorization") != "Bearer sk-test" || header.Get("OpenAI-Organization") != "org-1" {
+		t.Errorf("Call() header = %v", header)
+	}
+
+	t.Setenv("OPENAI_API_KEY", "sk-env")
+	t.Setenv("OPENAI_BASE_URL", ts.URL+"/v1")
+	t.Setenv("OPENAI_ORG_ID", "")
+	if _, err := NewChatModel(NewClient(""), "gpt-test").Call(context.Background(), testPrompt("hi")); err != nil {
+		t.Errorf("Call() error = %v", err)
+	}
+	if header = ts.Requests()[1].Header; header.Get("Authorization") != "Bearer sk-env" {
+		t.Errorf("Call() header = %v", header)
+	}
+}
+
+func TestNewAzureClient(t *testing.T) {
+	var got *http.Request
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		// a minimal response, as some compatible servers omit id, model and usage.
+		_, _ = io.WriteString(w, `{"choices":[{"index":0,"message":{"role":"assistant","content":"hi"}}]}`)
+	}))
+	defer ts.Close()
+
+	client := NewAzureClient("azure-key", ts.URL+"/",
+		WithAPIVersion("2024-06-01"),
+		WithAzureDeployments(map[string]string{"gpt-4o": "prod-gpt4o"}),
+	)
+
+	for model, path := range map[string]string{
+		"gpt-4o":        "/openai/deployments/prod-gpt4o/chat/completions",
+		"gpt-3.5-turbo": "/openai/deployments/gpt-35-turbo/chat/completions",
+	} {
+		resp, err := NewChatModel(client, model).Call(context.Background(), testPrompt("hi"))
+		if err != nil || resp.Text() != "hi" || resp.Model != model || !resp.Usage.IsZero() {
+			t.Errorf("Call() got = %v, error = %v", resp, err)
+			continue
+		}
+		if got.URL.Path != path || got.URL.Query().Get("api-version") != "2024-06-01" || got.Header.Get("api-key") != "azure-key" {
+			t.Errorf("Call() request = %s %v", got.URL, got.Header)
+		}
+	}
+}
+
+func TestEmbeddingModel_Compatible(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the data of compatible servers may come in any order.
+		_, _ = io.WriteString(w, `{"data":[{"index":1,"embedding":[0,1]},{"index":0,"embedding":[1,0]}]}`)
+	}))
+	defer ts.Close()
+
+	model := NewEmbeddingModel(NewClient("", WithBaseURL(ts.URL)), "bge-m3")
+	model.EncodingFormat = openai.EmbeddingEncodingFormatFloat
+
+	resp, err := model.Call(context.Background(), embedding.NewRequest([]string{"a", "b"}, embedding.Option{}))
+	if err != nil || resp.Model != "bge-m3" || !reflect.DeepEqual(resp.List(), [][]float32{{1, 0}, {0, 1}}) || resp.Embeddings[1].Index != 1 {
+		t.Errorf("Call() got = %v, error = %v", resp, err)
+	}
+}
@@ -0,0 +1,83 @@
+package openai
+
+import (
+	"errors"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/tech1024/goai"
+	"github.com/tech1024/goai/prompt"
+)
+
+// the openai provider serves DSNs like "openai:gpt-4o-mini?temperature=0.2" or
+// "openai://localhost:8000/meta-llama/Llama-3.1-8B" for an OpenAI-compatible
+// server, whose base url is https://host/v1 unless set by base_url.
+func init() {
+	goai.RegisterProvider(ProviderName, goai.Provider{
+		ChatModel: func(dsn *goai.DSN) (goai.ChatModel, error) {
+			client, err := clientOf(dsn)
+			if err != nil {
+				return nil, err
+			}
+
+			var extension Extension
+			if extension.Temperature, err = dsn.Float("temperature"); err != nil {
+				return nil, err
+			}
+			if extension.TopP, err = dsn.Float("top_p"); err != nil {
+				return nil, err
+			}
+			if extension.Seed, err = dsn.Int("seed"); err != nil {
+				return nil, err
+			}
+			maxTokens, err := dsn.Int("max_tokens")
+			if err != nil {
+				return nil, err
+			}
+			if maxTokens != nil {
+				extension.MaxTokens = *maxTokens
+			}
+			extension.Stop = dsn.Values("stop")
+			if extension.StreamUsage, err = dsn.Bool("stream_usage"); err != nil {
+				return nil, err
+			}
+
+			return goai.DefaultChatOption(prompt.Option{
+				Extensions: []prompt.Extension{&extension},
+			})(NewChatModel(client, dsn.Model)), nil
+		},
+		EmbeddingModel: func(dsn *goai.DSN) (goai.EmbeddingModel, error) {
+			client, err := clientOf(dsn)
+			if err != nil {
+				return nil, err
+			}
+
+			// encoding_format=float for servers not supporting base64.
+			embeddingModel := NewEmbeddingModel(client, dsn.Model)
+			embeddingModel.EncodingFormat = openai.EmbeddingEncodingFormat(dsn.Param("encoding_format"))
+
+			return embeddingModel, nil
+		},
+	})
+}
+
+// clientOf returns the client of the DSN.
+func clientOf(dsn *goai.DSN) (*openai.Client, error) {
+	if dsn.Model == "" {
+		return nil, errors.New("openai dsn: missing model")
+	}
+
+	apiKey, err := dsn.APIKey()
+	if err != nil {
+		return nil, err
+	}
+
+	var options []ClientOption
+	if baseURL := dsn.BaseURL("https", "/v1"); baseURL != "" {
+		options = append(options, WithBaseURL(baseURL))
+	}
+	if organization := dsn.Param("organization"); organization != "" {
+		options = append(options, WithOrganization(organization))
+	}
+
+	return NewClient(apiKey, options...), nil
+}
@@ -0,0 +1,85 @@
+package openai_test
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/tech1024/goai"
+	"github.com/tech1024/goai/chat"
+	"github.com/tech1024/goai/embedding"
+	"github.com/tech1024/goai/goaitest"
+	"github.com/tech1024/goai/prompt"
+	"github.com/tech1024/goai/provider/openai/openaitest"
+)
+
+func TestOpenChatModel(t *testing.T) {
+	fake := goaitest.NewChatModel(goaitest.Text("hello"))
+	ts := openaitest.NewServer(fake, goaitest.NewEmbeddingModel(8))
+	defer ts.Close()
+	t.Setenv("GOAI_TEST_OPENAI_KEY", "dsn-key")
+
+	chatModel, err := goai.OpenChatModel("openai:gpt-test?temperature=0.5&max_tokens=64&seed=7&stop=END&api_key_env=GOAI_TEST_OPENAI_KEY&base_url=" + ts.URL + "/v1")
+	if err != nil {
+		t.Fatalf("OpenChatModel() error = %v", err)
+	}
+
+	resp, err := chatModel.Call(context.Background(), prompt.NewPrompt(prompt.UserMessage("hi")))
+	if err != nil || resp.Text() != "hello" {
+		t.Fatalf("Call() got = %+v, error = %v", resp, err)
+	}
+
+	request := ts.Requests()[0]
+	var req map[string]any
+	_ = json.Unmarshal(request.Body, &req)
+	if req["model"] != "gpt-test" || req["temperature"] != 0.5 || req["max_completion_tokens"] != 64.0 || req["seed"] != 7.0 ||
+		request.Header.Get("Authorization") != "Bearer dsn-key" {
+		t.Errorf("Call() request = %s %v", request.Body, request.Header)
+	}
+
+	embeddingModel, err := goai.OpenEmbeddingModel("openai:text-embedding-test?dimensions=4&base_url=" + ts.URL + "/v1")
+	if err != nil {
+		t.Fatalf("OpenEmbeddingModel() error = %v", err)
+	}
+	embeddings, err := embeddingModel.Call(context.Background(), embedding.NewRequest([]string{"a"}, embedding.Option{}))
+	if err != nil || len(embeddings.Embeddings[0].Embedding) != 4 {
+		t.Errorf("Call() got = %+v, error = %v", embeddings, err)
+	}
+
+	// an explicit zero is not omitted.
+	chatModel, err = goai.OpenChatModel("openai:gpt-test?temperature=0&base_url=" + ts.URL + "/v1")
+	if err != nil {
+		t.Fatalf("OpenChatModel() error = %v", err)
+	}
+	fake.Enqueue(goaitest.Text("hello"))
+	if _, err = chatModel.Call(context.Background(), prompt.NewPrompt(prompt.UserMessage("hi"))); err != nil {
+		t.Fatalf("Call() error = %v", err)
+	}
+	requests := ts.Requests()
+	req = nil
+	_ = json.Unmarshal(requests[len(requests)-1].Body, &req)
+	if temperature, ok := req["temperature"].(float64); !ok || float32(temperature) != math.SmallestNonzeroFloat32 {
+		t.Errorf("Call() request = %s", requests[len(requests)-1].Body)
+	}
+
+	chatModel, err = goai.OpenChatModel("openai:gpt-test?stream_usage=false&base_url=" + ts.URL + "/v1")
+	if err != nil {
+		t.Fatalf("OpenChatModel() error = %v", err)
+	}
+	fake.Enqueue(goaitest.Chunks("a", "b"))
+	if err = chatModel.Stream(context.Background(), prompt.NewPrompt(prompt.UserMessage("hi")), func(*chat.Response) error { return nil }); err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	requests = ts.Requests()
+	if body := requests[len(requests)-1].Body; strings.Contains(string(body), "stream_options") {
+		t.Errorf("Stream() request = %s", body)
+	}
+
+	for _, dsn := range []string{"openai:", "openai:gpt-test?temprature=0.5", "openai:gpt-test?stream_usage=maybe", "openai:gpt-test?api_key_env=GOAI_TEST_MISSING"} {
+		if _, err = goai.OpenChatModel(dsn); err == nil {
+			t.Errorf("OpenChatModel(%q) error = nil", dsn)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/tech1024/goai/embedding"
+	"github.com/tech1024/goai/internal/wire"
+)
+
+// ErrInvalidEmbeddings is returned when the server returns the embeddings of
+// a batch with missing, duplicate or out of range indexes.
+var ErrInvalidEmbeddings = errors.New("openai: invalid embeddings")
+
+// DefaultMaxInputs the maximum number of inputs of a single embeddings request
+// accepted by OpenAI.
+const DefaultMaxInputs = 2048
+
+func NewEmbeddingModel(client *openai.Client, model string) *EmbeddingModel {
+	return &EmbeddingModel{
+		client: client,
+		model:  model,
+	}
+}
+
+// EmbeddingModel a goai.EmbeddingModel using the /v1/embeddings endpoint.
+type EmbeddingModel struct {
+	client *openai.Client
+	model  string
+
+	// MaxInputs the maximum number of inputs of a request, larger requests are
+	// split. Zero uses DefaultMaxInputs.
+	MaxInputs int
+
+	// EncodingFormat the encoding of the vectors on the wire, empty uses the
+	// smaller base64 encoding. Set it to openai.EmbeddingEncodingFormatFloat
+	// for servers not supporting base64.
+	EncodingFormat openai.EmbeddingEncodingFormat
+}
+
+func (embeddingModel *EmbeddingModel) Call(ctx context.Context, request embedding.Request) (embedding.Response, error) {
+	var embeddingResponse embedding.Response
+
+	req := openai.EmbeddingRequest{
+		Model:          openai.EmbeddingModel(embeddingModel.model),
+		Dimensions:     request.Option.Dimensions,
+		EncodingFormat: embeddingModel.EncodingFormat,
+	}
+	if request.Option.Model != "" {
+		req.Model = openai.EmbeddingModel(request.Option.Model)
+	}
+	if req.EncodingFormat == "" {
+		req.EncodingFormat = openai.EmbeddingEncodingFormatBase64
+	}
+
+	maxInputs := embeddingModel.MaxInputs
+	if maxInputs <= 0 {
+		maxInputs = DefaultMaxInputs
+	}
+
+	// the data may come in any order, each vector is put at its index.
+	embeddingResponse.Embeddings = make([]embedding.Embedding, len(request.Inputs))
+	for offset := 0; offset < len(request.Inputs); offset += maxInputs {
+		inputs := request.Inputs[offset:min(offset+maxInputs, len(request.Inputs))]
+		req.Input = inputs
+
+		resp, err := embeddingModel.client.CreateEmbeddings(ctx, req)
+		if err != nil {
+			return embeddingResponse, toError(err)
+		}
+
+		if len(resp.Data) != len(inputs) {
+			return embeddingResponse, fmt.Errorf("%w: %d embeddings for %d inputs", ErrInvalidEmbeddings, len(resp.Data), len(inputs))
+		}
+		for _, e := range resp.Data {
+			if e.Index < 0 || e.Index >= len(resp.Data) || embeddingResponse.Embeddings[offset+e.Index].Embedding != nil {
+				return embeddingResponse, fmt.Errorf("%w: duplicate or out of range index %d", ErrInvalidEmbeddings, e.Index)
+			}
+			embeddingResponse.Embeddings[offset+e.Index] = embedding.Embedding{
+				Embedding: e.Embedding,
+				Index:     offset + e.Index,
+			}
+		}
+		embeddingResponse.Model = wire.ModelOf(string(resp.Model), string(req.Model))
+		embeddingResponse.Usage = embeddingResponse.Usage.Add(toUsage(resp.Usage))
+	}
+
+	return embeddingResponse, nil
+}
@@ -0,0 +1,95 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/tech1024/goai/embedding"
+	"github.com/tech1024/goai/goaitest"
+	"github.com/tech1024/goai/prompt"
+	"github.com/tech1024/goai/provider/openai/openaitest"
+)
+
+func TestEmbeddingModel(t *testing.T) {
+	ts := openaitest.NewServer(nil, goaitest.NewEmbeddingModel(8))
+	defer ts.Close()
+
+	model := NewEmbeddingModel(ts.OpenAIClient(), "text-embedding-3-small")
+	model.MaxInputs = 2
+
+	inputs := []string{"one", "two", "three", "four", "five"}
+	resp, err := model.Call(context.Background(), embedding.NewRequest(inputs, embedding.Option{Model: "text-embedding-3-large", Dimensions: 4}))
+	if err != nil || len(resp.Embeddings) != 5 || resp.Model != "text-embedding-3-large" {
+		t.Fatalf("Call() got = %v, error = %v", resp, err)
+	}
+	for i, e := range resp.Embeddings {
+		if e.Index != i || !reflect.DeepEqual(e.Embedding, goaitest.Vector(inputs[i], 4)) {
+			t.Errorf("Call() embedding %d = %v", i, e)
+		}
+	}
+	var tokens int
+	for _, input := range inputs {
+		tokens += prompt.EstimateTokens(input)
+	}
+	if resp.Usage.PromptTokens != tokens || resp.Usage.TotalTokens != tokens {
+		t.Errorf("Call() usage = %v", resp.Usage)
+	}
+
+	requests := ts.Requests()
+	if len(requests) != 3 {
+		t.Fatalf("Call() requests = %d, want 3", len(requests))
+	}
+	var req openai.EmbeddingRequest
+	_ = json.Unmarshal(requests[2].Body, &req)
+	if req.Model != "text-embedding-3-large" || req.Dimensions != 4 || req.EncodingFormat != openai.EmbeddingEncodingFormatBase64 || !reflect.DeepEqual(req.Input, []any{"five"}) {
+		t.Errorf("Call() request = %s", requests[2].Body)
+	}
+
+	model.EncodingFormat = openai.EmbeddingEncodingFormatFloat
+	if resp, err = model.Call(context.Background(), embedding.NewRequest([]string{"one"}, embedding.Option{})); err != nil || !reflect.DeepEqual(resp.Embeddings[0].Embedding, goaitest.Vector("one", 8)) {
+		t.Errorf("Call() got = %v, error = %v", resp, err)
+	}
+
+	if resp, err = model.Call(context.Background(), embedding.NewRequest(nil, embedding.Option{})); err != nil || len(resp.Embeddings) != 0 || len(ts.Requests()) != 4 {
+		t.Errorf("Call() without inputs got = %v, error = %v", resp, err)
+	}
+
+	ts.EmbeddingModel.(*goaitest.EmbeddingModel).Err = &goaitest.StatusError{Code: http.StatusBadRequest, Message: "too many inputs"}
+	if _, err = model.Call(context.Background(), embedding.NewRequest(inputs, embedding.Option{})); err == nil {
+		t.Errorf("Call() error = nil")
+	}
+}
+
+func TestEmbeddingModel_Index(t *testing.T) {
+	data := `[{"index":2,"embedding":[3]},{"index":0,"embedding":[1]},{"index":1,"embedding":[2]}]`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"data":`+data+`}`)
+	}))
+	defer ts.Close()
+
+	model := NewEmbeddingModel(NewClient("", WithBaseURL(ts.URL)), "text-embedding-3-small")
+	model.EncodingFormat = openai.EmbeddingEncodingFormatFloat
+
+	request := embedding.NewRequest([]string{"one", "two", "three"}, embedding.Option{})
+	resp, err := model.Call(context.Background(), request)
+	if err != nil || !reflect.DeepEqual(resp.List(), [][]float32{{1}, {2}, {3}}) || resp.Embeddings[2].Index != 2 {
+		t.Errorf("Call() got = %v, error = %v", resp, err)
+	}
+
+	for _, data = range []string{
+		`[{"embedding":[1]},{"embedding":[2]},{"embedding":[3]}]`,
+		`[{"index":0,"embedding":[1]},{"index":3,"embedding":[2]},{"index":1,"embedding":[3]}]`,
+		`[{"index":0,"embedding":[1]},{"index":1,"embedding":[2]}]`,
+	} {
+		if _, err = model.Call(context.Background(), request); !errors.Is(err, ErrInvalidEmbeddings) {
+			t.Errorf("Call() of %s error = %v, wantErr %v", data, err, ErrInvalidEmbeddings)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package openai
+
+import (
+	"errors"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// StatusError wraps the errors of go-openai reporting the http status of the
+// response, an openai.APIError or an openai.RequestError.
+type StatusError struct {
+	// Code the http status code.
+	Code int
+
+	// Err the error of go-openai.
+	Err error
+}
+
+func (e *StatusError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *StatusError) Unwrap() error {
+	return e.Err
+}
+
+// StatusCode returns the http status code.
+func (e *StatusError) StatusCode() int {
+	return e.Code
+}
+
+// toError wraps the errors of go-openai carrying an http status in a
+// StatusError, other errors are returned as is.
+func toError(err error) error {
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) && apiErr.HTTPStatusCode != 0 {
+		return &StatusError{Code: apiErr.HTTPStatusCode, Err: err}
+	}
+	var requestErr *openai.RequestError
+	if errors.As(err, &requestErr) && requestErr.HTTPStatusCode != 0 {
+		return &StatusError{Code: requestErr.HTTPStatusCode, Err: err}
+	}
+
+	return err
+}
@@ -0,0 +1,46 @@
+package openai
+
+import (
+	"github.com/tech1024/goai/prompt"
+)
+
+// ProviderName the name of the openai provider.
+const ProviderName = "openai"
+
+// Extension the OpenAI specific options of a prompt, attach it to
+// prompt.Option.Extensions.
+type Extension struct {
+	// MaxTokens the maximum number of tokens generated, sent as
+	// max_completion_tokens.
+	MaxTokens int
+
+	// Temperature and TopP the sampling of the generation, nil uses the
+	// defaults of the model.
+	Temperature *float64
+	TopP        *float64
+
+	// Seed makes the sampling deterministic on a best-effort basis.
+	Seed *int
+
+	// Stop the sequences ending the generation.
+	Stop []string
+
+	// StreamUsage requests the usage with the last chunk of a stream, sent as
+	// stream_options.include_usage. nil requests it and streams again without
+	// it if the server rejects the request, like some OpenAI-compatible ones.
+	StreamUsage *bool
+}
+
+func (e *Extension) Provider() string {
+	return ProviderName
+}
+
+// extensionOf returns the openai extension of the prompt, an empty one if
+// there is none.
+func extensionOf(option prompt.Option) *Extension {
+	if extension, ok := option.Extension(ProviderName).(*Extension); ok && extension != nil {
+		return extension
+	}
+
+	return &Extension{}
+}
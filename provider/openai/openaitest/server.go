@@ -0,0 +1,359 @@
+// Package openaitest provides an in-process fake OpenAI server, whose chat
+// completion and embedding endpoints are answered by goai models, e.g. the
+// scripted fakes of the goaitest package.
+package openaitest
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/tech1024/goai"
+	"github.com/tech1024/goai/chat"
+	"github.com/tech1024/goai/embedding"
+	"github.com/tech1024/goai/goaitest"
+	"github.com/tech1024/goai/prompt"
+)
+
+// Server a fake OpenAI server.
+type Server struct {
+	*goaitest.Server
+
+	// ChatModel answers /v1/chat/completions.
+	ChatModel goai.ChatModel
+
+	// EmbeddingModel answers /v1/embeddings.
+	EmbeddingModel goai.EmbeddingModel
+}
+
+// NewServer starts a fake OpenAI server answering with the models, either may be nil.
+func NewServer(chatModel goai.ChatModel, embeddingModel goai.EmbeddingModel) *Server {
+	s := &Server{
+		Server:         goaitest.NewServer(),
+		ChatModel:      chatModel,
+		EmbeddingModel: embeddingModel,
+	}
+	s.HandleFunc("POST /v1/chat/completions", s.handleChatCompletions)
+	s.HandleFunc("POST /v1/embeddings", s.handleEmbeddings)
+
+	return s
+}
+
+// Config returns a go-openai client config pointing at the server.
+func (s *Server) Config() openai.ClientConfig {
+	config := openai.DefaultConfig("test-token")
+	config.BaseURL = s.URL + "/v1"
+	config.HTTPClient = s.Client()
+
+	return config
+}
+
+// OpenAIClient returns a go-openai client talking to the server.
+func (s *Server) OpenAIClient() *openai.Client {
+	return openai.NewClientWithConfig(s.Config())
+}
+
+func errorBody(err error) map[string]any {
+	return map[string]any{"error": map[string]any{
+		"message": err.Error(),
+		"type":    "invalid_request_error",
+		"code":    goaitest.HTTPStatus(err),
+	}}
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	goaitest.WriteJSON(w, goaitest.HTTPStatus(err), errorBody(err))
+}
+
+func (s *Server) handleChatCompletions(w http.ResponseWriter, r *http.Request) {
+	var req openai.ChatCompletionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, &goaitest.StatusError{Code: http.StatusBadRequest, Message: err.Error()})
+		return
+	}
+	if s.ChatModel == nil {
+		writeError(w, &goaitest.StatusError{Code: http.StatusNotFound, Message: "The model `" + req.Model + "` does not exist"})
+		return
+	}
+
+	p := toPrompt(req)
+	if req.Stream {
+		s.streamChatCompletions(r.Context(), w, req, p)
+		return
+	}
+
+	completion := openai.ChatCompletionResponse{
+		ID:      completionID(),
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Model:   req.Model,
+	}
+
+	// each of the n candidates is a call of the chat model.
+	var usage chat.Usage
+	for range max(req.N, 1) {
+		resp, err := s.ChatModel.Call(r.Context(), p)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		usage = usage.Add(resp.Usage)
+		for _, generation := range resp.Generations {
+			choice := openai.ChatCompletionChoice{
+				Index: len(completion.Choices),
+				Message: openai.ChatCompletionMessage{
+					Role:      openai.ChatMessageRoleAssistant,
+					Content:   generation.Content,
+					ToolCalls: toToolCalls(generation.ToolCalls),
+				},
+				FinishReason: finishReason(generation),
+			}
+			if req.LogProbs {
+				choice.LogProbs = toLogProbs(generation.LogProbs)
+			}
+			completion.Choices = append(completion.Choices, choice)
+		}
+	}
+	completion.Usage = toUsage(usage)
+
+	goaitest.WriteJSON(w, http.StatusOK, completion)
+}
+
+func (s *Server) streamChatCompletions(ctx context.Context, w http.ResponseWriter, req openai.ChatCompletionRequest, p prompt.Prompt) {
+	events := goaitest.NewEventStream(w)
+	send := func(v any) error {
+		return events.Send("", v)
+	}
+	id := completionID()
+	streamed := &chat.Response{}
+	var toolCalls int
+	chunk := func(choices []openai.ChatCompletionStreamChoice) openai.ChatCompletionStreamResponse {
+		return openai.ChatCompletionStreamResponse{
+			ID:      id,
+			Object:  "chat.completion.chunk",
+			Created: time.Now().Unix(),
+			Model:   req.Model,
+			Choices: choices,
+		}
+	}
+
+	err := s.ChatModel.Stream(ctx, p, func(resp *chat.Response) error {
+		streamed.Append(resp)
+		if resp.Text() == "" && len(resp.ToolCalls()) == 0 {
+			return nil
+		}
+
+		var choices []openai.ChatCompletionStreamChoice
+		for i, generation := range resp.Generations {
+			choices = append(choices, openai.ChatCompletionStreamChoice{
+				Index: i,
+				Delta: openai.ChatCompletionStreamChoiceDelta{
+					Role:    openai.ChatMessageRoleAssistant,
+					Content: generation.Content,
+				},
+			})
+		}
+		if resp.Text() != "" {
+			if err := send(chunk(choices)); err != nil {
+				return err
+			}
+		}
+
+		// tool calls are streamed like OpenAI does, the arguments in fragments.
+		for _, tc := range resp.ToolCalls() {
+			index := toolCalls
+			toolCalls++
+			for j, fragment := range goaitest.Fragments(tc.Arguments) {
+				call := openai.ToolCall{Index: &index, Function: openai.FunctionCall{Arguments: fragment}}
+				if j == 0 {
+					call.ID, call.Type, call.Function.Name = tc.ID, openai.ToolTypeFunction, tc.Name
+				}
+				delta := openai.ChatCompletionStreamChoiceDelta{Role: openai.ChatMessageRoleAssistant, ToolCalls: []openai.ToolCall{call}}
+				if err := send(chunk([]openai.ChatCompletionStreamChoice{{Delta: delta}})); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		if !events.Started() {
+			writeError(w, err)
+			return
+		}
+		_ = send(errorBody(err))
+		return
+	}
+
+	_ = send(chunk([]openai.ChatCompletionStreamChoice{{FinishReason: finishReason(chat.Generation{FinishReason: streamed.FinishReason(), ToolCalls: streamed.ToolCalls()})}}))
+
+	if req.StreamOptions != nil && req.StreamOptions.IncludeUsage {
+		u := toUsage(streamed.Usage)
+		final := chunk([]openai.ChatCompletionStreamChoice{})
+		final.Usage = &u
+		_ = send(final)
+	}
+
+	_ = events.Done()
+}
+
+func (s *Server) handleEmbeddings(w http.ResponseWriter, r *http.Request) {
+	var req openai.EmbeddingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, &goaitest.StatusError{Code: http.StatusBadRequest, Message: err.Error()})
+		return
+	}
+	if s.EmbeddingModel == nil {
+		writeError(w, &goaitest.StatusError{Code: http.StatusNotFound, Message: "The model `" + string(req.Model) + "` does not exist"})
+		return
+	}
+
+	var inputs []string
+	switch input := req.Input.(type) {
+	case string:
+		inputs = []string{input}
+	case []any:
+		for _, v := range input {
+			text, _ := v.(string)
+			inputs = append(inputs, text)
+		}
+	}
+
+	resp, err := s.EmbeddingModel.Call(r.Context(), embedding.NewRequest(inputs, embedding.Option{
+		Model:      string(req.Model),
+		Dimensions: req.Dimensions,
+	}))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var promptTokens int
+	for _, input := range inputs {
+		promptTokens += prompt.EstimateTokens(input)
+	}
+
+	data := make([]map[string]any, len(resp.Embeddings))
+	for i, e := range resp.Embeddings {
+		var vector any = e.Embedding
+		if req.EncodingFormat == openai.EmbeddingEncodingFormatBase64 {
+			vector = encodeBase64(e.Embedding)
+		}
+		data[i] = map[string]any{"object": "embedding", "embedding": vector, "index": e.Index}
+	}
+
+	goaitest.WriteJSON(w, http.StatusOK, map[string]any{
+		"object": "list",
+		"data":   data,
+		"model":  req.Model,
+		"usage":  openai.Usage{PromptTokens: promptTokens, TotalTokens: promptTokens},
+	})
+}
+
+func toPrompt(req openai.ChatCompletionRequest) prompt.Prompt {
+	p := prompt.Prompt{ChatOption: prompt.Option{Model: req.Model}}
+	for _, tool := range req.Tools {
+		if tool.Function == nil {
+			continue
+		}
+		parameters, _ := json.Marshal(tool.Function.Parameters)
+		p.ChatOption.Tools = append(p.ChatOption.Tools, prompt.Tool{
+			Name:        tool.Function.Name,
+			Description: tool.Function.Description,
+			Parameters:  parameters,
+		})
+	}
+
+	for _, m := range req.Messages {
+		content := m.Content
+		for _, part := range m.MultiContent {
+			content += part.Text
+		}
+
+		switch {
+		case len(m.ToolCalls) > 0:
+			var toolCalls []chat.ToolCall
+			for _, tc := range m.ToolCalls {
+				toolCalls = append(toolCalls, chat.ToolCall{ID: tc.ID, Name: tc.Function.Name, Arguments: tc.Function.Arguments})
+			}
+			p.Messages = append(p.Messages, prompt.AssistantToolCallMessage(content, toolCalls...))
+		case m.Role == openai.ChatMessageRoleTool:
+			p.Messages = append(p.Messages, prompt.ToolResultMessage(chat.ToolCall{ID: m.ToolCallID}, content))
+		default:
+			p.Messages = append(p.Messages, prompt.NewMessage(prompt.MessageType(m.Role), content))
+		}
+	}
+
+	return p
+}
+
+func toUsage(usage chat.Usage) openai.Usage {
+	return openai.Usage{
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		TotalTokens:      usage.TotalTokens,
+	}
+}
+
+func toToolCalls(toolCalls []chat.ToolCall) []openai.ToolCall {
+	var calls []openai.ToolCall
+	for i, tc := range toolCalls {
+		index := i
+		calls = append(calls, openai.ToolCall{
+			Index: &index,
+			ID:    tc.ID,
+			Type:  openai.ToolTypeFunction,
+			Function: openai.FunctionCall{
+				Name:      tc.Name,
+				Arguments: tc.Arguments,
+			},
+		})
+	}
+
+	return calls
+}
+
+func toLogProbs(logProbs []chat.LogProb) *openai.LogProbs {
+	result := &openai.LogProbs{Content: make([]openai.LogProb, len(logProbs))}
+	for i, logProb := range logProbs {
+		result.Content[i] = openai.LogProb{Token: logProb.Token, LogProb: logProb.LogProb, TopLogProbs: []openai.TopLogProbs{}}
+		for _, top := range logProb.TopLogProbs {
+			result.Content[i].TopLogProbs = append(result.Content[i].TopLogProbs, openai.TopLogProbs{Token: top.Token, LogProb: top.LogProb})
+		}
+	}
+
+	return result
+}
+
+func finishReason(generation chat.Generation) openai.FinishReason {
+	if generation.FinishReason != "" {
+		return openai.FinishReason(generation.FinishReason)
+	}
+	if len(generation.ToolCalls) > 0 {
+		return openai.FinishReasonToolCalls
+	}
+
+	return openai.FinishReasonStop
+}
+
+func encodeBase64(vector []float32) string {
+	bts := make([]byte, 4*len(vector))
+	for i, v := range vector {
+		binary.LittleEndian.PutUint32(bts[4*i:], math.Float32bits(v))
+	}
+
+	return base64.StdEncoding.EncodeToString(bts)
+}
+
+func completionID() string {
+	return fmt.Sprintf("chatcmpl-%d", time.Now().UnixNano())
+}
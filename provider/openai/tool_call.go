@@ -0,0 +1,25 @@
+package openai
+
+import (
+	"errors"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/tech1024/goai/chat"
+)
+
+// ErrInvalidToolCall is returned when the assembled arguments of a streamed
+// tool call are no valid JSON.
+var ErrInvalidToolCall = errors.New("openai: invalid tool call arguments")
+
+func toToolCalls(toolCalls []openai.ToolCall) []chat.ToolCall {
+	var calls []chat.ToolCall
+	for _, toolCall := range toolCalls {
+		calls = append(calls, chat.ToolCall{
+			ID:        toolCall.ID,
+			Name:      toolCall.Function.Name,
+			Arguments: toolCall.Function.Arguments,
+		})
+	}
+
+	return calls
+}
@@ -0,0 +1,62 @@
+package goai_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/tech1024/goai"
+	"github.com/tech1024/goai/goaitest"
+	"github.com/tech1024/goai/prompt"
+)
+
+func TestOpenChatModel(t *testing.T) {
+	fake := goaitest.NewChatModel(goaitest.Text("hello"))
+	goai.RegisterProvider("goaitest", goai.Provider{
+		ChatModel: func(dsn *goai.DSN) (goai.ChatModel, error) {
+			candidates, err := dsn.Int("candidates")
+			if err != nil || candidates == nil {
+				return fake, err
+			}
+
+			return goai.DefaultChatOption(prompt.Option{Model: dsn.Model, Candidates: *candidates})(fake), nil
+		},
+	})
+
+	if !slices.Contains(goai.Providers(), "goaitest") {
+		t.Errorf("Providers() got = %v", goai.Providers())
+	}
+
+	chatModel, err := goai.OpenChatModel("goaitest:test-model?candidates=2")
+	if err != nil {
+		t.Fatalf("OpenChatModel() error = %v", err)
+	}
+	if _, err = chatModel.Call(context.Background(), prompt.NewPrompt(prompt.UserMessage("hi"))); err != nil {
+		t.Fatalf("Call() error = %v", err)
+	}
+	if option := fake.LastPrompt().ChatOption; option.Model != "test-model" || option.Candidates != 2 {
+		t.Errorf("Call() option = %+v", option)
+	}
+
+	if _, err = goai.OpenChatModel("goaitest:test-model?candidate=2"); err == nil || !strings.Contains(err.Error(), "candidate") {
+		t.Errorf("OpenChatModel() with an unknown parameter error = %v", err)
+	}
+	if _, err = goai.OpenChatModel("goaitest:test-model?candidates=two"); err == nil {
+		t.Errorf("OpenChatModel() with an invalid parameter error = nil")
+	}
+	if _, err = goai.OpenChatModel("missing:model"); !errors.Is(err, goai.ErrUnknownProvider) {
+		t.Errorf("OpenChatModel() of an unknown provider error = %v", err)
+	}
+	if _, err = goai.OpenEmbeddingModel("goaitest:test-model"); !errors.Is(err, goai.ErrUnsupportedModel) {
+		t.Errorf("OpenEmbeddingModel() error = %v", err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("RegisterProvider() twice did not panic")
+		}
+	}()
+	goai.RegisterProvider("goaitest", goai.Provider{})
+}
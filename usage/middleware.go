@@ -0,0 +1,113 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/tech1024/goai"
+	"github.com/tech1024/goai/chat"
+	"github.com/tech1024/goai/prompt"
+)
+
+// ErrBudgetExceeded is returned by a guarded chat model once the budget
+// of the key is used up.
+var ErrBudgetExceeded = errors.New("usage budget exceeded")
+
+// Track a middleware recording the usage of every call with the tracker.
+func Track(tracker *Tracker) goai.ChatModelMiddleware {
+	return func(next goai.ChatModel) goai.ChatModel {
+		return &trackChatModel{next: next, tracker: tracker}
+	}
+}
+
+type trackChatModel struct {
+	next    goai.ChatModel
+	tracker *Tracker
+}
+
+func (m *trackChatModel) Call(ctx context.Context, p prompt.Prompt) (*chat.Response, error) {
+	resp, err := m.next.Call(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+
+	m.record(ctx, p, resp)
+
+	return resp, nil
+}
+
+func (m *trackChatModel) Stream(ctx context.Context, p prompt.Prompt, fn func(*chat.Response) error) error {
+	return m.next.Stream(ctx, p, func(resp *chat.Response) error {
+		if !resp.Usage.IsZero() {
+			m.record(ctx, p, resp)
+		}
+
+		return fn(resp)
+	})
+}
+
+// record adds the usage of the response keyed by the model requested by the
+// prompt, which Guard knows before the call, and priced by the requested
+// model, falling back to the one reported by the response.
+func (m *trackChatModel) record(ctx context.Context, p prompt.Prompt, resp *chat.Response) {
+	priced := p.ChatOption.Model
+	if priced == "" {
+		priced = resp.Model
+	}
+
+	m.tracker.record(ctx, p.ChatOption.Model, priced, resp.Usage)
+}
+
+// Limit the budget of a key, zero values are unlimited.
+type Limit struct {
+	MaxTokens int
+	MaxCost   float64
+}
+
+func (l Limit) exceeded(total Total) bool {
+	return (l.MaxTokens > 0 && total.TotalTokens >= l.MaxTokens) ||
+		(l.MaxCost > 0 && total.Cost >= l.MaxCost)
+}
+
+// Guard a middleware rejecting calls with ErrBudgetExceeded once the usage
+// recorded by the tracker for the key of the call reaches the limit. The key is
+// computed with the model of the prompt option like Track does, prompts without
+// a model share the key of the empty model, set a default model with
+// goai.DefaultChatOption to tell them apart.
+func Guard(tracker *Tracker, limit Limit) goai.ChatModelMiddleware {
+	return func(next goai.ChatModel) goai.ChatModel {
+		return &guardChatModel{next: next, tracker: tracker, limit: limit}
+	}
+}
+
+type guardChatModel struct {
+	next    goai.ChatModel
+	tracker *Tracker
+	limit   Limit
+}
+
+func (m *guardChatModel) check(ctx context.Context, p prompt.Prompt) error {
+	key := m.tracker.Key(ctx, p.ChatOption.Model)
+	if m.limit.exceeded(m.tracker.Total(key)) {
+		return fmt.Errorf("%w: %q", ErrBudgetExceeded, key)
+	}
+
+	return nil
+}
+
+func (m *guardChatModel) Call(ctx context.Context, p prompt.Prompt) (*chat.Response, error) {
+	if err := m.check(ctx, p); err != nil {
+		return nil, err
+	}
+
+	return m.next.Call(ctx, p)
+}
+
+func (m *guardChatModel) Stream(ctx context.Context, p prompt.Prompt, fn func(*chat.Response) error) error {
+	if err := m.check(ctx, p); err != nil {
+		return err
+	}
+
+	return m.next.Stream(ctx, p, fn)
+}
@@ -0,0 +1,108 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/tech1024/goai"
+	"github.com/tech1024/goai/chat"
+	"github.com/tech1024/goai/prompt"
+)
+
+type tenantKey struct{}
+
+type stubChatModel struct{}
+
+func (stubChatModel) Call(context.Context, prompt.Prompt) (*chat.Response, error) {
+	return &chat.Response{
+		Model:       "test-model",
+		Generations: []chat.Generation{{Content: "ok"}},
+		Usage:       chat.Usage{PromptTokens: 600, CompletionTokens: 400, TotalTokens: 1000},
+	}, nil
+}
+
+func (stubChatModel) Stream(_ context.Context, _ prompt.Prompt, fn func(*chat.Response) error) error {
+	if err := fn(&chat.Response{Model: "test-model", Generations: []chat.Generation{{Content: "ok"}}}); err != nil {
+		return err
+	}
+
+	return fn(&chat.Response{Model: "test-model", Usage: chat.Usage{PromptTokens: 6, CompletionTokens: 4, TotalTokens: 10}})
+}
+
+func TestGuard(t *testing.T) {
+	tracker := NewTracker(ByContextValue(tenantKey{}), PriceTable{"test-model": {Prompt: 1, Completion: 2}})
+	model := goai.WrapChatModel(stubChatModel{}, Guard(tracker, Limit{MaxTokens: 2000}), Track(tracker))
+	ctx := context.WithValue(context.Background(), tenantKey{}, "tenant-a")
+
+	for i := 0; i < 2; i++ {
+		if _, err := model.Call(ctx, prompt.NewPrompt()); err != nil {
+			t.Fatalf("Call() error = %v", err)
+		}
+	}
+
+	if _, err := model.Call(ctx, prompt.NewPrompt()); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("Call() error = %v, wantErr %v", err, ErrBudgetExceeded)
+	}
+
+	if err := model.Stream(context.Background(), prompt.NewPrompt(), func(*chat.Response) error { return nil }); err != nil {
+		t.Errorf("Stream() error = %v", err)
+	}
+
+	want := Total{Calls: 2, Usage: chat.Usage{PromptTokens: 1200, CompletionTokens: 800, TotalTokens: 2000}, Cost: 0.0028}
+	if got := tracker.Total("tenant-a"); got != want {
+		t.Errorf("Total() got = %v, want %v", got, want)
+	}
+
+	if got := tracker.Total(""); got.Calls != 1 || got.TotalTokens != 10 {
+		t.Errorf("Total() got = %v", got)
+	}
+}
+
+// datedChatModel reports a dated version of the requested model like OpenAI.
+type datedChatModel struct {
+	stubChatModel
+}
+
+func (m datedChatModel) Call(ctx context.Context, p prompt.Prompt) (*chat.Response, error) {
+	resp, err := m.stubChatModel.Call(ctx, p)
+	if err == nil {
+		resp.Model = "gpt-4o-mini-2024-07-18"
+	}
+
+	return resp, err
+}
+
+func TestGuard_ByModel(t *testing.T) {
+	tracker := NewTracker(ByModel, PriceTable{"gpt-4o-mini": {Prompt: 1, Completion: 2}})
+	model := goai.WrapChatModel(datedChatModel{}, Guard(tracker, Limit{MaxTokens: 1000}), Track(tracker))
+
+	p := prompt.NewPrompt()
+	p.ChatOption.Model = "gpt-4o-mini"
+	if _, err := model.Call(context.Background(), p); err != nil {
+		t.Fatalf("Call() error = %v", err)
+	}
+	if _, err := model.Call(context.Background(), p); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("Call() error = %v, wantErr %v", err, ErrBudgetExceeded)
+	}
+
+	if got := tracker.Total("gpt-4o-mini"); got.Calls != 1 || got.Cost != 0.0014 {
+		t.Errorf("Total() got = %v", got)
+	}
+
+	// without a requested model the calls share the empty model, they are
+	// priced by the dated model reported.
+	tracker = NewTracker(ByModel, PriceTable{"gpt-4o": {Prompt: 5, Completion: 10}, "gpt-4o-mini": {Prompt: 1, Completion: 2}})
+	model = goai.WrapChatModel(datedChatModel{}, Guard(tracker, Limit{MaxCost: 0.002}), Track(tracker))
+	for i := 0; i < 2; i++ {
+		if _, err := model.Call(context.Background(), prompt.NewPrompt()); err != nil {
+			t.Fatalf("Call() error = %v", err)
+		}
+	}
+	if _, err := model.Call(context.Background(), prompt.NewPrompt()); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("Call() without a model error = %v, wantErr %v", err, ErrBudgetExceeded)
+	}
+	if got := tracker.Total(""); got.Calls != 2 || got.Cost != 0.0028 {
+		t.Errorf("Total() got = %v", got)
+	}
+}
@@ -0,0 +1,47 @@
+package usage
+
+import (
+	"strings"
+
+	"github.com/tech1024/goai/chat"
+)
+
+// Price the price of a model per one million tokens.
+type Price struct {
+	Prompt     float64
+	Completion float64
+}
+
+// PriceTable the prices by model name.
+type PriceTable map[string]Price
+
+// Cost returns the cost of the usage, models without a price cost nothing.
+// Models without an exact price use the price of the longest name they start
+// with, e.g. the dated "gpt-4o-mini-2024-07-18" reported by OpenAI the one of
+// "gpt-4o-mini".
+func (pt PriceTable) Cost(model string, usage chat.Usage) float64 {
+	price, ok := pt.lookup(model)
+	if !ok {
+		return 0
+	}
+
+	return (float64(usage.PromptTokens)*price.Prompt + float64(usage.CompletionTokens)*price.Completion) / 1e6
+}
+
+func (pt PriceTable) lookup(model string) (Price, bool) {
+	if price, ok := pt[model]; ok {
+		return price, true
+	}
+
+	var name string
+	for prefix := range pt {
+		if len(prefix) > len(name) && strings.HasPrefix(model, prefix) {
+			name = prefix
+		}
+	}
+	if name == "" {
+		return Price{}, false
+	}
+
+	return pt[name], true
+}
@@ -0,0 +1,116 @@
+package usage
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/tech1024/goai/chat"
+)
+
+// KeyFunc returns the key the usage of a call is aggregated by.
+type KeyFunc func(ctx context.Context, model string) string
+
+// ByModel aggregates the usage by the model requested by the prompt, calls
+// without a requested model are aggregated under the empty model.
+func ByModel(_ context.Context, model string) string {
+	return model
+}
+
+// ByContextValue aggregates the usage by a string value of the context,
+// e.g. the tenant of the request, calls without the value share the key "".
+func ByContextValue(key any) KeyFunc {
+	return func(ctx context.Context, _ string) string {
+		value, _ := ctx.Value(key).(string)
+		return value
+	}
+}
+
+// Total the aggregated usage of a key.
+type Total struct {
+	Calls int
+	chat.Usage
+
+	// Cost the cost calculated by the price table of the tracker.
+	Cost float64
+}
+
+// Tracker aggregates the usage of chat model calls.
+type Tracker struct {
+	mu     sync.Mutex
+	key    KeyFunc
+	prices PriceTable
+	totals map[string]Total
+}
+
+// NewTracker returns a Tracker aggregating by key, prices may be nil when
+// the cost is of no interest.
+func NewTracker(key KeyFunc, prices PriceTable) *Tracker {
+	if key == nil {
+		key = ByModel
+	}
+
+	return &Tracker{
+		key:    key,
+		prices: prices,
+		totals: make(map[string]Total),
+	}
+}
+
+// Key returns the key the usage of a call with the model is aggregated by.
+func (t *Tracker) Key(ctx context.Context, model string) string {
+	return t.key(ctx, model)
+}
+
+// Record adds the usage of a call to the model, it returns the cost of the call.
+func (t *Tracker) Record(ctx context.Context, model string, usage chat.Usage) float64 {
+	return t.record(ctx, model, model, usage)
+}
+
+// record adds the usage of a call keyed by the model and priced by the
+// priced model, e.g. the one reported for a call without a model.
+func (t *Tracker) record(ctx context.Context, model, priced string, usage chat.Usage) float64 {
+	cost := t.prices.Cost(priced, usage)
+	key := t.key(ctx, model)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	total := t.totals[key]
+	total.Calls++
+	total.Usage = total.Usage.Add(usage)
+	total.Cost += cost
+	t.totals[key] = total
+
+	return cost
+}
+
+// Total returns the aggregated usage of the key.
+func (t *Tracker) Total(key string) Total {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.totals[key]
+}
+
+// Keys returns all keys with recorded usage in sorted order.
+func (t *Tracker) Keys() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	keys := make([]string, 0, len(t.totals))
+	for key := range t.totals {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// Reset clears the usage of all keys.
+func (t *Tracker) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.totals = make(map[string]Total)
+}